@@ -0,0 +1,52 @@
+package main
+
+// errorWindow tracks the failure rate over the most recent results,
+// so a run can be stopped once the target is clearly unhealthy.
+type errorWindow struct {
+	outcomes []bool // ring buffer; true marks a failed result
+	next     int
+	filled   int
+	failures int
+}
+
+// Size of the rolling window, and the number of results needed before
+// the rate is trusted enough to abort on.
+const (
+	errorWindowSize = 100
+	errorWindowMin  = 20
+)
+
+func newErrorWindow(size int) *errorWindow {
+	return &errorWindow{outcomes: make([]bool, size)}
+}
+
+// Add records whether a result failed, evicting the oldest outcome
+// once the window is full.
+func (w *errorWindow) Add(failed bool) {
+	if w.filled == len(w.outcomes) {
+		if w.outcomes[w.next] {
+			w.failures--
+		}
+	} else {
+		w.filled++
+	}
+	w.outcomes[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+// Rate returns the fraction of failed results in the window.
+func (w *errorWindow) Rate() float64 {
+	if w.filled == 0 {
+		return 0
+	}
+	return float64(w.failures) / float64(w.filled)
+}
+
+// Exceeds reports whether the window holds enough results and its
+// failure rate is above threshold.
+func (w *errorWindow) Exceeds(threshold float64) bool {
+	return w.filled >= errorWindowMin && w.Rate() > threshold
+}
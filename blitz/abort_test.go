@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestErrorWindow(t *testing.T) {
+	w := newErrorWindow(errorWindowSize)
+	for range errorWindowMin - 1 {
+		w.Add(true)
+	}
+	if w.Exceeds(0.5) {
+		t.Fatal("should not abort before the minimum sample size")
+	}
+	w.Add(true)
+	if !w.Exceeds(0.5) {
+		t.Fatalf("rate %.2f should exceed 0.5", w.Rate())
+	}
+
+	// Successes push the failures out of the window.
+	for range errorWindowSize {
+		w.Add(false)
+	}
+	if got := w.Rate(); got != 0 {
+		t.Errorf("Rate() = %v after a full window of successes, want 0", got)
+	}
+	w.Add(true)
+	if got, want := w.Rate(), 1.0/errorWindowSize; got != want {
+		t.Errorf("Rate() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// abURL is the -url-a or -url-b flag. It adds its target to the -url
+// list under a fixed name, so the run splits traffic by weight like any
+// other mix and the two endpoints can be found again for the comparison.
+type abURL struct {
+	urls *urlList
+	name string
+}
+
+func (a abURL) String() string {
+	if a.urls == nil {
+		return ""
+	}
+	for _, u := range *a.urls {
+		if u.Name == a.name {
+			return u.flagValue()
+		}
+	}
+	return ""
+}
+
+func (a abURL) Set(value string) error {
+	if a.String() != "" {
+		return fmt.Errorf("-url-%s given more than once", strings.ToLower(a.name))
+	}
+	w, err := parseWeightedURL(value)
+	if err != nil {
+		return err
+	}
+	w.Name = a.name
+	*a.urls = append(*a.urls, w)
+	return nil
+}
+
+// validateAB checks that -url-a and -url-b come as a pair and are the
+// only targets, since other traffic would muddy the comparison.
+func validateAB(cfg config) error {
+	var a, b, plain int
+	for _, u := range cfg.URLs {
+		switch u.Name {
+		case "A":
+			a++
+		case "B":
+			b++
+		default:
+			plain++
+		}
+	}
+	switch {
+	case a == 0 && b == 0:
+		return nil
+	case a != 1 || b != 1:
+		return fmt.Errorf("-url-a and -url-b must be given together")
+	case plain > 0 || cfg.Scenario != "" || cfg.HAR != "":
+		return fmt.Errorf("-url-a and -url-b cannot be combined with -url, -scenario or -har")
+	}
+	return nil
+}
+
+// ABComparison is the side-by-side result of a -url-a/-url-b run.
+type ABComparison struct {
+	URLA, URLB string
+	A, B       EndpointStats
+}
+
+// compareAB builds the comparison from the run's per-endpoint results,
+// or returns nil when the run was not an A/B test.
+func compareAB(urls urlList, s Summary) *ABComparison {
+	ab := &ABComparison{}
+	var haveA, haveB bool
+	for _, u := range urls {
+		switch u.Name {
+		case "A":
+			ab.URLA = u.URL
+		case "B":
+			ab.URLB = u.URL
+		}
+	}
+	if ab.URLA == "" || ab.URLB == "" {
+		return nil
+	}
+	for _, e := range s.Endpoints {
+		switch e.Name {
+		case "A":
+			ab.A, haveA = e, true
+		case "B":
+			ab.B, haveB = e, true
+		}
+	}
+	// A side that never completed a request still gets its row.
+	if !haveA {
+		ab.A.Name = "A"
+	}
+	if !haveB {
+		ab.B.Name = "B"
+	}
+	return ab
+}
+
+// abMetric is one row of the A/B table: how to read the value from each
+// side and how to show it.
+type abMetric struct {
+	Name   string
+	Value  func(EndpointStats) float64
+	Format func(float64) string
+	Points bool // compare as a difference in percentage points, not a ratio
+}
+
+func abLatency(name string, get func(LatencyStats) time.Duration) abMetric {
+	return abMetric{
+		Name:   name,
+		Value:  func(e EndpointStats) float64 { return float64(get(e.Latency)) },
+		Format: func(v float64) string { return time.Duration(v).Round(time.Millisecond).String() },
+	}
+}
+
+var abMetrics = []abMetric{
+	{Name: "Requests", Value: func(e EndpointStats) float64 { return float64(e.Total) }, Format: func(v float64) string { return fmt.Sprintf("%.0f", v) }},
+	{Name: "Fail %", Value: func(e EndpointStats) float64 {
+		if e.Total == 0 {
+			return 0
+		}
+		return float64(e.Failed) / float64(e.Total) * 100
+	}, Format: func(v float64) string { return fmt.Sprintf("%.2f%%", v) }, Points: true},
+	abLatency("Mean", func(l LatencyStats) time.Duration { return l.Mean }),
+	abLatency("P50", func(l LatencyStats) time.Duration { return l.P50 }),
+	abLatency("P95", func(l LatencyStats) time.Duration { return l.P95 }),
+	abLatency("P99", func(l LatencyStats) time.Duration { return l.P99 }),
+	abLatency("Max", func(l LatencyStats) time.Duration { return l.Max }),
+}
+
+// abRows formats the comparison as table cells: metric, A, B and the
+// change from A to B.
+func abRows(ab *ABComparison) [][]string {
+	var rows [][]string
+	for _, m := range abMetrics {
+		a, b := m.Value(ab.A), m.Value(ab.B)
+		var change string
+		switch {
+		case m.Points:
+			change = fmt.Sprintf("%+.2f pp", b-a)
+		case a == b:
+			change = "="
+		case a == 0:
+			change = "new"
+		default:
+			change = fmt.Sprintf("%+.1f%%", (b-a)/a*100)
+		}
+		rows = append(rows, []string{m.Name, m.Format(a), m.Format(b), change})
+	}
+	return rows
+}
+
+// abHeaders are the column titles for abRows.
+func abHeaders() []string {
+	return []string{"Metric", "A", "B", "B vs A"}
+}
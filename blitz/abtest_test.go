@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestABFlags(t *testing.T) {
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	if err := fs.Parse([]string{"-url-a", "http://old/=9", "-url-b", "http://new/"}); err != nil {
+		t.Fatal(err)
+	}
+	want := urlList{{URL: "http://old/", Weight: 9, Name: "A"}, {URL: "http://new/", Weight: 1, Name: "B"}}
+	if len(cfg.URLs) != 2 || cfg.URLs[0] != want[0] || cfg.URLs[1] != want[1] {
+		t.Fatalf("URLs = %+v, want %+v", cfg.URLs, want)
+	}
+	if err := validateAB(cfg); err != nil {
+		t.Errorf("validateAB() error = %v", err)
+	}
+	if err := fs.Parse([]string{"-url-a", "http://other/"}); err == nil {
+		t.Error("-url-a accepted twice")
+	}
+
+	targets, err := buildTargets(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := targets.scenarios[0].Steps[0].Tmpl.Name; got != "A" {
+		t.Errorf("A target named %q, want A", got)
+	}
+}
+
+func TestValidateAB(t *testing.T) {
+	tests := []urlList{
+		{{URL: "http://old/", Weight: 1, Name: "A"}},
+		{{URL: "http://old/", Weight: 1, Name: "A"}, {URL: "http://new/", Weight: 1, Name: "B"}, {URL: "http://x/", Weight: 1}},
+	}
+	for _, urls := range tests {
+		if err := validateAB(config{URLs: urls}); err == nil {
+			t.Errorf("validateAB(%+v) expected error, got nil", urls)
+		}
+	}
+}
+
+func TestCompareAB(t *testing.T) {
+	urls := urlList{{URL: "http://old/", Weight: 1, Name: "A"}, {URL: "http://new/", Weight: 1, Name: "B"}}
+	var results []Result
+	for range 10 {
+		results = append(results,
+			Result{Step: "A", Status: 200, Latency: 100 * time.Millisecond},
+			Result{Step: "B", Status: 200, Latency: 150 * time.Millisecond})
+	}
+	results = append(results, Result{Step: "B", Status: 500, Latency: 150 * time.Millisecond})
+	c := newCollector()
+	for _, r := range results {
+		c.Add(r)
+	}
+	s := c.Summary(time.Second)
+	s.AB = compareAB(urls, s)
+	if s.AB == nil || s.AB.A.Total != 10 || s.AB.B.Total != 11 {
+		t.Fatalf("compareAB() = %+v", s.AB)
+	}
+
+	rows := abRows(s.AB)
+	byName := make(map[string][]string)
+	for _, row := range rows {
+		byName[row[0]] = row
+	}
+	if got := byName["Mean"][3]; got != "+50.0%" {
+		t.Errorf("Mean change = %q, want +50.0%%", got)
+	}
+	if got := byName["Fail %"][3]; got != "+9.09 pp" {
+		t.Errorf("Fail %% change = %q, want +9.09 pp", got)
+	}
+
+	var buf bytes.Buffer
+	renderText(&buf, s)
+	if out := buf.String(); !strings.Contains(out, "A/B COMPARISON") || !strings.Contains(out, "B: http://new/") {
+		t.Errorf("text report missing A/B comparison:\n%s", out)
+	}
+	if j := newJSONReport(s); j.AB == nil || j.AB.B.Failed != 1 {
+		t.Errorf("JSON ab_comparison = %+v", j.AB)
+	}
+
+	if compareAB(urlList{{URL: "http://x/", Weight: 1}}, s) != nil {
+		t.Error("compareAB returned a comparison for a plain run")
+	}
+}
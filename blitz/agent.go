@@ -0,0 +1,655 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// A distributed run has one coordinator ("blitz -agents a,b ...") and
+// any number of agents ("blitz agent"). The coordinator forwards its
+// flags to every agent along with that agent's share of the workers,
+// requests and rate, then merges the collectors they send back and
+// reports them as a single run.
+//
+// An agent runs whatever it is sent, so it listens on loopback unless
+// told otherwise and serves only requests that carry its token. Flags
+// that name files or sockets are refused: they would let a coordinator
+// read and write files on the agent's machine.
+
+// agentTokenEnv names the environment variable that supplies the token
+// when -token or -agent-token is not given.
+const agentTokenEnv = "BLITZ_AGENT_TOKEN"
+
+// agentLocalFlags name files, directories or sockets on the machine
+// they run on, so an agent refuses them.
+var agentLocalFlags = map[string]bool{
+	"config":               true,
+	"url-file":             true,
+	"body-file":            true,
+	"file-upload":          true,
+	"graphql-query":        true,
+	"graphql-vars":         true,
+	"data":                 true,
+	"scenario":             true,
+	"har":                  true,
+	"proto":                true,
+	"import-path":          true,
+	"cacert":               true,
+	"cert":                 true,
+	"key":                  true,
+	"cert-dir":             true,
+	"unix-socket":          true,
+	"output-file":          true,
+	"report":               true,
+	"heatmap":              true,
+	"junit":                true,
+	"report-interval-file": true,
+	"save-baseline":        true,
+	"history-file":         true,
+	"compare":              true,
+	"save-errors":          true,
+	"slow-file":            true,
+	"record":               true,
+	"checkpoint":           true,
+	"resume":               true,
+}
+
+// localFlagsSet returns the agentLocalFlags set on fs, sorted by name.
+func localFlagsSet(fs *flag.FlagSet) []string {
+	var names []string
+	fs.Visit(func(f *flag.Flag) {
+		if agentLocalFlags[f.Name] {
+			names = append(names, "-"+f.Name)
+		}
+	})
+	return names
+}
+
+// tokenOrEnv returns token, or the agentTokenEnv variable if it is empty.
+func tokenOrEnv(token string) string {
+	if token == "" {
+		return os.Getenv(agentTokenEnv)
+	}
+	return token
+}
+
+// agentRequest is the work a coordinator sends to POST /run.
+type agentRequest struct {
+	Args     []string `json:"args"`     // run flags, as on the command line
+	Workers  int      `json:"workers"`  // this agent's workers
+	Requests int      `json:"requests"` // this agent's requests; 0 for a time-based run
+	Share    float64  `json:"share"`    // fraction of the rate to generate
+}
+
+// agentResponse is an agent's reply: its results, or why it could not run.
+type agentResponse struct {
+	Stats   *collector    `json:"stats,omitempty"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+	Aborted string        `json:"aborted,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// config parses the forwarded flags and applies this agent's share.
+func (req agentRequest) config() (config, error) {
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var cfg config
+	cfg.registerFlags(fs)
+	if err := fs.Parse(req.Args); err != nil {
+		return cfg, err
+	}
+	if local := localFlagsSet(fs); len(local) > 0 {
+		return cfg, fmt.Errorf("agents do not accept flags that name local files: %s", strings.Join(local, ", "))
+	}
+	if req.Workers <= 0 {
+		return cfg, errors.New("workers must be positive")
+	}
+	cfg.Workers = req.Workers
+	cfg.Requests = req.Requests
+	cfg.requestsSet = true
+	cfg.methodSet = flagWasSet(fs, "method")
+	cfg.share = req.Share
+	return cfg, nil
+}
+
+// agent runs load tests for a coordinator, one at a time.
+type agent struct {
+	token string // every request must carry it as a bearer token
+
+	mu     sync.Mutex
+	cancel context.CancelFunc // stops the current run; nil when idle
+}
+
+// runAgent implements "blitz agent": serve runs until interrupted.
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("blitz agent", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:7070", "Address to accept runs from a coordinator on; use e.g. :7070 to accept them from other machines")
+	token := fs.String("token", "", "Secret a coordinator must send with -agent-token (default: $"+agentTokenEnv+")")
+	fs.Parse(args)
+
+	a := &agent{token: tokenOrEnv(*token)}
+	if a.token == "" {
+		fail(errors.New("blitz agent needs a -token or $" + agentTokenEnv))
+		fs.Usage()
+		return exitError
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "Agent listening on %s\n", ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Handler: a.handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func (a *agent) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run", a.handleRun)
+	mux.HandleFunc("POST /stop", a.handleStop)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || a.token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(a.token)) != 1 {
+			writeAgentResponse(w, http.StatusUnauthorized, agentResponse{Error: "missing or wrong agent token"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (a *agent) handleRun(w http.ResponseWriter, r *http.Request) {
+	var req agentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAgentResponse(w, http.StatusBadRequest, agentResponse{Error: "decoding request: " + err.Error()})
+		return
+	}
+	cfg, err := req.config()
+	if err != nil {
+		writeAgentResponse(w, http.StatusBadRequest, agentResponse{Error: err.Error()})
+		return
+	}
+
+	// The run also stops if the coordinator goes away.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	a.mu.Lock()
+	if a.cancel != nil {
+		a.mu.Unlock()
+		writeAgentResponse(w, http.StatusConflict, agentResponse{Error: "agent is busy with another run"})
+		return
+	}
+	a.cancel = cancel
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.cancel = nil
+		a.mu.Unlock()
+	}()
+
+	t, err := newLoadTest(cfg)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.As(err, new(usageError)) {
+			code = http.StatusBadRequest
+		}
+		writeAgentResponse(w, code, agentResponse{Error: err.Error()})
+		return
+	}
+	defer t.Close()
+
+	fmt.Fprintf(os.Stderr, "Running for %s: %d workers\n", r.RemoteAddr, cfg.Workers)
+	out := t.execute(ctx, silentDisplay{})
+	fmt.Fprintf(os.Stderr, "Finished: %d requests in %s\n", out.stats.total, out.elapsed.Round(time.Millisecond))
+
+	if t.otlp != nil {
+		if err := t.otlp.Close(out.stats.Summary(out.elapsed)); err != nil {
+			fmt.Fprintln(os.Stderr, cli.Error("Error: OTLP export: "+err.Error()))
+		}
+	}
+	writeAgentResponse(w, http.StatusOK, agentResponse{Stats: out.stats, Elapsed: out.elapsed, Aborted: out.aborted})
+}
+
+// handleStop ends the current run early; its results are still sent
+// back to the coordinator.
+func (a *agent) handleStop(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	if a.cancel != nil {
+		a.cancel()
+	}
+	a.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeAgentResponse(w http.ResponseWriter, code int, resp agentResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// coordinatorFlags are not forwarded to agents: they shape how the
+// coordinator reports, or are resolved before forwarding.
+var coordinatorFlags = map[string]bool{
+	"agents":        true,
+	"agent-token":   true,
+	"config":        true,
+	"output":        true,
+	"output-file":   true,
+	"report":        true,
+	"heatmap":       true,
+	"junit":         true,
+	"live":          true,
+	"percentiles":   true,
+	"quiet":         true,
+	"save-baseline": true,
+	"tag":           true,
+	"history-file":  true,
+	"compare":       true,
+	"workers":       true,
+	"requests":      true,
+}
+
+// repeatable is implemented by flag values that collect one entry per use.
+type repeatable interface {
+	values() []string
+}
+
+func (u *urlList) values() []string {
+	vals := make([]string, len(*u))
+	for i, w := range *u {
+		vals[i] = w.flagValue()
+	}
+	return vals
+}
+
+func (h *headerList) values() []string { return *h }
+
+func (l *stringList) values() []string { return *l }
+
+// forwardedArgs rebuilds the command line to send to agents from the
+// flags set on fs, including any taken from a -config file.
+func forwardedArgs(fs *flag.FlagSet) []string {
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		if coordinatorFlags[f.Name] {
+			return
+		}
+		if r, ok := f.Value.(repeatable); ok {
+			for _, v := range r.values() {
+				args = append(args, "-"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "-"+f.Name+"="+f.Value.String())
+	})
+	return args
+}
+
+// split divides n into parts that differ by at most one.
+func split(n, parts int) []int {
+	out := make([]int, parts)
+	for i := range out {
+		out[i] = n / parts
+		if i < n%parts {
+			out[i]++
+		}
+	}
+	return out
+}
+
+// parseAgents splits a -agents list into base URLs.
+func parseAgents(list string) []string {
+	var agents []string
+	for _, a := range strings.Split(list, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if !strings.Contains(a, "://") {
+			a = "http://" + a
+		}
+		agents = append(agents, strings.TrimSuffix(a, "/"))
+	}
+	return agents
+}
+
+// runDistributed splits the run described by cfg and fs across the
+// -agents and merges what they report. Agents that fail are reported
+// and leave the outcome partial; it is an error only if all of them do.
+func runDistributed(ctx context.Context, cfg config, fs *flag.FlagSet) (outcome, error) {
+	agents := parseAgents(cfg.Agents)
+	if len(agents) == 0 {
+		return outcome{}, usageError{errors.New("-agents needs at least one address")}
+	}
+	if len(cfg.URLs) == 0 && cfg.Scenario == "" && cfg.HAR == "" {
+		return outcome{}, usageError{errors.New("URL is required")}
+	}
+	if cfg.Workers <= 0 {
+		return outcome{}, usageError{errors.New("-workers must be positive")}
+	}
+	token := tokenOrEnv(cfg.AgentToken)
+	if token == "" {
+		return outcome{}, usageError{errors.New("-agents needs the agents' -agent-token or $" + agentTokenEnv)}
+	}
+	// Coordinator flags are not forwarded, so only the rest are refused.
+	var local []string
+	for _, name := range localFlagsSet(fs) {
+		if !coordinatorFlags[name[1:]] {
+			local = append(local, name)
+		}
+	}
+	if len(local) > 0 {
+		return outcome{}, usageError{fmt.Errorf("%s name local files, which agents do not read", strings.Join(local, ", "))}
+	}
+
+	// Mirror newLoadTest: time-based runs are unbounded unless -requests
+	// was given explicitly.
+	sched, err := buildSchedule(cfg.Rate, cfg.Stages, cfg.RampUp)
+	if err != nil {
+		return outcome{}, usageError{err}
+	}
+	requests := cfg.Requests
+	if (cfg.Duration > 0 || sched.Length() > 0) && !cfg.requestsSet {
+		requests = 0
+	}
+
+	// Every agent needs at least one worker and, for a fixed count, at
+	// least one request, since zero would mean unbounded.
+	n := min(len(agents), cfg.Workers)
+	if requests > 0 {
+		n = min(n, requests)
+	}
+	agents = agents[:n]
+	workers := split(cfg.Workers, n)
+	counts := split(requests, n)
+	args := forwardedArgs(fs)
+
+	if !cfg.Quiet {
+		fmt.Fprintf(os.Stderr, "Running on %d agents...\n", n)
+	}
+
+	// On interrupt, ask agents to stop and still collect what they ran.
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			for _, a := range agents {
+				if resp, err := postAgent(a+"/stop", token, nil); err == nil {
+					resp.Body.Close()
+				}
+			}
+		case <-stopped:
+		}
+	}()
+
+	responses := make([]agentResponse, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, a := range agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := agentRequest{Args: args, Workers: workers[i], Requests: counts[i], Share: 1 / float64(n)}
+			responses[i], errs[i] = dispatch(a, token, req)
+		}()
+	}
+	wg.Wait()
+
+	out := outcome{stats: newCollector()}
+	var failures []error
+	for i, a := range agents {
+		if errs[i] != nil {
+			failures = append(failures, fmt.Errorf("agent %s: %w", a, errs[i]))
+			continue
+		}
+		resp := responses[i]
+		out.stats.Merge(resp.Stats)
+		out.elapsed = max(out.elapsed, resp.Elapsed)
+		if resp.Aborted != "" && out.aborted == "" {
+			out.aborted = fmt.Sprintf("agent %s: %s", a, resp.Aborted)
+		}
+	}
+	if len(failures) == n {
+		return outcome{}, errors.Join(failures...)
+	}
+	for _, err := range failures {
+		fmt.Fprintln(os.Stderr, cli.Error("Error: "+err.Error()))
+	}
+	out.partial = len(failures) > 0
+	return out, nil
+}
+
+// postAgent POSTs body to an agent endpoint with the agent token.
+func postAgent(url, token string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return http.DefaultClient.Do(req)
+}
+
+// dispatch sends one agent its share of the run and waits for the results.
+func dispatch(agentURL, token string, req agentRequest) (agentResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return agentResponse{}, err
+	}
+	resp, err := postAgent(agentURL+"/run", token, bytes.NewReader(payload))
+	if err != nil {
+		return agentResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var result agentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return agentResponse{}, fmt.Errorf("decoding response (%s): %w", resp.Status, err)
+	}
+	if result.Error != "" {
+		return agentResponse{}, errors.New(result.Error)
+	}
+	if resp.StatusCode != http.StatusOK || result.Stats == nil {
+		return agentResponse{}, fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return result, nil
+}
+
+// collectorJSON is the wire form of a collector.
+type collectorJSON struct {
+	Total       int               `json:"total"`
+	Successful  int               `json:"successful"`
+	Failed      int               `json:"failed"`
+	Errors      int               `json:"errors"`
+	Mismatches  int               `json:"mismatches"`
+	Redirected  int               `json:"redirected"`
+	Retried     int               `json:"retried"`
+	Timeouts    int               `json:"timeouts"`
+	Retries     int               `json:"retries"`
+	Hedged      int               `json:"hedged"`
+	HedgeWins   int               `json:"hedge_wins"`
+	Throttled   int               `json:"throttled"`
+	BackedOff   time.Duration     `json:"backed_off_ns"`
+	Reused      int               `json:"reused"`
+	Bytes       int64             `json:"bytes"`
+	MinBytes    int64             `json:"min_bytes"`
+	MaxBytes    int64             `json:"max_bytes"`
+	WireBytes   int64             `json:"wire_bytes"`
+	Encoded     int               `json:"encoded"`
+	Paced       bool              `json:"paced"`
+	StatusCodes map[int]int       `json:"status_codes"`
+	Protocols   map[string]int    `json:"protocols"`
+	Families    map[string]int    `json:"families"`
+	Categories  []ErrorCategory   `json:"categories"`
+	Latency     histogram         `json:"latency"`
+	Succeeded   histogram         `json:"succeeded"`
+	Corrected   histogram         `json:"corrected"`
+	DNS         histogram         `json:"dns"`
+	Connect     histogram         `json:"connect"`
+	TLS         histogram         `json:"tls"`
+	TTFB        histogram         `json:"ttfb"`
+	Download    histogram         `json:"download"`
+	Timeline    timeline          `json:"timeline"`
+	Endpoints   []endpointJSON    `json:"endpoints"`
+	ByStatus    map[int]histogram `json:"by_status"`
+}
+
+type endpointJSON struct {
+	Name    string     `json:"name"`
+	Total   int        `json:"total"`
+	Failed  int        `json:"failed"`
+	Errors  int        `json:"errors"`
+	Latency histogram  `json:"latency"`
+	Hashes  []BodyHash `json:"hashes,omitempty"`
+}
+
+func (c *collector) MarshalJSON() ([]byte, error) {
+	j := collectorJSON{
+		Total:       c.total,
+		Successful:  c.successful,
+		Failed:      c.failed,
+		Errors:      c.errors,
+		Mismatches:  c.mismatches,
+		Redirected:  c.redirected,
+		Retried:     c.retried,
+		Timeouts:    c.timeouts,
+		Retries:     c.retries,
+		Hedged:      c.hedged,
+		HedgeWins:   c.hedgeWins,
+		Throttled:   c.throttled,
+		BackedOff:   c.backedOff,
+		Reused:      c.reused,
+		Bytes:       c.bytes,
+		MinBytes:    c.minBytes,
+		MaxBytes:    c.maxBytes,
+		WireBytes:   c.wireBytes,
+		Encoded:     c.encoded,
+		Paced:       c.paced,
+		StatusCodes: c.statusCodes,
+		Protocols:   c.protocols,
+		Families:    c.families,
+		Latency:     c.latency,
+		Succeeded:   c.succeeded,
+		Corrected:   c.corrected,
+		DNS:         c.dns,
+		Connect:     c.connect,
+		TLS:         c.tls,
+		TTFB:        c.ttfb,
+		Download:    c.download,
+		Timeline:    c.timeline,
+		ByStatus:    make(map[int]histogram, len(c.byStatus)),
+	}
+	for status, h := range c.byStatus {
+		j.ByStatus[status] = *h
+	}
+	for _, cat := range c.categories {
+		j.Categories = append(j.Categories, *cat)
+	}
+	for _, name := range c.endpointOrder {
+		e := c.endpoints[name]
+		j.Endpoints = append(j.Endpoints, endpointJSON{
+			Name:    name,
+			Total:   e.total,
+			Failed:  e.failed,
+			Errors:  e.errors,
+			Latency: e.latency,
+			Hashes:  e.bodyHashes(),
+		})
+	}
+	return json.Marshal(j)
+}
+
+func (c *collector) UnmarshalJSON(data []byte) error {
+	var j collectorJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	*c = *newCollector()
+	c.total = j.Total
+	c.successful = j.Successful
+	c.failed = j.Failed
+	c.errors = j.Errors
+	c.mismatches = j.Mismatches
+	c.redirected = j.Redirected
+	c.retried = j.Retried
+	c.timeouts = j.Timeouts
+	c.retries = j.Retries
+	c.hedged = j.Hedged
+	c.hedgeWins = j.HedgeWins
+	c.throttled = j.Throttled
+	c.backedOff = j.BackedOff
+	c.reused = j.Reused
+	c.bytes = j.Bytes
+	c.minBytes = j.MinBytes
+	c.maxBytes = j.MaxBytes
+	c.wireBytes = j.WireBytes
+	c.encoded = j.Encoded
+	c.paced = j.Paced
+	for code, n := range j.StatusCodes {
+		c.statusCodes[code] = n
+	}
+	for proto, n := range j.Protocols {
+		c.protocols[proto] = n
+	}
+	for family, n := range j.Families {
+		c.families[family] = n
+	}
+	for _, cat := range j.Categories {
+		c.categories[cat.Name] = &cat
+	}
+	c.latency = j.Latency
+	c.succeeded = j.Succeeded
+	c.corrected = j.Corrected
+	c.dns = j.DNS
+	c.connect = j.Connect
+	c.tls = j.TLS
+	c.ttfb = j.TTFB
+	c.download = j.Download
+	c.timeline = j.Timeline
+	for _, e := range j.Endpoints {
+		ec := &endpointCollector{
+			total:   e.Total,
+			failed:  e.Failed,
+			errors:  e.Errors,
+			latency: e.Latency,
+		}
+		for _, h := range e.Hashes {
+			ec.addHash(h)
+		}
+		c.endpoints[e.Name] = ec
+		c.endpointOrder = append(c.endpointOrder, e.Name)
+	}
+	for status, h := range j.ByStatus {
+		c.byStatus[status] = &h
+	}
+	return nil
+}
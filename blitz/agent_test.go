@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func sampleResults() []Result {
+	return []Result{
+		{Step: "GET /a", Status: 200, Proto: "HTTP/1.1", Bytes: 10, Latency: 5 * time.Millisecond, BodyHash: "aa"},
+		{Step: "GET /a", Status: 500, Proto: "HTTP/1.1", Bytes: 3, Latency: 20 * time.Millisecond, BodyHash: "bb"},
+		{Step: "GET /b", Status: 200, Proto: "HTTP/2.0", Bytes: 40, Latency: 8 * time.Millisecond, Retries: 1, BodyHash: "cc"},
+		{Step: "GET /b", Error: errors.New("connection refused"), Latency: time.Millisecond},
+	}
+}
+
+func TestCollectorMerge(t *testing.T) {
+	results := sampleResults()
+
+	whole := newCollector()
+	for _, r := range results {
+		whole.Add(r)
+	}
+	a, b := newCollector(), newCollector()
+	for i, r := range results {
+		if i%2 == 0 {
+			a.Add(r)
+		} else {
+			b.Add(r)
+		}
+	}
+	a.Merge(b)
+
+	want := whole.Summary(time.Second)
+	if got := a.Summary(time.Second); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged summary = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestCollectorJSONRoundTrip(t *testing.T) {
+	c := newCollector()
+	for _, r := range sampleResults() {
+		c.Add(r)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded collector
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	want := c.Summary(time.Second)
+	if got := decoded.Summary(time.Second); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded summary = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	if got := split(10, 3); !slices.Equal(got, []int{4, 3, 3}) {
+		t.Errorf("split(10, 3) = %v, want [4 3 3]", got)
+	}
+	if got := split(0, 2); !slices.Equal(got, []int{0, 0}) {
+		t.Errorf("split(0, 2) = %v, want [0 0]", got)
+	}
+}
+
+func TestForwardedArgs(t *testing.T) {
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	err := fs.Parse([]string{
+		"-url", "http://h/a", "-url", "/b=3", "-header", "X-A: 1",
+		"-workers", "8", "-agents", "a:7070", "-output", "json", "-insecure",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := agentRequest{Args: forwardedArgs(fs), Workers: 4, Requests: 7}
+	got, err := req.config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (urlList{{URL: "http://h/a", Weight: 1}, {URL: "/b", Weight: 3}}); !reflect.DeepEqual(got.URLs, want) {
+		t.Errorf("URLs = %v, want %v", got.URLs, want)
+	}
+	if !slices.Equal(got.Headers, headerList{"X-A: 1"}) || !got.Transport.Insecure {
+		t.Errorf("headers/insecure not forwarded: %v %v", got.Headers, got.Transport.Insecure)
+	}
+	if got.Agents != "" || got.Output != "text" {
+		t.Errorf("coordinator flags forwarded: agents=%q output=%q", got.Agents, got.Output)
+	}
+	if got.Workers != 4 || got.Requests != 7 {
+		t.Errorf("workers/requests = %d/%d, want 4/7", got.Workers, got.Requests)
+	}
+}
+
+func TestRunDistributed(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer target.Close()
+
+	var agents []string
+	for range 2 {
+		srv := httptest.NewServer((&agent{token: "s3cret"}).handler())
+		defer srv.Close()
+		agents = append(agents, srv.URL)
+	}
+	// An unreachable agent is reported but doesn't fail the run.
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	err := fs.Parse([]string{
+		"-url", target.URL, "-requests", "25", "-workers", "3",
+		"-agents", agents[0] + "," + agents[1] + "," + dead.URL, "-agent-token", "s3cret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.requestsSet = true
+
+	out, err := runDistributed(context.Background(), cfg, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := out.stats.Summary(out.elapsed)
+	// The dead agent's 8 requests are missing.
+	if s.Total != 17 || s.Successful != 17 {
+		t.Errorf("total/successful = %d/%d, want 17/17", s.Total, s.Successful)
+	}
+	if !out.partial {
+		t.Error("outcome should be partial when an agent fails")
+	}
+}
+
+func TestAgentToken(t *testing.T) {
+	srv := httptest.NewServer((&agent{token: "s3cret"}).handler())
+	defer srv.Close()
+
+	for _, token := range []string{"", "wrong", "s3cre"} {
+		resp, err := postAgent(srv.URL+"/stop", token, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want %d", token, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+	resp, err := postAgent(srv.URL+"/stop", "s3cret", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("right token: status %d, want %d", resp.StatusCode, http.StatusNoContent)
+	}
+}
+
+func TestAgentRefusesLocalFiles(t *testing.T) {
+	for _, args := range [][]string{
+		{"-url", "http://h", "-body-file", "/etc/passwd"},
+		{"-url", "http://h", "-record", "/tmp/out.csv"},
+		{"-scenario", "steps.yaml"},
+		{"-url", "http://h", "-unix-socket", "/var/run/docker.sock"},
+	} {
+		req := agentRequest{Args: args, Workers: 1}
+		if _, err := req.config(); err == nil {
+			t.Errorf("agent accepted %q", args)
+		}
+	}
+
+	// The coordinator refuses them before contacting any agent.
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	err := fs.Parse([]string{"-url", "http://h", "-data", "users.csv", "-agents", "a:7070", "-agent-token", "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runDistributed(context.Background(), cfg, fs); !errors.As(err, new(usageError)) {
+		t.Errorf("runDistributed with -data: err = %v, want a usage error", err)
+	}
+}
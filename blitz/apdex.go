@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Apdex scores a run against a target response time T: requests that
+// succeeded within T are satisfied, those within 4T tolerating, and the
+// slower ones and every failure frustrated. The score is
+// (satisfied + tolerating/2) / total, from 0 to 1.
+type Apdex struct {
+	T          time.Duration
+	Score      float64
+	Satisfied  int
+	Tolerating int
+	Frustrated int
+}
+
+// apdexRatings are the standard Apdex bands, best first.
+var apdexRatings = []struct {
+	min  float64
+	name string
+}{
+	{0.94, "Excellent"},
+	{0.85, "Good"},
+	{0.70, "Fair"},
+	{0.50, "Poor"},
+}
+
+// Rating names the band the score falls in.
+func (a Apdex) Rating() string {
+	for _, r := range apdexRatings {
+		if a.Score >= r.min {
+			return r.name
+		}
+	}
+	return "Unacceptable"
+}
+
+// String formats the score the way Apdex reports conventionally do,
+// e.g. "0.91 [300ms] Good".
+func (a Apdex) String() string {
+	// Rounded like -threshold values, so "apdex>0.9" reads the same.
+	return fmt.Sprintf("%.2f [%s] %s", math.Round(a.Score*100)/100, a.T, a.Rating())
+}
+
+// addApdex scores s against the -apdex-t target t, from the latency of
+// the successful requests. Latencies are bucketed by the histogram, so
+// one close to T or 4T may land on either side of it.
+func (c *collector) addApdex(s *Summary, t time.Duration) {
+	if t <= 0 || c.total == 0 {
+		return
+	}
+	counts := c.succeeded.Distribution([]time.Duration{t + 1, 4*t + 1})
+	a := &Apdex{T: t, Satisfied: int(counts[0]), Tolerating: int(counts[1])}
+	a.Frustrated = c.total - a.Satisfied - a.Tolerating
+	a.Score = (float64(a.Satisfied) + float64(a.Tolerating)/2) / float64(c.total)
+	s.Apdex = a
+}
+
+// apdexRow is the summary line for a, with the counts behind the score.
+func apdexRow(a Apdex) string {
+	return fmt.Sprintf("%s (%d satisfied, %d tolerating, %d frustrated)", a, a.Satisfied, a.Tolerating, a.Frustrated)
+}
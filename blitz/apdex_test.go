@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApdex(t *testing.T) {
+	c := newCollector()
+	add := func(n int, latency time.Duration, status int, err error) {
+		for i := 0; i < n; i++ {
+			c.Add(Result{Status: status, Latency: latency, Error: err})
+		}
+	}
+	add(60, 100*time.Millisecond, 200, nil)          // satisfied
+	add(20, 900*time.Millisecond, 200, nil)          // tolerating
+	add(10, 2*time.Second, 200, nil)                 // frustrated: too slow
+	add(5, 10*time.Millisecond, 500, nil)            // frustrated: failed, however fast
+	add(5, time.Millisecond, 0, errors.New("reset")) // frustrated: error
+
+	var s Summary
+	c.addApdex(&s, 300*time.Millisecond)
+	a := s.Apdex
+	if a == nil {
+		t.Fatal("addApdex() left Apdex nil")
+	}
+	if a.Satisfied != 60 || a.Tolerating != 20 || a.Frustrated != 20 {
+		t.Errorf("buckets = %d/%d/%d, want 60/20/20", a.Satisfied, a.Tolerating, a.Frustrated)
+	}
+	if a.Score != 0.7 || a.Rating() != "Fair" {
+		t.Errorf("score = %v (%s), want 0.7 (Fair)", a.Score, a.Rating())
+	}
+	if got, want := a.String(), "0.70 [300ms] Fair"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	var none Summary
+	c.addApdex(&none, 0)
+	if none.Apdex != nil {
+		t.Error("addApdex() without -apdex-t set a score")
+	}
+}
+
+func TestApdexSurvivesMerge(t *testing.T) {
+	a, b := newCollector(), newCollector()
+	a.Add(Result{Status: 200, Latency: 10 * time.Millisecond})
+	b.Add(Result{Status: 200, Latency: time.Second})
+	a.Merge(b)
+
+	var s Summary
+	a.addApdex(&s, 100*time.Millisecond)
+	if s.Apdex.Satisfied != 1 || s.Apdex.Frustrated != 1 {
+		t.Errorf("merged buckets = %+v, want one satisfied and one frustrated", *s.Apdex)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// expectations are per-response checks evaluated by workers as
+// responses arrive. A nil *expectations checks nothing.
+type expectations struct {
+	BodyContains []byte
+	Regexps      []*regexp.Regexp
+	JSONPaths    []*jsonPath
+	GraphQL      bool // fail responses carrying a GraphQL errors array
+}
+
+// newExpectations builds the per-response checks from cfg, or returns
+// nil if there are none.
+func newExpectations(cfg config) (*expectations, error) {
+	e := &expectations{BodyContains: []byte(cfg.ExpectBody), GraphQL: cfg.GraphQLQuery != ""}
+	for _, expr := range cfg.MatchRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -match-regex: %w", err)
+		}
+		e.Regexps = append(e.Regexps, re)
+	}
+	for _, expr := range cfg.MatchJSONPath {
+		p, err := parseJSONPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("-match-jsonpath: %w", err)
+		}
+		e.JSONPaths = append(e.JSONPaths, p)
+	}
+	if !e.needsBody() {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// needsBody reports whether responses must be buffered for checking.
+func (e *expectations) needsBody() bool {
+	return e != nil && (len(e.BodyContains) > 0 || len(e.Regexps) > 0 || len(e.JSONPaths) > 0 || e.GraphQL)
+}
+
+// checkBody returns an error describing why body failed expectations.
+func (e *expectations) checkBody(body []byte) error {
+	if e == nil {
+		return nil
+	}
+	if len(e.BodyContains) > 0 && !bytes.Contains(body, e.BodyContains) {
+		return fmt.Errorf("body does not contain %q", e.BodyContains)
+	}
+	for _, re := range e.Regexps {
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match /%s/", re)
+		}
+	}
+	for _, p := range e.JSONPaths {
+		if err := p.check(body); err != nil {
+			return err
+		}
+	}
+	if e.GraphQL {
+		return checkGraphQL(body)
+	}
+	return nil
+}
+
+// AssertionResult is the outcome of one run-level assertion.
+type AssertionResult struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// assertions are run-level checks evaluated against the final Summary.
+type assertions struct {
+	Statuses     []int
+	BodyContains string
+	MaxP95       time.Duration
+	Thresholds   []threshold
+}
+
+// evaluate checks the summary against every configured assertion.
+func (a assertions) evaluate(s Summary) []AssertionResult {
+	var out []AssertionResult
+
+	if len(a.Statuses) > 0 {
+		unexpected := s.Errors
+		for code, count := range s.StatusCodes {
+			if !slices.Contains(a.Statuses, code) {
+				unexpected += count
+			}
+		}
+		out = append(out, AssertionResult{
+			Name:   "expect-status " + joinInts(a.Statuses),
+			Passed: unexpected == 0,
+			Detail: fmt.Sprintf("%d unexpected responses", unexpected),
+		})
+	}
+
+	if a.BodyContains != "" {
+		out = append(out, AssertionResult{
+			Name:   fmt.Sprintf("expect-body-contains %q", a.BodyContains),
+			Passed: s.Mismatches == 0,
+			Detail: fmt.Sprintf("%d responses did not match", s.Mismatches),
+		})
+	}
+
+	if a.MaxP95 > 0 {
+		out = append(out, AssertionResult{
+			Name:   "expect-max-p95 " + a.MaxP95.String(),
+			Passed: s.Latency.P95 <= a.MaxP95,
+			Detail: "p95 was " + s.Latency.P95.Round(time.Millisecond).String(),
+		})
+	}
+
+	for _, t := range a.Thresholds {
+		out = append(out, t.evaluate(s))
+	}
+
+	return out
+}
+
+// allPassed reports whether every assertion passed.
+func allPassed(results []AssertionResult) bool {
+	for _, r := range results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// parseStatusList parses a comma-separated list of status codes.
+func parseStatusList(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var codes []int
+	for _, part := range strings.Split(s, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q", part)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func joinInts(list []int) string {
+	parts := make([]string, len(list))
+	for i, v := range list {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAssertionsEvaluate(t *testing.T) {
+	s := Summary{
+		StatusCodes: map[int]int{200: 9, 404: 1},
+		Mismatches:  0,
+		Latency:     LatencyStats{P95: 120 * time.Millisecond},
+	}
+
+	tests := []struct {
+		name string
+		a    assertions
+		want bool
+	}{
+		{"status allowed", assertions{Statuses: []int{200, 404}}, true},
+		{"status unexpected", assertions{Statuses: []int{200}}, false},
+		{"body matched", assertions{BodyContains: "ok"}, true},
+		{"p95 under limit", assertions{MaxP95: 200 * time.Millisecond}, true},
+		{"p95 over limit", assertions{MaxP95: 100 * time.Millisecond}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := tt.a.evaluate(s)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			if results[0].Passed != tt.want {
+				t.Errorf("Passed = %v, want %v (%s)", results[0].Passed, tt.want, results[0].Detail)
+			}
+		})
+	}
+}
+
+func TestParseStatusList(t *testing.T) {
+	codes, err := parseStatusList("200, 201,404")
+	if err != nil {
+		t.Fatalf("parseStatusList() error = %v", err)
+	}
+	if len(codes) != 3 || codes[0] != 200 || codes[2] != 404 {
+		t.Errorf("parseStatusList() = %v, want [200 201 404]", codes)
+	}
+
+	if _, err := parseStatusList("200,abc"); err == nil {
+		t.Error("expected error for non-numeric code")
+	}
+	if _, err := parseStatusList("42"); err == nil {
+		t.Error("expected error for out-of-range code")
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	s := Summary{
+		Total:   200,
+		Failed:  1,
+		RPS:     1500,
+		Latency: LatencyStats{P99: 450 * time.Millisecond},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"p99<500ms", true},
+		{"p99 < 400ms", false},
+		{"error_rate<1%", true},
+		{"error_rate<=0.004", false},
+		{"rps>1000", true},
+		{"rps>=2000", false},
+		{"failed<1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			ths, err := parseThresholds([]string{tt.expr})
+			if err != nil {
+				t.Fatalf("parseThresholds() error = %v", err)
+			}
+			if got := ths[0].evaluate(s); got.Passed != tt.want {
+				t.Errorf("Passed = %v, want %v (%s)", got.Passed, tt.want, got.Detail)
+			}
+		})
+	}
+}
+
+func TestParseThresholdsErrors(t *testing.T) {
+	if ths, err := parseThresholds([]string{"p95<1s,rps>10"}); err != nil || len(ths) != 2 {
+		t.Errorf("comma-separated thresholds = %v, %v; want 2", ths, err)
+	}
+	for _, bad := range []string{"p99", "latency<1s", "p99<fast", "rps=10"} {
+		if _, err := parseThresholds([]string{bad}); err == nil {
+			t.Errorf("parseThresholds(%q) expected error", bad)
+		}
+	}
+}
+
+func TestExpectationsMatch(t *testing.T) {
+	cfg := config{MatchRegex: stringList{`"status":\s*"ok"`}, MatchJSONPath: stringList{`$.count>=0`}}
+	if _, err := newExpectations(cfg); err == nil {
+		t.Error("unsupported jsonpath operator should be rejected")
+	}
+
+	cfg.MatchJSONPath = stringList{`$.count==2`}
+	e, err := newExpectations(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.checkBody([]byte(`{"status": "ok", "count": 2}`)); err != nil {
+		t.Errorf("matching body failed: %v", err)
+	}
+	if err := e.checkBody([]byte(`{"status": "error", "count": 2}`)); err == nil {
+		t.Error("body not matching -match-regex should fail")
+	}
+	if err := e.checkBody([]byte(`{"status": "ok", "count": 1}`)); err == nil {
+		t.Error("body not matching -match-jsonpath should fail")
+	}
+
+	if e, _ := newExpectations(config{}); e != nil {
+		t.Error("no checks configured should give nil expectations")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// Delta compares one metric of this run against a saved baseline.
+type Delta struct {
+	Name           string
+	Unit           string // "ms", "req/s" or "%"
+	Baseline       float64
+	Current        float64
+	HigherIsBetter bool
+}
+
+// Change returns the relative change from the baseline in percent, or
+// NaN when the baseline is zero.
+func (d Delta) Change() float64 {
+	if d.Baseline == 0 {
+		return math.NaN()
+	}
+	return (d.Current - d.Baseline) / d.Baseline * 100
+}
+
+// Improved reports whether the metric moved in the good direction.
+func (d Delta) Improved() bool {
+	if d.HigherIsBetter {
+		return d.Current > d.Baseline
+	}
+	return d.Current < d.Baseline
+}
+
+// saveBaseline writes s as a JSON report that a later run can -compare
+// against.
+func saveBaseline(path string, s Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := renderJSON(f, s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadBaseline reads a report written by -save-baseline or -output json.
+func loadBaseline(path string) (*jsonReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var base jsonReport
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	return &base, nil
+}
+
+// compareBaseline lists the headline metrics of s next to base.
+func compareBaseline(base *jsonReport, s Summary) []Delta {
+	errorRate := func(failed, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(failed) / float64(total) * 100
+	}
+	return []Delta{
+		{Name: "Requests/sec", Unit: "req/s", Baseline: base.RPS, Current: s.RPS, HigherIsBetter: true},
+		{Name: "Error rate", Unit: "%", Baseline: errorRate(base.Failed, base.TotalRequests), Current: errorRate(s.Failed, s.Total)},
+		{Name: "P50", Unit: "ms", Baseline: base.Latency.P50, Current: ms(s.Latency.P50)},
+		{Name: "P95", Unit: "ms", Baseline: base.Latency.P95, Current: ms(s.Latency.P95)},
+		{Name: "P99", Unit: "ms", Baseline: base.Latency.P99, Current: ms(s.Latency.P99)},
+	}
+}
+
+// renderComparison prints the baseline deltas, green where the metric
+// improved and red where it regressed.
+func renderComparison(w io.Writer, deltas []Delta) {
+	t := cli.NewTable("Metric", "Baseline", "Current", "Change")
+	t.Writer = w
+	for _, d := range deltas {
+		var change string
+		switch pct := d.Change(); {
+		case d.Current == d.Baseline:
+			change = "="
+		case math.IsNaN(pct):
+			change = "new"
+		default:
+			change = fmt.Sprintf("%+.1f%%", pct)
+		}
+		if d.Current != d.Baseline {
+			if d.Improved() {
+				change = cli.Success(change)
+			} else {
+				change = cli.Error(change)
+			}
+		}
+		t.AddRow(d.Name, formatDeltaValue(d.Baseline, d.Unit), formatDeltaValue(d.Current, d.Unit), change)
+	}
+	t.Render()
+}
+
+func formatDeltaValue(v float64, unit string) string {
+	switch unit {
+	case "ms":
+		return time.Duration(v * float64(time.Millisecond)).Round(time.Millisecond).String()
+	case "%":
+		return fmt.Sprintf("%.2f%%", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
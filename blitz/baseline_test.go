@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBaselineRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run1.json")
+	base := Summary{
+		Total: 100, Failed: 2, RPS: 500,
+		Latency: LatencyStats{P50: 10 * time.Millisecond, P95: 40 * time.Millisecond, P99: 80 * time.Millisecond},
+	}
+	if err := saveBaseline(path, base); err != nil {
+		t.Fatalf("saveBaseline() error = %v", err)
+	}
+	loaded, err := loadBaseline(path)
+	if err != nil {
+		t.Fatalf("loadBaseline() error = %v", err)
+	}
+
+	current := Summary{
+		Total: 100, Failed: 0, RPS: 400,
+		Latency: LatencyStats{P50: 10 * time.Millisecond, P95: 60 * time.Millisecond, P99: 80 * time.Millisecond},
+	}
+	deltas := make(map[string]Delta)
+	for _, d := range compareBaseline(loaded, current) {
+		deltas[d.Name] = d
+	}
+
+	if d := deltas["Requests/sec"]; d.Improved() || d.Change() != -20 {
+		t.Errorf("RPS delta = %+v (change %v), want a 20%% regression", d, d.Change())
+	}
+	if d := deltas["P95"]; d.Improved() || d.Change() != 50 {
+		t.Errorf("P95 delta = %+v (change %v), want a 50%% regression", d, d.Change())
+	}
+	if d := deltas["Error rate"]; !d.Improved() {
+		t.Errorf("error rate delta = %+v, want an improvement", d)
+	}
+	if d := (Delta{Baseline: 0, Current: 1}); !math.IsNaN(d.Change()) {
+		t.Errorf("Change() from a zero baseline = %v, want NaN", d.Change())
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+)
+
+// capture takes a value from a scenario step's response so later steps
+// in the same iteration can use it as {{.Name}}, such as the token a
+// login step returns. The value comes from a response header or a JSON
+// path into the body.
+type capture struct {
+	Name   string
+	Header string    // canonical header name; "" when Path is set
+	Path   *jsonPath // path into the JSON body; nil when Header is set
+}
+
+// parseCapture parses a step's capture source: "header:Name" for a
+// response header, or a JSON path such as $.data.token.
+func parseCapture(name, source string) (*capture, error) {
+	if name == "" {
+		return nil, fmt.Errorf("capture needs a variable name")
+	}
+	source = strings.TrimSpace(source)
+	if h, ok := strings.CutPrefix(source, "header:"); ok {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			return nil, fmt.Errorf("capture %s: empty header name", name)
+		}
+		return &capture{Name: name, Header: http.CanonicalHeaderKey(h)}, nil
+	}
+	if !strings.HasPrefix(source, "$") {
+		return nil, fmt.Errorf("capture %s: %q is neither header:NAME nor a JSON path", name, source)
+	}
+	p, err := parseJSONPath(source)
+	if err != nil {
+		return nil, fmt.Errorf("capture %s: %w", name, err)
+	}
+	if p.op != "" {
+		return nil, fmt.Errorf("capture %s: %q compares a value rather than selecting one", name, source)
+	}
+	return &capture{Name: name, Path: p}, nil
+}
+
+// captureError reports a capture whose value was missing from the
+// response. The response counts as failed and the iteration ends,
+// since the steps after it would run without the value.
+type captureError struct {
+	name string
+	err  error
+}
+
+func (e *captureError) Error() string {
+	return fmt.Sprintf("capture %s: %v", e.name, e.err)
+}
+
+// extract returns the captured value from a response. JSON strings are
+// used as-is and other JSON values in their compact encoding, so a
+// numeric ID reads 42 rather than "42".
+func (c *capture) extract(header http.Header, body []byte) (string, error) {
+	if c.Path == nil {
+		v := header.Get(c.Header)
+		if v == "" {
+			return "", fmt.Errorf("no %s header", c.Header)
+		}
+		return v, nil
+	}
+	v, err := c.Path.lookup(body)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", fmt.Errorf("%s: value is null", c.Path.expr)
+	case string:
+		return v, nil
+	}
+	data, _ := json.Marshal(v)
+	return string(data), nil
+}
+
+// captureAll runs every capture of tmpl against a response, returning
+// the values by name, or the first capture that found nothing.
+func (t *requestTemplate) captureAll(header http.Header, body []byte) (map[string]string, error) {
+	values := make(map[string]string, len(t.Capture))
+	for _, c := range t.Capture {
+		v, err := c.extract(header, body)
+		if err != nil {
+			return nil, &captureError{name: c.Name, err: err}
+		}
+		values[c.Name] = v
+	}
+	return values, nil
+}
+
+// capturesBody reports whether a response to tmpl must be read into
+// memory for its captures.
+func (t *requestTemplate) capturesBody() bool {
+	for _, c := range t.Capture {
+		if c.Path != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withCaptures returns vars with the captured values added, replacing
+// any data column of the same name. vars may be a shared data row, so
+// it is copied rather than modified.
+func withCaptures(vars, captured map[string]string) map[string]string {
+	merged := make(map[string]string, len(vars)+len(captured))
+	maps.Copy(merged, vars)
+	maps.Copy(merged, captured)
+	return merged
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCapture(t *testing.T) {
+	c, err := parseCapture("session", "header: x-session-id")
+	if err != nil || c.Header != "X-Session-Id" || c.Path != nil {
+		t.Errorf("header capture = %+v, %v; want X-Session-Id", c, err)
+	}
+	c, err = parseCapture("token", "$.data.token")
+	if err != nil || c.Path == nil {
+		t.Errorf("path capture = %+v, %v; want a JSON path", c, err)
+	}
+	for _, source := range []string{"", "header:", "token", "$.ok==true", "$.["} {
+		if _, err := parseCapture("x", source); err == nil {
+			t.Errorf("parseCapture(%q) should fail", source)
+		}
+	}
+}
+
+func TestCaptureExtract(t *testing.T) {
+	header := http.Header{"X-Request-Id": {"r-1"}}
+	body := []byte(`{"token":"abc","id":42,"user":{"admin":false},"gone":null}`)
+	for source, want := range map[string]string{
+		"header:X-Request-Id": "r-1",
+		"$.token":             "abc",
+		"$.id":                "42",
+		"$.user":              `{"admin":false}`,
+		"$.user.admin":        "false",
+	} {
+		c, _ := parseCapture("v", source)
+		if got, err := c.extract(header, body); err != nil || got != want {
+			t.Errorf("%s = %q, %v; want %q", source, got, err, want)
+		}
+	}
+	for _, source := range []string{"header:X-Missing", "$.missing", "$.gone"} {
+		c, _ := parseCapture("v", source)
+		if _, err := c.extract(header, body); err == nil {
+			t.Errorf("%s should find nothing", source)
+		}
+	}
+}
+
+// TestCaptureChain runs a login, profile scenario where the profile
+// step needs the token the login step returned.
+func TestCaptureChain(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			w.Header().Set("X-Session", "s-"+r.URL.Query().Get("user"))
+			w.Write([]byte(`{"auth":{"token":"t-` + r.URL.Query().Get("user") + `"}}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer t-ann" || r.URL.Query().Get("session") != "s-ann" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	data := `
+steps:
+  - name: login
+    url: /login?user={{.user}}
+    capture:
+      token: $.auth.token
+      session: header:X-Session
+  - name: me
+    url: /me?session={{.session}}
+    headers:
+      Authorization: Bearer {{.token}}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sc, err := loadScenario(path, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := &mix{}
+	targets.add(sc, 1)
+	if err := targets.compile(map[string]string{"user": "ann"}); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	ds := &dataSource{rows: []map[string]string{{"user": "ann"}}}
+	r := &requester{client: srv.Client(), targets: targets, data: ds}
+
+	results := make(chan Result, 2)
+	r.runIteration(context.Background(), job{}, results)
+	close(results)
+	var got []Result
+	for res := range results {
+		got = append(got, res)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d results, want 2", len(got))
+	}
+	if got[1].Status != http.StatusOK {
+		t.Errorf("me status = %d, want 200 with the captured token and session", got[1].Status)
+	}
+	if ds.rows[0]["token"] != "" {
+		t.Error("captures leaked into the shared data row")
+	}
+}
+
+func TestCaptureMissingEndsIteration(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	login, _ := parseCapture("token", "$.token")
+	sc := &scenario{Steps: []step{
+		{Tmpl: &requestTemplate{Name: "login", Method: http.MethodGet, URL: srv.URL, Capture: []*capture{login}}},
+		{Tmpl: &requestTemplate{Name: "me", Method: http.MethodGet, URL: srv.URL + "?t={{.token}}"}},
+	}}
+	targets := &mix{}
+	targets.add(sc, 1)
+	if err := targets.compile(nil); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	r := &requester{client: srv.Client(), targets: targets}
+
+	results := make(chan Result, 2)
+	r.runIteration(context.Background(), job{}, results)
+	close(results)
+	res := <-results
+	var capErr *captureError
+	if !errors.As(res.Mismatch, &capErr) || classify(res) != catCapture {
+		t.Errorf("login mismatch = %v, want a capture failure", res.Mismatch)
+	}
+	if _, more := <-results; more {
+		t.Error("the step after a failed capture should not run")
+	}
+}
+
+func TestCompileRejectsLaterCapture(t *testing.T) {
+	token, _ := parseCapture("token", "$.token")
+	sc := &scenario{Steps: []step{
+		{Tmpl: &requestTemplate{Name: "me", Method: http.MethodGet, URL: "http://api.test/?t={{.token}}"}},
+		{Tmpl: &requestTemplate{Name: "login", Method: http.MethodGet, URL: "http://api.test/login", Capture: []*capture{token}}},
+	}}
+	targets := &mix{}
+	targets.add(sc, 1)
+	if err := targets.compile(nil); err == nil {
+		t.Error("a step using a value captured after it should be rejected")
+	}
+}
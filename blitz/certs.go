@@ -0,0 +1,96 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// certRotator presents a different client certificate from a -cert-dir
+// on successive TLS handshakes, so one run looks like a fleet of
+// distinct mTLS clients. With every set all handshakes use the same
+// certificate and move on to the next one every interval; otherwise
+// each new connection takes the next certificate in turn.
+type certRotator struct {
+	certs []tls.Certificate
+	every time.Duration
+	start time.Time
+	next  atomic.Uint64
+}
+
+// loadCertDir loads every key pair in dir: each NAME.key with the
+// certificate in NAME.crt or NAME.pem beside it, in name order.
+func loadCertDir(dir string) ([]tls.Certificate, error) {
+	keys, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+	var certs []tls.Certificate
+	for _, key := range keys {
+		base := strings.TrimSuffix(key, ".key")
+		certFile := base + ".crt"
+		if _, err := os.Stat(certFile); err != nil {
+			certFile = base + ".pem"
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, key)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate for %s: %w", key, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no client certificates in %s (want NAME.key with NAME.crt or NAME.pem)", dir)
+	}
+	return certs, nil
+}
+
+func newCertRotator(dir string, every time.Duration) (*certRotator, error) {
+	certs, err := loadCertDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &certRotator{certs: certs, every: every, start: time.Now()}, nil
+}
+
+// GetClientCertificate picks the certificate for a handshake; it is
+// used as tls.Config.GetClientCertificate.
+func (r *certRotator) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	var i int
+	if r.every > 0 {
+		i = int(time.Since(r.start)/r.every) % len(r.certs)
+	} else {
+		i = int((r.next.Add(1) - 1) % uint64(len(r.certs)))
+	}
+	return &r.certs[i], nil
+}
+
+// closeIdleEvery closes t's idle connections every interval, so with
+// -cert-rotate kept-alive connections are redialed with the current
+// certificate; busy ones follow once their request completes. The
+// returned func stops it.
+func closeIdleEvery(t *http.Transport, every time.Duration) func() error {
+	ticker := time.NewTicker(every)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				t.CloseIdleConnections()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() error {
+		ticker.Stop()
+		close(done)
+		return nil
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed key pair for cn to dir as
+// cn.key and cn.crt.
+func writeClientCert(t *testing.T, dir, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(dir, cn+".crt"), certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, cn+".key"), keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertDirRotation(t *testing.T) {
+	dir := t.TempDir()
+	for _, cn := range []string{"a", "b", "c"} {
+		writeClientCert(t, dir, cn)
+	}
+
+	var mu sync.Mutex
+	var seen []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.TLS.PeerCertificates[0].Subject.CommonName)
+		mu.Unlock()
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	transport, err := newTransport(transportOptions{Insecure: true, CertDir: dir, DisableKeepAlive: true})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+
+	want := []string{"a", "b", "c", "a"}
+	if len(seen) != len(want) {
+		t.Fatalf("server saw %v, want %v", seen, want)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Errorf("connection %d used %s, want %s (all: %v)", i, seen[i], want[i], seen)
+		}
+	}
+}
+
+func TestCertRotatorInterval(t *testing.T) {
+	dir := t.TempDir()
+	writeClientCert(t, dir, "a")
+	writeClientCert(t, dir, "b")
+
+	r, err := newCertRotator(dir, time.Hour)
+	if err != nil {
+		t.Fatalf("newCertRotator() error = %v", err)
+	}
+	first, _ := r.GetClientCertificate(nil)
+	second, _ := r.GetClientCertificate(nil)
+	if first != second {
+		t.Error("handshakes within one -cert-rotate interval used different certificates")
+	}
+	r.start = r.start.Add(-time.Hour)
+	if third, _ := r.GetClientCertificate(nil); third == first {
+		t.Error("the next -cert-rotate interval kept the same certificate")
+	}
+}
+
+func TestLoadCertDirErrors(t *testing.T) {
+	if _, err := loadCertDir(t.TempDir()); err == nil {
+		t.Error("loadCertDir() of an empty directory succeeded, want an error")
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "orphan.key"), []byte("not a key"), 0o600)
+	if _, err := loadCertDir(dir); err == nil {
+		t.Error("loadCertDir() with a key but no certificate succeeded, want an error")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpoint is the state of a run that -checkpoint saves periodically:
+// the collected statistics, histograms included, and how long the run
+// had gone. -resume loads one and runs only what is left, so a long
+// soak test cut short by a laptop sleep or a network blip carries on
+// instead of starting over.
+type checkpoint struct {
+	Target  string        `json:"target"`
+	Saved   time.Time     `json:"saved"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+	Done    bool          `json:"done"` // the run finished; there is nothing to resume
+	Stats   *collector    `json:"stats"`
+}
+
+// saveCheckpoint writes cp to path. It writes a temporary file and
+// renames it into place, so an interruption mid-write leaves the
+// previous checkpoint intact.
+func saveCheckpoint(path string, cp checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// loadCheckpoint reads a checkpoint written by -checkpoint.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint %s: %w", path, err)
+	}
+	if cp.Stats == nil {
+		return nil, fmt.Errorf("parsing checkpoint %s: no statistics", path)
+	}
+	return &cp, nil
+}
+
+// resume trims cfg and sched to the part of the run cp has not covered
+// yet: the remaining requests of a -requests run, the remaining time of
+// a -duration run and the remaining stages of a schedule. steps is the
+// number of results each iteration produces.
+func (cp *checkpoint) resume(cfg *config, sched schedule, steps int) (schedule, error) {
+	switch {
+	case cp.Done:
+		return nil, errors.New("the checkpointed run already finished; there is nothing to resume")
+	case cp.Target != cfg.target():
+		return nil, fmt.Errorf("the checkpoint is of a run against %s, not %s", cp.Target, cfg.target())
+	}
+	if cfg.Requests > 0 {
+		cfg.Requests -= cp.Stats.total / max(steps, 1)
+		if cfg.Requests <= 0 {
+			return nil, errors.New("the checkpointed run already sent all its -requests")
+		}
+	}
+	if cfg.Duration > 0 {
+		cfg.Duration -= cp.Elapsed
+		if cfg.Duration <= 0 {
+			return nil, errors.New("the checkpointed run already lasted its -duration")
+		}
+	}
+	if l := sched.Length(); l > 0 && cp.Elapsed >= l {
+		return nil, errors.New("the checkpointed run already completed its schedule")
+	}
+	return sched.After(cp.Elapsed), nil
+}
+
+// statsAt returns the checkpointed statistics for a run resumed at
+// start. The timeline is keyed by wall-clock second, so it is moved
+// forward by the time the run was stopped; otherwise the gap would chart
+// as seconds without requests.
+func (cp *checkpoint) statsAt(start time.Time) *collector {
+	if !cp.Saved.IsZero() {
+		cp.Stats.timeline.Shift(start.Sub(cp.Saved))
+	}
+	return cp.Stats
+}
+
+// saveCheckpoint writes the state of the run so far to -checkpoint.
+func (t *loadTest) saveCheckpoint(stats *collector, elapsed time.Duration, done bool) error {
+	return saveCheckpoint(t.cfg.Checkpoint, checkpoint{
+		Target:  t.cfg.target(),
+		Saved:   time.Now(),
+		Elapsed: elapsed,
+		Done:    done,
+		Stats:   stats,
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NickDiPreta/gokit/loadtest"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	stats := newCollector()
+	for _, r := range sampleResults() {
+		stats.Add(r)
+	}
+	path := filepath.Join(t.TempDir(), "cp.json")
+	cp := checkpoint{Target: "http://localhost", Elapsed: 90 * time.Second, Stats: stats}
+	if err := saveCheckpoint(path, cp); err != nil {
+		t.Fatalf("saveCheckpoint() error = %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("checkpoint directory has %d entries, want only the checkpoint", len(entries))
+	}
+
+	loaded, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error = %v", err)
+	}
+	if loaded.Elapsed != cp.Elapsed || loaded.Target != cp.Target {
+		t.Errorf("loaded %+v, want elapsed %v and target %s", loaded, cp.Elapsed, cp.Target)
+	}
+	want := stats.Summary(cp.Elapsed)
+	got := loaded.Stats.Summary(loaded.Elapsed)
+	if got.Total != want.Total || got.Failed != want.Failed || got.Latency.P99 != want.Latency.P99 {
+		t.Errorf("resumed summary = %d total, %d failed, p99 %v; want %d, %d, %v",
+			got.Total, got.Failed, got.Latency.P99, want.Total, want.Failed, want.Latency.P99)
+	}
+}
+
+func TestCheckpointResumeTimeline(t *testing.T) {
+	stats := newCollector()
+	for i := range 10 {
+		stats.Add(Result{Status: 200, Latency: time.Millisecond, Timestamp: time.Unix(1000+int64(i), 0)})
+	}
+	path := filepath.Join(t.TempDir(), "cp.json")
+	err := saveCheckpoint(path, checkpoint{Target: "http://localhost", Saved: time.Unix(1010, 0), Elapsed: 10 * time.Second, Stats: stats})
+	if err != nil {
+		t.Fatalf("saveCheckpoint() error = %v", err)
+	}
+	cp, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error = %v", err)
+	}
+
+	// Resumed an hour after the checkpoint was saved.
+	start := time.Unix(4610, 0)
+	resumed := cp.statsAt(start)
+	resumed.Add(Result{Status: 200, Latency: time.Millisecond, Timestamp: start})
+
+	points := resumed.Summary(11 * time.Second).Timeline
+	if len(points) != 11 {
+		t.Fatalf("resumed timeline has %d points, want 11 without the gap", len(points))
+	}
+	for i, p := range points {
+		if p.Requests != 1 {
+			t.Errorf("second %d: %d requests, want 1", i, p.Requests)
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	stats := newCollector()
+	for i := 0; i < 30; i++ {
+		stats.Add(Result{Status: 200, Latency: time.Millisecond})
+	}
+	cp := &checkpoint{Target: "http://localhost", Elapsed: 40 * time.Second, Stats: stats}
+
+	cfg := config{URLs: urlList{{URL: "http://localhost"}}, Requests: 100, Duration: time.Minute}
+	sched, err := cp.resume(&cfg, loadtest.ConstantRate(10), 1)
+	if err != nil {
+		t.Fatalf("resume() error = %v", err)
+	}
+	if cfg.Requests != 70 || cfg.Duration != 20*time.Second {
+		t.Errorf("resumed -requests %d, -duration %v; want 70 and 20s", cfg.Requests, cfg.Duration)
+	}
+	if len(sched) != 1 || sched[0].From != 10 {
+		t.Errorf("resumed schedule = %+v, want the constant rate", sched)
+	}
+
+	tests := []struct {
+		name string
+		cfg  config
+		cp   checkpoint
+	}{
+		{"finished", config{URLs: urlList{{URL: "http://localhost"}}}, checkpoint{Target: "http://localhost", Done: true, Stats: stats}},
+		{"other target", config{URLs: urlList{{URL: "http://example.com"}}}, checkpoint{Target: "http://localhost", Stats: stats}},
+		{"requests sent", config{URLs: urlList{{URL: "http://localhost"}}, Requests: 30}, checkpoint{Target: "http://localhost", Stats: stats}},
+		{"duration over", config{URLs: urlList{{URL: "http://localhost"}}, Duration: 40 * time.Second}, checkpoint{Target: "http://localhost", Elapsed: 40 * time.Second, Stats: stats}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.cp.resume(&tt.cfg, nil, 1); err == nil {
+			t.Errorf("%s: resume() succeeded, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// acceptEncodings maps -compression values to the Accept-Encoding header
+// sent. With any of them set blitz reads bodies itself instead of
+// letting the transport ask for gzip and decode it transparently, so the
+// bytes on the wire can be told apart from the decoded size.
+var acceptEncodings = map[string]string{
+	"gzip": "gzip",
+	"br":   "br",
+	"none": "identity",
+}
+
+// parseCompression validates a -compression value.
+func parseCompression(s string) (string, error) {
+	if _, ok := acceptEncodings[s]; !ok && s != "" {
+		return "", fmt.Errorf("unknown -compression %q (use gzip, br or none)", s)
+	}
+	return s, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// wireBody returns the reader a -compression response body should be
+// read through, the counter of its bytes as sent and whether it arrived
+// encoded. gzip bodies are decoded; there is no brotli decoder, so br
+// bodies are read as sent.
+func wireBody(resp *http.Response) (io.Reader, *countingReader, bool, error) {
+	wire := &countingReader{r: resp.Body}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return wire, wire, false, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(wire)
+		switch {
+		case errors.Is(err, io.EOF):
+			return wire, wire, true, nil // no body, as for HEAD or 304
+		case err != nil:
+			return nil, wire, true, fmt.Errorf("decoding gzip body: %w", err)
+		}
+		return gz, wire, true, nil
+	}
+	return wire, wire, true, nil
+}
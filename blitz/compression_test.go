@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	payload := strings.Repeat("blitz ", 1000)
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	var accepted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = append(accepted, r.Header.Get("Accept-Encoding"))
+		switch r.Header.Get("Accept-Encoding") {
+		case "gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(zipped.Bytes())
+		case "br":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte("not really brotli"))
+		default:
+			w.Write([]byte(payload))
+		}
+	}))
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}
+	get := func(encoding string) Result {
+		r := &requester{client: srv.Client(), encoding: encoding, expect: &expectations{BodyContains: []byte("blitz")}}
+		if encoding == "br" {
+			r.expect = nil
+		}
+		return r.makeRequest(context.Background(), tmpl, nil)
+	}
+
+	res := get("gzip")
+	if res.Error != nil || res.Mismatch != nil {
+		t.Fatalf("gzip request failed: %v %v", res.Error, res.Mismatch)
+	}
+	if res.Bytes != int64(len(payload)) || res.WireBytes != int64(zipped.Len()) || !res.Encoded {
+		t.Errorf("gzip: Bytes = %d, WireBytes = %d, Encoded = %v; want %d, %d, true",
+			res.Bytes, res.WireBytes, res.Encoded, len(payload), zipped.Len())
+	}
+
+	res = get("none")
+	if res.Bytes != int64(len(payload)) || res.WireBytes != res.Bytes || res.Encoded {
+		t.Errorf("none: Bytes = %d, WireBytes = %d, Encoded = %v", res.Bytes, res.WireBytes, res.Encoded)
+	}
+
+	res = get("br")
+	if res.Error != nil || !res.Encoded || res.WireBytes != res.Bytes {
+		t.Errorf("br: %+v, want the body counted as sent", res)
+	}
+
+	if want := []string{"gzip", "identity", "br"}; strings.Join(accepted, ",") != strings.Join(want, ",") {
+		t.Errorf("Accept-Encoding sent = %q, want %q", accepted, want)
+	}
+
+	c := newCollector()
+	c.Add(get("gzip"))
+	c.Add(get("none"))
+	s := c.Summary(0)
+	if s.Bytes.Wire != int64(zipped.Len()+len(payload)) || s.Bytes.Encoded != 1 {
+		t.Errorf("summary Wire = %d, Encoded = %d", s.Bytes.Wire, s.Bytes.Encoded)
+	}
+	if row, ok := wireRow(s.Bytes); !ok || !strings.Contains(row, "% of decoded") {
+		t.Errorf("wireRow() = %q, %v", row, ok)
+	}
+}
+
+func TestCompressionBadGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write([]byte("plain text"))
+	}))
+	defer srv.Close()
+
+	r := &requester{client: srv.Client(), encoding: "gzip"}
+	res := r.makeRequest(context.Background(), &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}, nil)
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "gzip") {
+		t.Errorf("error = %v, want a gzip decoding failure", res.Error)
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for _, v := range []string{"", "gzip", "br", "none"} {
+		if _, err := parseCompression(v); err != nil {
+			t.Errorf("parseCompression(%q) error = %v", v, err)
+		}
+	}
+	if _, err := parseCompression("zstd"); err == nil {
+		t.Error("parseCompression accepted zstd")
+	}
+}
+
+func TestCompressionHashBodies(t *testing.T) {
+	payload := strings.Repeat("blitz ", 1000)
+	var zipped bytes.Buffer
+	zw := gzip.NewWriter(&zipped)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(zipped.Bytes())
+	}))
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}
+	r := &requester{client: srv.Client(), encoding: "gzip", hashBody: true}
+	res := r.makeRequest(context.Background(), tmpl, nil)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	sum := sha256.Sum256([]byte(payload))
+	if want := hex.EncodeToString(sum[:bodyHashLen]); res.BodyHash != want {
+		t.Errorf("BodyHash = %s, want the hash of the decoded body %s", res.BodyHash, want)
+	}
+	if res.Bytes != int64(len(payload)) || res.WireBytes != int64(zipped.Len()) {
+		t.Errorf("Bytes = %d, WireBytes = %d; want %d decoded and %d on the wire",
+			res.Bytes, res.WireBytes, len(payload), zipped.Len())
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// config holds every option for a load test run. Fields are bound
+// directly to command-line flags by registerFlags.
+type config struct {
+	ConfigFile string
+	Profile    string
+
+	Requests int
+	Workers  int
+	URLs     urlList
+	URLFile  string
+	Sitemap  string
+	Rate     float64
+	Duration time.Duration
+	Stages   string
+	RampUp   time.Duration
+	Burst    string
+	BurstLen time.Duration
+	Think    thinkTime
+
+	MaxInflight int
+	Connections int
+
+	Method       string
+	Body         string
+	BodyFile     string
+	Form         stringList
+	FileUploads  stringList
+	GraphQLQuery string
+	GraphQLVars  string
+	Headers      headerList
+	RequestID    string
+	BasicAuth    string
+	Bearer       string
+	Cookies      bool
+	MaxRedirects int
+	NoFollow     bool
+	Retries      int
+	RetryBackoff time.Duration
+	Timeout      time.Duration
+	ReqTimeout   time.Duration
+	Hedge        time.Duration
+	RetryAfter   string
+	NoPreflight  bool
+	Scenario     string
+	HAR          string
+	HARSpeed     float64
+	HARAllHosts  bool
+	Data         string
+	DataMode     string
+
+	Output       string
+	OutputFile   string
+	Report       string
+	JUnit        string
+	Heatmap      string
+	Record       string
+	Checkpoint   string
+	CheckpointIn time.Duration
+	Resume       string
+	SaveErrors   string
+	SaveErrorsN  int
+	SlowAfter    time.Duration
+	SlowFile     string
+	SlowMax      int
+	HashBodies   bool
+	DiscardBody  bool
+	MaxBodyRead  int64
+	Compression  string
+	Live         bool
+	Quiet        bool
+	SelfStats    bool
+	Percentiles  string
+	ApdexT       time.Duration
+	Interval     time.Duration
+	IntervalFile string
+	MetricsAddr  string
+	SaveBaseline string
+	Tag          string
+	HistoryFile  string
+	Compare      string
+	OTLPEndpoint string
+	OTLPTraces   bool
+	StatsD       string
+	StatsDPrefix string
+	StatsDTags   bool
+
+	Transport transportOptions
+	GRPC      grpcOptions
+	WSRate    float64
+	TCPHold   time.Duration
+
+	Agents     string
+	AgentToken string
+
+	FindMax      bool
+	MaxErrorRate float64
+	MaxP99       time.Duration
+
+	SuccessCodes string
+
+	ExpectStatus   string
+	ExpectBody     string
+	MatchRegex     stringList
+	MatchJSONPath  stringList
+	ExpectP95      time.Duration
+	Thresholds     stringList
+	AbortErrorRate float64
+
+	requestsSet bool    // -requests was given explicitly
+	methodSet   bool    // -method was given explicitly
+	listedFrom  string  // where a -url-file or -sitemap list was read from; "" for -url targets
+	share       float64 // fraction of the rate this process generates; 0 for all of it
+}
+
+// stringList collects the values of a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// registerFlags binds every config field to a flag on fs.
+func (c *config) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.ConfigFile, "config", "", "Load options from a YAML/JSON file keyed by flag name; command-line flags take precedence")
+	fs.StringVar(&c.Profile, "profile", "", "Apply this named profile from the -config file's profiles (e.g. smoke, soak or spike) over its top-level options")
+
+	// Load shape
+	fs.IntVar(&c.Requests, "requests", 50, "How many requests to send")
+	fs.IntVar(&c.Workers, "workers", 10, "How many workers to use")
+	fs.Var(&c.URLs, "url", "Target URL to stress test; repeat with an optional weight to mix targets, as an '=weight' suffix or, for URLs with a query, after a space (relative paths resolve against the first URL)")
+	fs.StringVar(&c.URLFile, "url-file", "", "Read target URLs from this file, one per line, and send to them in turn; '-' reads stdin, so a pipeline can generate the list (relative lines resolve against -url)")
+	fs.StringVar(&c.Sitemap, "sitemap", "", "Fetch this sitemap.xml (or sitemap index) and spread the load across the URLs it lists, in turn")
+	fs.Var(abURL{&c.URLs, "A"}, "url-a", "Compare two targets side by side: the A target, e.g. the current deployment (optional '=weight' suffix sets the split)")
+	fs.Var(abURL{&c.URLs, "B"}, "url-b", "The B target compared against -url-a, e.g. a canary")
+	fs.Var((*rateFlag)(&c.Rate), "rate", "Set the maximum requests per second; fractions such as 0.5 and per-minute or per-hour counts such as 120/m or 30/h pace slow drip tests")
+	fs.DurationVar(&c.Duration, "duration", 0, "Run for this long instead of a fixed request count (e.g. 30s, 5m)")
+	fs.StringVar(&c.Stages, "stages", "", "Staged load profile, e.g. \"0-30s:10rps,30s-2m:10-100rps\" (overrides -rate)")
+	fs.DurationVar(&c.RampUp, "ramp-up", 0, "Ramp linearly from 0 to -rate over this duration")
+	fs.StringVar(&c.Burst, "burst", "", "Add spikes on top of the rate, as count@interval: 500@10s sends 500 extra requests every 10s")
+	fs.DurationVar(&c.BurstLen, "burst-length", time.Second, "Spread each -burst over this long")
+	fs.IntVar(&c.MaxInflight, "max-inflight", 0, "Cap on requests outstanding at once across all workers, 0 for no cap beyond -workers")
+	fs.IntVar(&c.Connections, "connections", 0, "Open exactly this many HTTP/1.1 keep-alive connections per host before the run and send every request over them, like wrk (sets -workers unless given)")
+	fs.DurationVar(&c.Think.Mean, "think", 0, "Pause each worker this long between iterations, like a user between clicks")
+	fs.DurationVar(&c.Think.Jitter, "think-jitter", 0, "Vary each -think pause uniformly by up to this much either way")
+	fs.BoolVar(&c.Think.Poisson, "think-poisson", false, "Draw -think pauses from an exponential distribution, so each worker's requests arrive as a Poisson process")
+
+	// Request
+	fs.StringVar(&c.Method, "method", http.MethodGet, "HTTP method to use (GET, POST, PUT, DELETE, ...)")
+	fs.StringVar(&c.Body, "body", "", "Request body to send with each request; {{uuid}}, {{seq}}, {{now}} and {{rand N}} vary it per request")
+	fs.StringVar(&c.BodyFile, "body-file", "", "Path to a file whose contents are sent as the request body")
+	fs.Var(&c.Form, "form", "Send a form field as key=value (repeatable); the body is URL-encoded, or multipart with -file-upload, and the method defaults to POST")
+	fs.Var(&c.FileUploads, "file-upload", "Upload a file in a multipart/form-data body as field=@path (repeatable)")
+	fs.StringVar(&c.GraphQLQuery, "graphql-query", "", "POST the GraphQL query in this file as a JSON body, counting responses with an errors array as failures")
+	fs.StringVar(&c.GraphQLVars, "graphql-vars", "", "JSON file of variables for -graphql-query")
+	fs.Var(&c.Headers, "header", "Request header as 'Key: Value' (repeatable); values can vary per request with {{uuid}}, {{seq}}, {{now}} or {{rand N}}")
+	fs.StringVar(&c.RequestID, "request-id-header", "", "Send a unique ID in this header (e.g. X-Request-ID) on every request and include it in -record output")
+	fs.StringVar(&c.Compression, "compression", "", "Ask for gzip, br or none (identity) bodies instead of the transport's transparent gzip, and report bytes on the wire; br bodies are not decoded")
+	fs.StringVar(&c.BasicAuth, "basic-auth", "", "Send HTTP basic auth credentials as user:pass")
+	fs.StringVar(&c.Bearer, "bearer", "", "Send this token as an 'Authorization: Bearer' header")
+	fs.BoolVar(&c.Cookies, "cookies", false, "Give each worker its own cookie jar so session cookies carry across requests")
+	fs.IntVar(&c.MaxRedirects, "max-redirects", 10, "Maximum redirects to follow before failing a request")
+	fs.BoolVar(&c.NoFollow, "no-follow", false, "Don't follow redirects; the 3xx response itself is measured and counts as a success")
+	fs.IntVar(&c.Retries, "retries", 0, "Resend a request up to this many times after a connection reset, refusal or timeout")
+	fs.DurationVar(&c.RetryBackoff, "retry-backoff", 100*time.Millisecond, "Delay before the first retry, doubled for each further attempt")
+	fs.StringVar(&c.SuccessCodes, "success-codes", "", "Comma-separated HTTP status codes that count as success instead of any 2xx (e.g. 200,201,404)")
+	fs.DurationVar(&c.Timeout, "timeout", 30*time.Second, "HTTP client timeout covering the whole exchange, including redirects and reading the body (0 for none)")
+	fs.DurationVar(&c.ReqTimeout, "request-timeout", 0, "Deadline for each attempt, applied through the request context; also applies to -grpc calls")
+	fs.StringVar(&c.RetryAfter, "retry-after", "", "Honor Retry-After on 429 and 503 responses by pausing that virtual user ('worker') or every worker ('global'), and report the time backed off")
+	fs.DurationVar(&c.Hedge, "hedge", 0, "Send a duplicate of any request still unanswered after this long and use whichever answers first (e.g. 100ms)")
+	fs.BoolVar(&c.NoPreflight, "no-preflight", false, "Skip the single request to each host that checks it is reachable before the run starts")
+	fs.StringVar(&c.Data, "data", "", "CSV file whose columns fill {{.column}} placeholders in URL, headers and body")
+	fs.StringVar(&c.DataMode, "data-order", "sequential", "Order rows are used from -data: sequential or random")
+	fs.StringVar(&c.Scenario, "scenario", "", "YAML/JSON file of request steps each virtual user runs in order")
+	fs.StringVar(&c.HAR, "har", "", "Replay the requests in this browser-exported HAR file, in order, as each virtual user's scenario (-url retargets them to another host)")
+	fs.Float64Var(&c.HARSpeed, "har-speed", 1, "Divide the recorded pauses between -har requests by this factor; 0 replays them back to back")
+	fs.BoolVar(&c.HARAllHosts, "har-all-hosts", false, "Also replay -har requests to hosts other than the first request's, such as CDNs and analytics")
+
+	// gRPC
+	fs.StringVar(&c.GRPC.Method, "grpc", "", "Load test this unary gRPC method (package.Service/Method) at a grpc:// or grpcs:// -url; -body is the JSON request")
+	fs.StringVar(&c.GRPC.ProtoFile, "proto", "", "Read the -grpc service definition from this .proto file instead of server reflection")
+	fs.Var(&c.GRPC.ImportPaths, "import-path", "Directory to search for -proto imports (repeatable; default: the file's directory)")
+
+	// WebSocket
+	fs.Float64Var(&c.WSRate, "ws-rate", 0, "Messages per second per connection for ws:// and wss:// targets (overrides -rate)")
+
+	// TCP
+	fs.DurationVar(&c.TCPHold, "tcp-hold", 0, "Keep each tcp:// connection open this long before closing it; a peer closing it first is a failure")
+
+	// Output
+	fs.StringVar(&c.Output, "output", "text", "Summary format: text, json or markdown")
+	fs.StringVar(&c.OutputFile, "output-file", "", "Write the summary to this file instead of stdout")
+	fs.StringVar(&c.Report, "report", "", "Also write a self-contained HTML report with charts to this file")
+	fs.StringVar(&c.Heatmap, "heatmap", "", "Also write latency counts per second and latency bucket to this CSV file, for plotting a latency heatmap")
+	fs.StringVar(&c.JUnit, "junit", "", "Also write the assertion and -threshold results to this file as JUnit XML, for CI systems such as Jenkins and GitLab")
+	fs.StringVar(&c.Percentiles, "percentiles", "", "Comma-separated latency percentiles to report instead of 50,95,99 (e.g. 50,90,99,99.9)")
+	fs.DurationVar(&c.ApdexT, "apdex-t", 0, "Report an Apdex score with this target response time T: successes within T satisfy, within 4T tolerate, and the rest frustrate (e.g. 300ms)")
+	fs.BoolVar(&c.Live, "live", false, "Show a live dashboard with rolling latency percentiles instead of the progress line")
+	fs.DurationVar(&c.Interval, "report-interval", 0, "Print a snapshot of each interval's throughput, failures and latency this often (e.g. 1m), for a trend over long soak tests")
+	fs.StringVar(&c.IntervalFile, "report-interval-file", "", "Append -report-interval snapshots to this file as NDJSON instead of printing them")
+	fs.BoolVar(&c.SelfStats, "selfstats", false, "Report blitz's own CPU, memory, goroutine and GC use at the end, to spot runs limited by the load generator rather than the target")
+	fs.BoolVar(&c.Quiet, "quiet", false, "Print nothing but the summary in the -output format, without progress or colors, for scripts and cron jobs (-report-interval snapshots still go to stderr)")
+	fs.StringVar(&c.MetricsAddr, "metrics-addr", "", "Serve live Prometheus metrics at http://ADDR/metrics during the run (e.g. :9090)")
+	fs.StringVar(&c.SaveBaseline, "save-baseline", "", "Save this run's results to a JSON file for later -compare")
+	fs.StringVar(&c.Tag, "tag", "", "Record this run under a tag (e.g. release-1.4) in the -history-file, for review with 'blitz history'")
+	fs.StringVar(&c.HistoryFile, "history-file", defaultHistoryFile, "Append-only JSONL file that -tag records runs in")
+	fs.StringVar(&c.Compare, "compare", "", "Compare this run against a baseline saved with -save-baseline")
+	fs.StringVar(&c.OTLPEndpoint, "otlp-endpoint", "", "Push run metrics to this OTLP/HTTP collector (e.g. http://localhost:4318)")
+	fs.StringVar(&c.StatsD, "statsd", "", "Send request counters and latency timings to this StatsD server over UDP during the run (e.g. localhost:8125)")
+	fs.StringVar(&c.StatsDPrefix, "statsd-prefix", "blitz", "Prefix for -statsd metric names")
+	fs.BoolVar(&c.StatsDTags, "statsd-tags", false, "Add DogStatsD tags (status, step and -tag) to -statsd metrics")
+	fs.BoolVar(&c.OTLPTraces, "otlp-traces", false, "Also export a span per request, sending its trace ID to the target in a traceparent header")
+	fs.BoolVar(&c.DiscardBody, "discard-body", false, "Close each response once its headers arrive, without reading the body, when only time to first byte matters")
+	fs.Int64Var(&c.MaxBodyRead, "max-body-read", 0, "Read at most this many bytes of each response body, then close it (0 reads whole bodies)")
+	fs.BoolVar(&c.HashBodies, "hash-bodies", false, "Hash every response body and report the distinct bodies per endpoint, to catch inconsistent or partial responses")
+	fs.StringVar(&c.SaveErrors, "save-errors", "", "Write the first failed responses (status, headers and up to 64KiB of body) to files in this directory")
+	fs.IntVar(&c.SaveErrorsN, "save-errors-max", 10, "How many failed responses -save-errors keeps")
+	fs.DurationVar(&c.SlowAfter, "slow-threshold", 0, "Log the timing breakdown (DNS, connect, TLS, TTFB, download) of requests slower than this, e.g. 1s")
+	fs.StringVar(&c.SlowFile, "slow-file", "", "Write -slow-threshold entries to this file as NDJSON instead of stderr")
+	fs.IntVar(&c.SlowMax, "slow-max", 20, "How many slow requests -slow-threshold logs")
+	fs.StringVar(&c.Record, "record", "", "Stream every result to this file (.csv for CSV, otherwise NDJSON)")
+	fs.StringVar(&c.Checkpoint, "checkpoint", "", "Periodically save the run's progress and statistics to this file, for -resume")
+	fs.DurationVar(&c.CheckpointIn, "checkpoint-interval", 30*time.Second, "How often -checkpoint saves")
+	fs.StringVar(&c.Resume, "resume", "", "Continue the interrupted run saved in this -checkpoint file, running only the requests, time or stages it had left; keeps checkpointing to the same file unless -checkpoint names another")
+
+	// Transport
+	fs.BoolVar(&c.Transport.Insecure, "insecure", false, "Skip TLS certificate verification")
+	fs.StringVar(&c.Transport.CACert, "cacert", "", "PEM file of CA certificates to trust")
+	fs.StringVar(&c.Transport.ClientCert, "cert", "", "PEM client certificate for mutual TLS")
+	fs.StringVar(&c.Transport.ClientKey, "key", "", "PEM private key for -cert")
+	fs.StringVar(&c.Transport.CertDir, "cert-dir", "", "Rotate through the client certificates in this directory (NAME.key with NAME.crt or NAME.pem), a different one per connection, to look like a fleet of mTLS clients")
+	fs.DurationVar(&c.Transport.CertRotate, "cert-rotate", 0, "Instead of one -cert-dir certificate per connection, switch every connection to the next one this often (e.g. 1m)")
+	fs.StringVar(&c.Transport.TLSMinVersion, "tls-min-version", "", "Minimum TLS version: 1.0, 1.1, 1.2 or 1.3")
+	fs.BoolVar(&c.Transport.HTTP1, "http1", false, "Force HTTP/1.1")
+	fs.BoolVar(&c.Transport.HTTP2, "http2", false, "Force HTTP/2 (h2 over TLS, h2c prior knowledge over plain HTTP)")
+	fs.BoolVar(&c.Transport.HTTP3, "http3", false, "Send HTTP/3 over QUIC, to compare against HTTP/2 under the same load (experimental; https:// targets only)")
+	fs.StringVar(&c.Transport.Proxy, "proxy", "", "Proxy URL (http://, https:// or socks5://); defaults to HTTP_PROXY/HTTPS_PROXY")
+	fs.Var(&c.Transport.Resolve, "resolve", "Dial host:port at addr instead of resolving it, as host:port:addr (repeatable)")
+	fs.StringVar(&c.Transport.UnixSocket, "unix-socket", "", "Connect to this Unix domain socket instead of the URL's host")
+	fs.BoolVar(&c.Transport.IPv4Only, "4", false, "Connect over IPv4 only")
+	fs.BoolVar(&c.Transport.IPv6Only, "6", false, "Connect over IPv6 only")
+	fs.StringVar(&c.Transport.DNSServer, "dns-server", "", "Resolve hosts with this DNS server (ip or ip:port) instead of the system resolver")
+	fs.BoolVar(&c.Transport.DNSCache, "dns-cache", false, "Resolve each host once and reuse the addresses for every connection")
+	fs.BoolVar(&c.Transport.DNSFresh, "dns-fresh", false, "Do a fresh DNS lookup for every request, bypassing system caches (implies -disable-keepalive)")
+	fs.BoolVar(&c.Transport.DisableKeepAlive, "disable-keepalive", false, "Open a new connection for every request")
+	fs.IntVar(&c.Transport.MaxIdleConns, "max-idle-conns", 0, "Idle connections to keep per host (default: one per worker)")
+	fs.IntVar(&c.Transport.MaxConnsPerHost, "max-conns-per-host", 0, "Maximum connections per host, 0 for unlimited")
+
+	// Find max
+	fs.BoolVar(&c.FindMax, "find-max", false, "Search for the highest sustainable rate: double -rate (default 10) until a probe breaks the limits, then binary search; each probe lasts -duration (default 10s)")
+	fs.Float64Var(&c.MaxErrorRate, "max-error-rate", 0.01, "Highest failure rate -find-max treats as sustainable")
+	fs.DurationVar(&c.MaxP99, "max-p99", 0, "Highest p99 latency -find-max treats as sustainable (default: no limit)")
+
+	// Distributed
+	fs.StringVar(&c.Agents, "agents", "", "Split the load across these comma-separated 'blitz agent' addresses and report the combined results")
+	fs.StringVar(&c.AgentToken, "agent-token", "", "Token the -agents were started with (default: $"+agentTokenEnv+")")
+
+	// Assertions
+	fs.StringVar(&c.ExpectStatus, "expect-status", "", "Fail the run unless every response has one of these status codes (e.g. 200,201)")
+	fs.StringVar(&c.ExpectBody, "expect-body-contains", "", "Fail the run unless every response body contains this string")
+	fs.Var(&c.MatchRegex, "match-regex", "Count a response as failed unless its body matches this regular expression (repeatable)")
+	fs.Var(&c.MatchJSONPath, "match-jsonpath", "Count a response as failed unless its JSON body satisfies this path check, e.g. '$.status==\"ok\"' or '$.data.items[0]' (repeatable)")
+	fs.DurationVar(&c.ExpectP95, "expect-max-p95", 0, "Fail the run if p95 latency exceeds this duration")
+	fs.Var(&c.Thresholds, "threshold", "Fail the run unless a metric meets this SLO, e.g. \"p99<500ms\", \"error_rate<1%\", \"rps>1000\", \"apdex>0.9\" (repeatable or comma-separated)")
+	fs.Float64Var(&c.AbortErrorRate, "abort-on-error-rate", 0, "Stop the run early once the failure rate over the last 100 requests exceeds this fraction (e.g. 0.2)")
+}
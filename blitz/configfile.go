@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyConfigFile sets every flag named in the YAML/JSON file at path
+// that was not already given on the command line, so CLI flags always
+// win. Keys are flag names (underscores may stand in for dashes); list
+// values set repeatable flags once per item, and "header" may also be a
+// map of header names to values.
+//
+// A "profiles" map may name sets of options, such as smoke, soak and
+// spike; the one chosen by profile is laid over the top-level options,
+// replacing any it repeats, lists included.
+func applyConfigFile(fs *flag.FlagSet, path, profile string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	// YAML is a superset of JSON, so one decoder handles both formats.
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	var profiles map[string]map[string]any
+	if raw, ok := values["profiles"]; ok {
+		delete(values, "profiles")
+		if profiles, err = configProfiles(raw); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+
+	options := make(map[string]any)
+	add := func(set map[string]any, where string) error {
+		for key, v := range set {
+			name := strings.ReplaceAll(key, "_", "-")
+			if fs.Lookup(name) == nil || name == "config" || name == "profile" {
+				return fmt.Errorf("config %s: unknown option %q%s", path, key, where)
+			}
+			options[name] = v
+		}
+		return nil
+	}
+	if err := add(values, ""); err != nil {
+		return err
+	}
+	if profile != "" {
+		set, ok := profiles[profile]
+		if !ok {
+			return fmt.Errorf("config %s: no profile %q (it has %s)", path, profile, profileNames(profiles))
+		}
+		if err := add(set, fmt.Sprintf(" in profile %q", profile)); err != nil {
+			return err
+		}
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	for _, name := range slices.Sorted(maps.Keys(options)) {
+		if explicit[name] {
+			continue
+		}
+		items, err := configValues(name, options[name])
+		if err != nil {
+			return fmt.Errorf("config %s: option %q: %w", path, name, err)
+		}
+		for _, item := range items {
+			if err := fs.Set(name, item); err != nil {
+				return fmt.Errorf("config %s: option %q: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// configProfiles checks the shape of a config file's "profiles" value:
+// a map of profile names to maps of options.
+func configProfiles(raw any) (map[string]map[string]any, error) {
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("profiles: expected a map of profile names to options")
+	}
+	profiles := make(map[string]map[string]any, len(m))
+	for name, v := range m {
+		set, ok := v.(map[string]any)
+		if !ok && v != nil {
+			return nil, fmt.Errorf("profile %q: expected a map of options", name)
+		}
+		profiles[name] = set
+	}
+	return profiles, nil
+}
+
+// profileNames lists the profiles for an error message.
+func profileNames(profiles map[string]map[string]any) string {
+	if len(profiles) == 0 {
+		return "no profiles"
+	}
+	return "profiles " + strings.Join(slices.Sorted(maps.Keys(profiles)), ", ")
+}
+
+// configValues flattens a decoded config value into the strings to
+// pass to the flag's Set method.
+func configValues(name string, v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := configScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = s
+		}
+		return items, nil
+	case map[string]any:
+		if name != "header" {
+			return nil, fmt.Errorf("expected a value or list, got a map")
+		}
+		var items []string
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			s, err := configScalar(v[key])
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, key+": "+s)
+		}
+		return items, nil
+	default:
+		s, err := configScalar(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+func configScalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool, int, float64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
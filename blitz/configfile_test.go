@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blitz.yaml")
+	os.WriteFile(path, []byte(`
+requests: 500
+workers: 20
+url:
+  - https://api.example.com/users=3
+  - /health
+header:
+  Accept: application/json
+  X-Env: staging
+expect_max_p95: 250ms
+threshold: [p99<500ms, error_rate<1%]
+http2: true
+`), 0o644)
+
+	var cfg config
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	cfg.registerFlags(fs)
+	if err := fs.Parse([]string{"-workers", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyConfigFile(fs, path, ""); err != nil {
+		t.Fatalf("applyConfigFile() error = %v", err)
+	}
+
+	if cfg.Requests != 500 || cfg.Workers != 5 {
+		t.Errorf("requests, workers = %d, %d; want 500 from the file and 5 from the command line", cfg.Requests, cfg.Workers)
+	}
+	if len(cfg.URLs) != 2 || cfg.URLs[0].Weight != 3 {
+		t.Errorf("urls = %+v", cfg.URLs)
+	}
+	if want := (headerList{"Accept: application/json", "X-Env: staging"}); !slices.Equal(cfg.Headers, want) {
+		t.Errorf("headers = %q, want %q", cfg.Headers, want)
+	}
+	if cfg.ExpectP95 != 250*time.Millisecond || len(cfg.Thresholds) != 2 || !cfg.Transport.HTTP2 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+}
+
+func TestApplyConfigFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"unknown.yaml":  "requets: 10\n",
+		"badtype.json":  `{"requests": "many"}`,
+		"nested.yaml":   "config: other.yaml\n",
+		"map.yaml":      "url: {a: b}\n",
+		"profiles.yaml": "profiles: [smoke]\n",
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(content), 0o644)
+		var cfg config
+		fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+		cfg.registerFlags(fs)
+		if err := applyConfigFile(fs, path, ""); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestApplyConfigFileProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blitz.yaml")
+	os.WriteFile(path, []byte(`
+url: https://api.example.com/
+workers: 10
+threshold: [error_rate<1%]
+profiles:
+  smoke:
+    requests: 500
+  soak:
+    duration: 2h
+    rate: 100/s
+    threshold: [p99<800ms, error_rate<0.1%]
+`), 0o644)
+
+	load := func(profile string, args ...string) (config, error) {
+		var cfg config
+		fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+		cfg.registerFlags(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cfg, applyConfigFile(fs, path, profile)
+	}
+
+	cfg, err := load("soak", "-rate", "20")
+	if err != nil {
+		t.Fatalf("applyConfigFile() error = %v", err)
+	}
+	if cfg.Duration != 2*time.Hour || cfg.Workers != 10 || cfg.Rate != 20 {
+		t.Errorf("duration, workers, rate = %s, %d, %g; want 2h from the profile, 10 from the file and 20 from the command line", cfg.Duration, cfg.Workers, cfg.Rate)
+	}
+	if want := (stringList{"p99<800ms", "error_rate<0.1%"}); !slices.Equal(cfg.Thresholds, want) {
+		t.Errorf("thresholds = %q, want the profile's %q in place of the top-level ones", cfg.Thresholds, want)
+	}
+
+	if cfg, err = load(""); err != nil || cfg.Requests == 500 || cfg.Duration != 0 {
+		t.Errorf("no profile: requests %d, duration %s, error %v; want only the top-level options", cfg.Requests, cfg.Duration, err)
+	}
+	if _, err := load("spike"); err == nil || !strings.Contains(err.Error(), "smoke, soak") {
+		t.Errorf("unknown profile: error = %v, want one listing smoke, soak", err)
+	}
+}
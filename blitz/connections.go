@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// preDialer hands the transport connections opened before the run, so
+// that with -connections every connection exists before the first
+// request and no request's latency includes a dial or TLS handshake.
+// Once a host's connections are used up it dials as usual, which
+// happens only to replace one the server closed.
+type preDialer struct {
+	mu    sync.Mutex
+	ready map[string][]net.Conn // by the "host:port" the transport dials
+}
+
+// take returns a pre-opened connection to addr, or nil if none is left.
+func (p *preDialer) take(addr string) net.Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conns := p.ready[addr]
+	if len(conns) == 0 {
+		return nil
+	}
+	p.ready[addr] = conns[1:]
+	return conns[0]
+}
+
+// Close closes the connections the transport never took.
+func (p *preDialer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conns := range p.ready {
+		for _, c := range conns {
+			c.Close()
+		}
+	}
+	p.ready = nil
+	return nil
+}
+
+// preconnect opens n connections to every host the targets name,
+// completing the TLS handshake for https ones, and hooks transport up
+// to use them before dialing anything new. URLs whose host is a
+// template placeholder are skipped; their connections open on first
+// use. The transport must speak HTTP/1.1 only, since the handshake
+// here offers no other protocol.
+func preconnect(ctx context.Context, transport *http.Transport, targets *mix, n int) (*preDialer, error) {
+	dial := dialFunc(transport.DialContext)
+	tlsConfig := transport.TLSClientConfig
+	dialTLS := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		raw, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		cfg := tlsConfig.Clone()
+		if cfg.ServerName == "" {
+			cfg.ServerName, _, _ = net.SplitHostPort(addr)
+		}
+		cfg.NextProtos = []string{"http/1.1"}
+		conn := tls.Client(raw, cfg)
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	p := &preDialer{ready: make(map[string][]net.Conn)}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for addr, secure := range targetAddrs(targets) {
+		open := dial
+		if secure {
+			open = dialTLS
+		}
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				conn, err := open(ctx, "tcp", addr)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("opening -connections to %s: %w", addr, err)
+					}
+					return
+				}
+				p.ready[addr] = append(p.ready[addr], conn)
+			}()
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		p.Close()
+		return nil, firstErr
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if conn := p.take(addr); conn != nil {
+			return conn, nil
+		}
+		return dial(ctx, network, addr)
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if conn := p.take(addr); conn != nil {
+			return conn, nil
+		}
+		return dialTLS(ctx, network, addr)
+	}
+	return p, nil
+}
+
+// checkConnections validates -connections against the options it
+// cannot work with. nonHTTP reports a WebSocket, tcp:// or gRPC run.
+func checkConnections(cfg config, nonHTTP bool) error {
+	n := cfg.Connections
+	switch {
+	case n < 0:
+		return errors.New("-connections must not be negative")
+	case n == 0:
+		return nil
+	case nonHTTP:
+		return errors.New("-connections only applies to HTTP targets")
+	case cfg.share > 0:
+		return errors.New("-connections does not apply to agents")
+	case cfg.Transport.DisableKeepAlive || cfg.Transport.DNSFresh:
+		return errors.New("-connections keeps its connections alive; drop -disable-keepalive and -dns-fresh")
+	case cfg.Transport.HTTP2:
+		return errors.New("-connections sends HTTP/1.1 and cannot be combined with -http2")
+	case cfg.Transport.MaxConnsPerHost > 0:
+		return errors.New("-connections sets the connections per host; drop -max-conns-per-host")
+	case cfg.Transport.Proxy != "":
+		return errors.New("-connections cannot be combined with -proxy")
+	case cfg.Workers < n:
+		return fmt.Errorf("-workers %d would leave some of the %d -connections unused", cfg.Workers, n)
+	}
+	return nil
+}
+
+// targetAddrs returns the "host:port" of every fixed host the targets
+// send to, mapped to whether it is reached over TLS.
+func targetAddrs(targets *mix) map[string]bool {
+	addrs := make(map[string]bool)
+	for _, sc := range targets.scenarios {
+		for _, st := range sc.Steps {
+			u, err := url.Parse(st.Tmpl.URL)
+			if err != nil || u.Host == "" || strings.Contains(u.Host, "{{") {
+				continue
+			}
+			port := u.Port()
+			switch {
+			case port != "":
+			case u.Scheme == "https":
+				port = "443"
+			default:
+				port = "80"
+			}
+			addrs[net.JoinHostPort(u.Hostname(), port)] = u.Scheme == "https"
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPreconnect(t *testing.T) {
+	var opened atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			opened.Add(1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	const n = 3
+	transport, err := newTransport(transportOptions{Insecure: true, HTTP1: true, MaxConnsPerHost: n, MaxIdleConns: n})
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}), 1)
+	conns, err := preconnect(context.Background(), transport, targets, n)
+	if err != nil {
+		t.Fatalf("preconnect() error = %v", err)
+	}
+	defer conns.Close()
+	if got := opened.Load(); got != n {
+		t.Fatalf("%d connections open before the first request, want %d", got, n)
+	}
+
+	client := &http.Client{Transport: transport}
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				resp, err := client.Get(srv.URL)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if resp.ProtoMajor != 1 {
+					t.Errorf("protocol = %s, want HTTP/1.1", resp.Proto)
+				}
+				resp.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if got := opened.Load(); got != n {
+		t.Errorf("%d connections after 50 requests, want the %d opened up front", got, n)
+	}
+}
+
+func TestPreconnectUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	transport, _ := newTransport(transportOptions{HTTP1: true})
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{Name: "get", Method: http.MethodGet, URL: "http://" + addr}), 1)
+	if _, err := preconnect(context.Background(), transport, targets, 2); err == nil {
+		t.Error("preconnect to a closed port should fail")
+	}
+}
+
+func TestCheckConnections(t *testing.T) {
+	base := config{Connections: 4, Workers: 4}
+	if err := checkConnections(base, false); err != nil {
+		t.Errorf("checkConnections() error = %v", err)
+	}
+	bad := map[string]func(*config){
+		"negative":    func(c *config) { c.Connections = -1 },
+		"keepalive":   func(c *config) { c.Transport.DisableKeepAlive = true },
+		"http2":       func(c *config) { c.Transport.HTTP2 = true },
+		"conns cap":   func(c *config) { c.Transport.MaxConnsPerHost = 8 },
+		"proxy":       func(c *config) { c.Transport.Proxy = "http://proxy:3128" },
+		"few workers": func(c *config) { c.Workers = 2 },
+	}
+	for name, change := range bad {
+		cfg := base
+		change(&cfg)
+		if err := checkConnections(cfg, false); err == nil {
+			t.Errorf("%s: checkConnections() succeeded, want an error", name)
+		}
+	}
+	if err := checkConnections(base, true); err == nil {
+		t.Error("-connections with a non-HTTP target should be rejected")
+	}
+}
+
+func TestConnectionsTwoHosts(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	a, b := httptest.NewServer(handler), httptest.NewServer(handler)
+	defer a.Close()
+	defer b.Close()
+
+	tests := []struct {
+		name  string
+		args  []string
+		slots int
+	}{
+		{"connections per host", nil, 4},
+		{"lower -max-inflight", []string{"-max-inflight", "3"}, 3},
+		{"higher -max-inflight", []string{"-max-inflight", "6"}, 4},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+		var cfg config
+		cfg.registerFlags(fs)
+		args := append([]string{"-url", a.URL, "-url", b.URL, "-connections", "2", "-workers", "8"}, tt.args...)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		lt, err := newLoadTest(cfg)
+		if err != nil {
+			t.Fatalf("%s: newLoadTest() error = %v", tt.name, err)
+		}
+		if got := cap(lt.req.inflight); got != tt.slots {
+			t.Errorf("%s: %d in-flight slots, want %d", tt.name, got, tt.slots)
+		}
+		if got := lt.req.client.Transport.(*http.Transport).MaxIdleConns; got != 4 {
+			t.Errorf("%s: MaxIdleConns = %d, want 2 per host for 2 hosts", tt.name, got)
+		}
+		lt.Close()
+	}
+}
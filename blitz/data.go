@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sync/atomic"
+)
+
+// dataSource supplies rows from a CSV file as template variables,
+// keyed by the header row's column names.
+type dataSource struct {
+	rows   []map[string]string
+	random bool
+	cursor atomic.Uint64
+}
+
+// loadData reads a CSV file whose first line names the columns.
+func loadData(path string, random bool) (*dataSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%s needs a header row and at least one data row", path)
+	}
+
+	columns := records[0]
+	ds := &dataSource{random: random}
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(columns))
+		for i, col := range columns {
+			if i < len(record) {
+				row[col] = record[i]
+			}
+		}
+		ds.rows = append(ds.rows, row)
+	}
+	return ds, nil
+}
+
+// next returns the next row, cycling round-robin or picking at random.
+// A nil dataSource yields nil.
+func (d *dataSource) next() map[string]string {
+	if d == nil {
+		return nil
+	}
+	if d.random {
+		return d.rows[rand.IntN(len(d.rows))]
+	}
+	i := d.cursor.Add(1) - 1
+	return d.rows[i%uint64(len(d.rows))]
+}
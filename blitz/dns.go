@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// newResolver returns the resolver dials use. With a -dns-server every
+// lookup is sent to that server; with fresh the pure Go resolver is
+// used so a local cache such as nscd is not consulted. Otherwise the
+// default resolver is kept.
+func newResolver(server string, fresh bool) (*net.Resolver, error) {
+	if server == "" {
+		if fresh {
+			return &net.Resolver{PreferGo: true}, nil
+		}
+		return net.DefaultResolver, nil
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	host, _, _ := net.SplitHostPort(server)
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("invalid -dns-server %q, expected an IP address with optional :port", server)
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, server)
+		},
+	}, nil
+}
+
+// dnsCache resolves each host once, on its first dial, and reuses the
+// addresses for every later connection, taking DNS out of the picture
+// for the rest of the run. Failed lookups are not cached.
+type dnsCache struct {
+	resolver *net.Resolver
+	mu       sync.Mutex
+	hosts    map[string][]net.IP
+}
+
+func newDNSCache(resolver *net.Resolver) *dnsCache {
+	return &dnsCache{resolver: resolver, hosts: make(map[string][]net.IP)}
+}
+
+// lookup returns the cached addresses of host, resolving it if needed.
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	c.mu.Lock()
+	ips, ok := c.hosts[host]
+	c.mu.Unlock()
+	if ok {
+		return ips, nil
+	}
+	ips, err := c.resolver.LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.hosts[host] = ips
+	c.mu.Unlock()
+	return ips, nil
+}
+
+// dial connects to addr using the cached addresses of its host, trying
+// each in turn. family is "4", "6" or "" for either. Lookups go through
+// ctx, so the first one shows up as DNS time in the timing breakdown and
+// later connections report none.
+func (c *dnsCache) dial(ctx context.Context, dialer *net.Dialer, network, family, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return dialer.DialContext(ctx, network+family, addr)
+	}
+	ips, err := c.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, ip := range ips {
+		if (family == "4" && ip.To4() == nil) || (family == "6" && ip.To4() != nil) {
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, &net.DNSError{Err: "no addresses in the requested family", Name: host, IsNotFound: true}
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewResolver(t *testing.T) {
+	if r, err := newResolver("", false); err != nil || r != net.DefaultResolver {
+		t.Errorf("newResolver(\"\", false) = %v, %v, want the default resolver", r, err)
+	}
+	for _, server := range []string{"127.0.0.1", "127.0.0.1:5353", "::1", "[::1]:53"} {
+		if _, err := newResolver(server, false); err != nil {
+			t.Errorf("newResolver(%q) error = %v", server, err)
+		}
+	}
+	if _, err := newResolver("dns.example.com", false); err == nil {
+		t.Error("newResolver accepted a hostname")
+	}
+}
+
+func TestDNSOptionsExclusive(t *testing.T) {
+	if _, err := newTransport(transportOptions{DNSCache: true, DNSFresh: true}); err == nil {
+		t.Error("newTransport accepted -dns-cache with -dns-fresh")
+	}
+	if _, err := newTransport(transportOptions{UnixSocket: "/tmp/x.sock", DNSCache: true}); err == nil {
+		t.Error("newTransport accepted -unix-socket with -dns-cache")
+	}
+}
+
+func TestDNSFreshDisablesKeepAlive(t *testing.T) {
+	transport, err := newTransport(transportOptions{DNSFresh: true})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("-dns-fresh left keep-alives enabled")
+	}
+}
+
+func TestDNSCacheDials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	transport, err := newTransport(transportOptions{DNSCache: true, DisableKeepAlive: true, IPv4Only: true})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	client := &http.Client{Transport: transport}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("http://localhost:" + port)
+		if err != nil {
+			t.Fatalf("request %d through -dns-cache failed: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func TestDNSCacheLookupOnce(t *testing.T) {
+	c := newDNSCache(net.DefaultResolver)
+	c.hosts["cached.invalid"] = []net.IP{net.IPv4(127, 0, 0, 1)}
+	ips, err := c.lookup(context.Background(), "cached.invalid")
+	if err != nil || len(ips) != 1 {
+		t.Fatalf("lookup() = %v, %v, want the cached address", ips, err)
+	}
+
+	dialer := &net.Dialer{}
+	_, err = c.dial(context.Background(), dialer, "tcp", "6", "cached.invalid:80")
+	if err == nil || !strings.Contains(err.Error(), "no addresses") {
+		t.Errorf("dial() with no IPv6 address error = %v", err)
+	}
+}
@@ -0,0 +1,610 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+	"github.com/NickDiPreta/gokit/loadtest"
+	"golang.org/x/net/http/httpguts"
+)
+
+// usageError marks a configuration mistake, reported along with the
+// flag usage rather than as a runtime failure.
+type usageError struct{ error }
+
+func (e usageError) Unwrap() error { return e.error }
+
+// loadTest is a fully prepared run: targets built, clients connected and
+// exporters started. The command line and agents both execute one.
+type loadTest struct {
+	cfg      config
+	sched    schedule
+	req      *requester
+	rec      recorder
+	interval io.Writer // -report-interval-file, if any
+	saver    *errorSaver
+	slow     *slowLogger
+	exporter *metrics
+	otlp     *otlpExporter
+	statsd   *statsdClient
+	resumed  *checkpoint // the run -resume continues, if any
+	closers  []func() error
+}
+
+// outcome is what executing a loadTest produced.
+type outcome struct {
+	stats   *collector
+	elapsed time.Duration
+	aborted string // why -abort-on-error-rate stopped the run, if it did
+	partial bool   // part of the load was never generated, e.g. an agent failed
+}
+
+// newLoadTest validates cfg and sets up everything the run needs.
+// Mistakes in the options are returned as a usageError.
+func newLoadTest(cfg config) (*loadTest, error) {
+	if len(cfg.URLs) == 0 && cfg.Scenario == "" && cfg.HAR == "" {
+		return nil, usageError{errors.New("URL is required")}
+	}
+	if err := validateAB(cfg); err != nil {
+		return nil, usageError{err}
+	}
+	if cfg.HAR != "" && (cfg.Scenario != "" || cfg.GRPC.Method != "" || len(cfg.URLs) > 1) {
+		return nil, usageError{errors.New("-har takes at most one -url (to retarget the replay) and no -scenario or -grpc")}
+	}
+	if len(cfg.Form) > 0 || len(cfg.FileUploads) > 0 {
+		switch {
+		case cfg.Body != "" || cfg.BodyFile != "":
+			return nil, usageError{errors.New("-form and -file-upload replace -body and -body-file")}
+		case cfg.Scenario != "" || cfg.HAR != "" || cfg.GRPC.Method != "":
+			return nil, usageError{errors.New("-form and -file-upload only apply to -url targets")}
+		}
+	}
+	if cfg.GraphQLQuery != "" {
+		switch {
+		case cfg.Body != "" || cfg.BodyFile != "" || len(cfg.Form) > 0 || len(cfg.FileUploads) > 0:
+			return nil, usageError{errors.New("-graphql-query builds the body; drop -body, -body-file and -form")}
+		case cfg.Scenario != "" || cfg.HAR != "" || cfg.GRPC.Method != "":
+			return nil, usageError{errors.New("-graphql-query only applies to -url targets")}
+		}
+	} else if cfg.GraphQLVars != "" {
+		return nil, usageError{errors.New("-graphql-vars requires -graphql-query")}
+	}
+	expect, err := newExpectations(cfg)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	success, err := parseStatusList(cfg.SuccessCodes)
+	if err != nil {
+		return nil, usageError{fmt.Errorf("-success-codes: %w", err)}
+	}
+	if cfg.HARSpeed < 0 {
+		return nil, usageError{errors.New("-har-speed must not be negative")}
+	}
+
+	if cfg.MaxRedirects < 0 {
+		return nil, usageError{errors.New("-max-redirects must not be negative")}
+	}
+	if cfg.Timeout < 0 || cfg.ReqTimeout < 0 {
+		return nil, usageError{errors.New("-timeout and -request-timeout must not be negative")}
+	}
+	if cfg.SaveErrorsN < 1 {
+		return nil, usageError{errors.New("-save-errors-max must be at least 1")}
+	}
+	if cfg.SlowAfter < 0 {
+		return nil, usageError{errors.New("-slow-threshold must not be negative")}
+	}
+	if cfg.SlowAfter == 0 && cfg.SlowFile != "" {
+		return nil, usageError{errors.New("-slow-file requires -slow-threshold")}
+	}
+	if cfg.SlowMax < 1 {
+		return nil, usageError{errors.New("-slow-max must be at least 1")}
+	}
+	if cfg.RequestID != "" && !httpguts.ValidHeaderFieldName(cfg.RequestID) {
+		return nil, usageError{fmt.Errorf("invalid -request-id-header %q", cfg.RequestID)}
+	}
+	throttle, err := parseRetryAfterMode(cfg.RetryAfter)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if cfg.Hedge < 0 {
+		return nil, usageError{errors.New("-hedge must not be negative")}
+	}
+	if cfg.Retries < 0 {
+		return nil, usageError{errors.New("-retries must not be negative")}
+	}
+	if cfg.AbortErrorRate < 0 || cfg.AbortErrorRate >= 1 {
+		return nil, usageError{errors.New("-abort-on-error-rate must be a fraction between 0 and 1")}
+	}
+
+	if cfg.Think.Mean < 0 || cfg.Think.Jitter < 0 {
+		return nil, usageError{errors.New("-think and -think-jitter must not be negative")}
+	}
+	if cfg.Think.Jitter > 0 && cfg.Think.Poisson {
+		return nil, usageError{errors.New("-think-jitter and -think-poisson are mutually exclusive")}
+	}
+
+	if cfg.Interval < 0 {
+		return nil, usageError{errors.New("-report-interval must not be negative")}
+	}
+	if cfg.IntervalFile != "" && cfg.Interval == 0 {
+		return nil, usageError{errors.New("-report-interval-file requires -report-interval")}
+	}
+
+	if cfg.OTLPTraces && cfg.OTLPEndpoint == "" {
+		return nil, usageError{errors.New("-otlp-traces requires -otlp-endpoint")}
+	}
+	if cfg.StatsDTags && cfg.StatsD == "" {
+		return nil, usageError{errors.New("-statsd-tags requires -statsd")}
+	}
+
+	if cfg.GRPC.Method != "" && (cfg.Scenario != "" || len(cfg.URLs) != 1) {
+		return nil, usageError{errors.New("-grpc needs exactly one -url and no -scenario")}
+	}
+
+	wsMode := len(cfg.URLs) > 0 && isWebSocketURL(cfg.URLs[0].URL)
+	if wsMode {
+		switch {
+		case cfg.Scenario != "" || len(cfg.URLs) != 1 || cfg.GRPC.Method != "":
+			return nil, usageError{errors.New("WebSocket targets need exactly one -url and no -scenario or -grpc")}
+		case cfg.Body == "" && cfg.BodyFile == "" && cfg.Duration == 0:
+			return nil, usageError{errors.New("holding WebSocket connections open without -body requires -duration")}
+		}
+		// Each worker holds one connection, so the per-connection rate
+		// becomes a total rate across all of them.
+		if cfg.WSRate > 0 {
+			cfg.Rate = cfg.WSRate * float64(cfg.Workers)
+		}
+	}
+
+	tcpMode := len(cfg.URLs) > 0 && isTCPURL(cfg.URLs[0].URL)
+	if tcpMode {
+		if cfg.Scenario != "" || cfg.HAR != "" || cfg.GRPC.Method != "" {
+			return nil, usageError{errors.New("tcp:// targets take no -scenario, -har or -grpc")}
+		}
+		for _, u := range cfg.URLs {
+			if !isTCPURL(u.URL) {
+				return nil, usageError{errors.New("tcp:// targets cannot be mixed with other -url schemes")}
+			}
+		}
+	}
+	compression, err := parseCompression(cfg.Compression)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	switch {
+	case compression != "" && (wsMode || tcpMode || cfg.GRPC.Method != ""):
+		return nil, usageError{errors.New("-compression only applies to HTTP targets")}
+	case throttle != nil && (wsMode || tcpMode || cfg.GRPC.Method != ""):
+		return nil, usageError{errors.New("-retry-after only applies to HTTP targets")}
+	case cfg.MaxBodyRead < 0:
+		return nil, usageError{errors.New("-max-body-read must not be negative")}
+	case cfg.DiscardBody && cfg.MaxBodyRead > 0:
+		return nil, usageError{errors.New("-discard-body and -max-body-read are mutually exclusive")}
+	case (cfg.DiscardBody || cfg.MaxBodyRead > 0) && (wsMode || tcpMode || cfg.GRPC.Method != ""):
+		return nil, usageError{errors.New("-discard-body and -max-body-read only apply to HTTP targets")}
+	case (cfg.DiscardBody || cfg.MaxBodyRead > 0) && (expect.needsBody() || cfg.HashBodies):
+		return nil, usageError{errors.New("-discard-body and -max-body-read leave bodies unread, so they cannot be checked or hashed")}
+	case compression == "br" && expect.needsBody():
+		return nil, usageError{errors.New("-compression br bodies cannot be decoded, so they cannot be checked; use gzip")}
+	case cfg.MaxInflight < 0:
+		return nil, usageError{errors.New("-max-inflight must not be negative")}
+	case cfg.MaxInflight > 0 && wsMode:
+		return nil, usageError{errors.New("-max-inflight does not apply to WebSocket targets, which hold one connection per worker")}
+	case cfg.Transport.CertRotate < 0:
+		return nil, usageError{errors.New("-cert-rotate must not be negative")}
+	case cfg.Transport.CertRotate > 0 && cfg.Transport.CertDir == "":
+		return nil, usageError{errors.New("-cert-rotate requires -cert-dir")}
+	case cfg.TCPHold < 0:
+		return nil, usageError{errors.New("-tcp-hold must not be negative")}
+	case cfg.TCPHold > 0 && !tcpMode:
+		return nil, usageError{errors.New("-tcp-hold requires a tcp:// -url")}
+	case cfg.CheckpointIn <= 0:
+		return nil, usageError{errors.New("-checkpoint-interval must be positive")}
+	case (cfg.Checkpoint != "" || cfg.Resume != "") && cfg.share > 0:
+		return nil, usageError{errors.New("-checkpoint and -resume do not apply to agents")}
+	}
+	if err := checkConnections(cfg, wsMode || tcpMode || cfg.GRPC.Method != ""); err != nil {
+		return nil, usageError{err}
+	}
+	if err := checkHTTP3(cfg, wsMode || tcpMode || cfg.GRPC.Method != ""); err != nil {
+		return nil, usageError{err}
+	}
+
+	sched, err := buildSchedule(cfg.Rate, cfg.Stages, cfg.RampUp)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if cfg.Burst != "" {
+		b, err := parseBurst(cfg.Burst, cfg.BurstLen)
+		if err != nil {
+			return nil, usageError{err}
+		}
+		switch {
+		case len(sched) == 0:
+			return nil, usageError{errors.New("-burst needs a baseline -rate or -stages")}
+		case cfg.Duration <= 0 && sched.Length() == 0:
+			return nil, usageError{errors.New("-burst needs a bounded run: set -duration or end -stages with a fixed length")}
+		}
+		if sched, err = sched.WithBursts(b, cfg.Duration); err != nil {
+			return nil, usageError{fmt.Errorf("-burst: %w", err)}
+		}
+	}
+	// An agent generates only its share of the rate. A -ws-rate is
+	// already per connection, and agents split the connections instead.
+	if sched != nil && cfg.share > 0 && !(wsMode && cfg.WSRate > 0) {
+		sched = sched.Scale(cfg.share)
+	}
+
+	// Time-based runs are unbounded in request count unless -requests
+	// was given explicitly.
+	if (cfg.Duration > 0 || sched.Length() > 0) && !cfg.requestsSet {
+		cfg.Requests = 0
+	}
+
+	header, err := parseHeaders(cfg.Headers)
+	if err != nil {
+		return nil, usageError{err}
+	}
+	if err := applyAuth(header, cfg.BasicAuth, cfg.Bearer); err != nil {
+		return nil, usageError{err}
+	}
+
+	targets, err := buildTargets(cfg, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var resumed *checkpoint
+	if cfg.Resume != "" {
+		if resumed, err = loadCheckpoint(cfg.Resume); err != nil {
+			return nil, err
+		}
+		if sched, err = resumed.resume(&cfg, sched, targets.steps()); err != nil {
+			return nil, fmt.Errorf("-resume %s: %w", cfg.Resume, err)
+		}
+		if cfg.Checkpoint == "" {
+			cfg.Checkpoint = cfg.Resume
+		}
+	}
+
+	var data *dataSource
+	var sample map[string]string
+	if cfg.Data != "" {
+		if cfg.DataMode != "sequential" && cfg.DataMode != "random" {
+			return nil, usageError{fmt.Errorf("unknown -data-order %q", cfg.DataMode)}
+		}
+		data, err = loadData(cfg.Data, cfg.DataMode == "random")
+		if err != nil {
+			return nil, err
+		}
+		sample = data.rows[0]
+	}
+	// Placeholders can call templateFuncs without any -data.
+	if err := targets.compile(sample); err != nil {
+		return nil, err
+	}
+
+	// -connections opens that many connections to every host, so the
+	// run as a whole holds that many times the number of hosts.
+	var connections int
+	if cfg.Connections > 0 {
+		connections = cfg.Connections * max(len(targetAddrs(targets)), 1)
+		cfg.Transport.MaxConnsPerHost = cfg.Connections
+		cfg.Transport.MaxIdleConns = connections
+		cfg.Transport.HTTP1 = true
+	}
+	if cfg.Transport.MaxIdleConns <= 0 {
+		cfg.Transport.MaxIdleConns = cfg.Workers
+	}
+	transport, err := newTransport(cfg.Transport)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &loadTest{cfg: cfg, sched: sched, resumed: resumed}
+	// Release whatever was set up if a later step fails.
+	ok := false
+	defer func() {
+		if !ok {
+			t.Close()
+		}
+	}()
+
+	if cfg.Transport.CertRotate > 0 {
+		t.closers = append(t.closers, closeIdleEvery(transport, cfg.Transport.CertRotate))
+	}
+	if cfg.Connections > 0 {
+		conns, err := preconnect(context.Background(), transport, targets, cfg.Connections)
+		if err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, conns.Close)
+	}
+
+	var grpcClient *grpcClient
+	if cfg.GRPC.Method != "" {
+		tlsConfig, err := newTLSConfig(cfg.Transport)
+		if err != nil {
+			return nil, err
+		}
+		grpcClient, err = newGRPCClient(context.Background(), cfg.URLs[0].URL, cfg.GRPC, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, grpcClient.Close)
+	}
+
+	if cfg.Record != "" {
+		t.rec, err = newRecorder(cfg.Record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.IntervalFile != "" {
+		f, err := os.OpenFile(cfg.IntervalFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		t.interval = f
+		t.closers = append(t.closers, f.Close)
+	}
+
+	if cfg.SaveErrors != "" {
+		if t.saver, err = newErrorSaver(cfg.SaveErrors, cfg.SaveErrorsN); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.SlowAfter > 0 {
+		if t.slow, err = newSlowLogger(cfg.SlowAfter, cfg.SlowMax, cfg.SlowFile); err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, t.slow.Close)
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if cfg.Transport.HTTP3 {
+		h3, err := newHTTP3Transport(cfg.Transport)
+		if err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, h3.Close)
+		roundTripper = h3
+	}
+
+	t.req = &requester{
+		client: &http.Client{
+			Timeout:       cfg.Timeout,
+			Transport:     roundTripper,
+			CheckRedirect: redirectPolicy(cfg.MaxRedirects, !cfg.NoFollow),
+		},
+		targets:  targets,
+		data:     data,
+		cookies:  cfg.Cookies,
+		retries:  cfg.Retries,
+		backoff:  cfg.RetryBackoff,
+		timeout:  cfg.ReqTimeout,
+		hedge:    cfg.Hedge,
+		spans:    cfg.OTLPTraces,
+		idHeader: cfg.RequestID,
+		hashBody: cfg.HashBodies,
+		encoding: compression,
+		grpc:     grpcClient,
+		ws:       wsMode,
+		tcpHold:  cfg.TCPHold,
+		think:    cfg.Think,
+		expect:   expect,
+		success:  success,
+		saver:    t.saver,
+
+		skipBody:  cfg.DiscardBody,
+		bodyLimit: cfg.MaxBodyRead,
+		throttle:  throttle,
+	}
+	if tcpMode {
+		t.req.tcp = transport.DialContext
+	}
+	// Agents split the cap like they split the rate.
+	slots := cfg.MaxInflight
+	if slots > 0 && cfg.share > 0 {
+		slots = max(int(math.Ceil(float64(slots)*cfg.share)), 1)
+	}
+	// Workers beyond -connections wait for a free connection here, so
+	// the wait counts as queueing rather than as the request's latency.
+	if connections > 0 && cfg.Workers > connections && (slots == 0 || slots > connections) {
+		slots = connections
+	}
+	if slots > 0 {
+		t.req.inflight = make(chan struct{}, slots)
+	}
+
+	if cfg.MetricsAddr != "" {
+		t.exporter = newMetrics()
+		srv, err := serveMetrics(cfg.MetricsAddr, t.exporter)
+		if err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, srv.Close)
+	}
+
+	if cfg.OTLPEndpoint != "" {
+		t.otlp = newOTLPExporter(cfg.OTLPEndpoint)
+	}
+
+	if cfg.StatsD != "" {
+		if t.statsd, err = newStatsdClient(cfg.StatsD, cfg.StatsDPrefix, cfg.StatsDTags, cfg.Tag); err != nil {
+			return nil, err
+		}
+		t.closers = append(t.closers, t.statsd.Close)
+	}
+
+	ok = true
+	return t, nil
+}
+
+// Close releases the clients, servers and files opened by newLoadTest.
+// It does not flush the OTLP exporter, which needs the final summary.
+func (t *loadTest) Close() {
+	for _, c := range t.closers {
+		c()
+	}
+	t.closers = nil
+	if t.rec != nil {
+		t.rec.Close()
+		t.rec = nil
+	}
+}
+
+// steps returns the number of results the run is expected to produce,
+// or zero when it is time-based.
+func (t *loadTest) steps() int {
+	// Each job runs every scenario step, so progress counts steps.
+	return t.cfg.Requests * t.req.targets.steps()
+}
+
+// execute runs the load test until it completes, ctx is cancelled or
+// -abort-on-error-rate trips, showing progress on prog.
+func (t *loadTest) execute(ctx context.Context, prog display) outcome {
+	// runCtx is additionally cancelled when -abort-on-error-rate trips.
+	runCtx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	genCtx := runCtx
+	if t.cfg.Duration > 0 {
+		var cancelGen context.CancelFunc
+		genCtx, cancelGen = context.WithTimeout(runCtx, t.cfg.Duration)
+		defer cancelGen()
+	}
+
+	jobsChan := loadtest.Generate(genCtx, t.cfg.Requests, t.sched)
+	resultsChan := make(chan Result)
+
+	start := time.Now()
+
+	go func() {
+		runWorkers(runCtx, t.req, t.cfg.Workers, jobsChan, resultsChan)
+		close(resultsChan)
+	}()
+
+	ticker := time.NewTicker(prog.Interval())
+	defer ticker.Stop()
+
+	var snapshots *intervalReporter
+	var snapshotC <-chan time.Time // stays nil without -report-interval
+	if t.cfg.Interval > 0 {
+		snapshots = newIntervalReporter(start, t.interval)
+		snapTicker := time.NewTicker(t.cfg.Interval)
+		defer snapTicker.Stop()
+		snapshotC = snapTicker.C
+	}
+
+	var checkpointC <-chan time.Time // stays nil without -checkpoint
+	if t.cfg.Checkpoint != "" {
+		checkpointTicker := time.NewTicker(t.cfg.CheckpointIn)
+		defer checkpointTicker.Stop()
+		checkpointC = checkpointTicker.C
+	}
+
+	out := outcome{stats: newCollector()}
+	// A resumed run adds to the statistics and time it was saved with.
+	var prior time.Duration
+	if t.resumed != nil {
+		out.stats = t.resumed.statsAt(start)
+		prior = t.resumed.Elapsed
+	}
+	var window *errorWindow
+	if t.cfg.AbortErrorRate > 0 {
+		window = newErrorWindow(errorWindowSize)
+	}
+
+collect:
+	for {
+		select {
+		case res, ok := <-resultsChan:
+			if !ok {
+				break collect
+			}
+			// Requests cut off by an interrupt or abort say nothing about the target.
+			if runCtx.Err() != nil && errors.Is(res.Error, context.Canceled) {
+				continue
+			}
+			if t.rec != nil {
+				if err := t.rec.Record(res); err != nil {
+					fmt.Fprintln(os.Stderr, cli.Error("Error: recording result: "+err.Error()))
+					t.rec = nil
+				}
+			}
+			if t.saver != nil {
+				if err := t.saver.Save(res); err != nil {
+					fmt.Fprintln(os.Stderr, cli.Error("Error: saving failed response: "+err.Error()))
+					t.saver = nil
+				}
+			}
+			if t.slow != nil {
+				if err := t.slow.Log(res); err != nil {
+					fmt.Fprintln(os.Stderr, cli.Error("Error: logging slow request: "+err.Error()))
+					t.slow = nil
+				}
+			}
+			out.stats.Add(res)
+			prog.Add(res)
+			if snapshots != nil {
+				snapshots.Add(res)
+			}
+			if t.exporter != nil {
+				t.exporter.Add(res)
+			}
+			if t.otlp != nil {
+				t.otlp.AddSpan(res)
+			}
+			if t.statsd != nil {
+				t.statsd.Add(res)
+			}
+			if window != nil && out.aborted == "" {
+				window.Add(failed(res))
+				if window.Exceeds(t.cfg.AbortErrorRate) {
+					out.aborted = fmt.Sprintf("error rate %.1f%% over the last %d requests exceeded %.1f%%",
+						window.Rate()*100, window.filled, t.cfg.AbortErrorRate*100)
+					abort()
+				}
+			}
+		case <-ticker.C:
+			prog.Render()
+		case now := <-snapshotC:
+			if err := snapshots.Flush(now, prog); err != nil {
+				fmt.Fprintln(os.Stderr, cli.Error("Error: writing interval report: "+err.Error()))
+				snapshotC = nil
+			}
+		case <-checkpointC:
+			if err := t.saveCheckpoint(out.stats, prior+time.Since(start), false); err != nil {
+				fmt.Fprintln(os.Stderr, cli.Error("Error: writing checkpoint: "+err.Error()))
+				checkpointC = nil
+			}
+		}
+	}
+	prog.Finish()
+	out.elapsed = prior + time.Since(start)
+
+	if t.cfg.Checkpoint != "" {
+		// A run that was cut short stays resumable.
+		if err := t.saveCheckpoint(out.stats, out.elapsed, runCtx.Err() == nil); err != nil {
+			fmt.Fprintln(os.Stderr, cli.Error("Error: writing checkpoint: "+err.Error()))
+		}
+	}
+
+	if t.rec != nil {
+		if err := t.rec.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, cli.Error("Error: closing record file: "+err.Error()))
+		}
+		t.rec = nil
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error categories reported in the summary breakdown.
+const (
+	catDNS          = "DNS failure"
+	catRefused      = "Connection refused"
+	catReset        = "Connection reset"
+	catClosed       = "Connection closed"
+	catTimeout      = "Timeout"
+	catTLS          = "TLS error"
+	catOther        = "Other error"
+	catHTTP4xx      = "HTTP 4xx"
+	catHTTP5xx      = "HTTP 5xx"
+	catHTTPOther    = "HTTP non-2xx"
+	catUnlisted     = "HTTP status not in -success-codes"
+	catBodyMismatch = "Body mismatch"
+	catGraphQL      = "GraphQL error"
+	catCapture      = "Capture failed"
+)
+
+// classify returns the failure category of r, or "" if it succeeded.
+func classify(r Result) string {
+	switch {
+	case r.Error != nil:
+		return classifyError(r.Error)
+	case r.Check == statusListed:
+		// Any status listed in -success-codes is fine; only the body can fail.
+	case r.Status >= 500:
+		return catHTTP5xx
+	case r.Status >= 400:
+		return catHTTP4xx
+	case r.Check == statusUnlisted:
+		return catUnlisted
+	case r.Status < 200 || r.Status >= 300:
+		return catHTTPOther
+	}
+	if r.Mismatch != nil {
+		var gqlErr *graphQLError
+		if errors.As(r.Mismatch, &gqlErr) {
+			return catGraphQL
+		}
+		var capErr *captureError
+		if errors.As(r.Mismatch, &capErr) {
+			return catCapture
+		}
+		return catBodyMismatch
+	}
+	return ""
+}
+
+// classifyError maps a transport error to a category by unwrapping it
+// to the underlying net, syscall or TLS error.
+func classifyError(err error) string {
+	if st, ok := status.FromError(err); ok {
+		// A call past its deadline, including -request-timeout's, fails
+		// with a status that does not wrap context.DeadlineExceeded.
+		if st.Code() == codes.DeadlineExceeded {
+			return catTimeout
+		}
+		return "gRPC " + st.Code().String()
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsTimeout {
+			return catTimeout
+		}
+		return catDNS
+	}
+
+	if isTLSError(err) {
+		return catTLS
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return catRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return catReset
+	case errors.Is(err, context.DeadlineExceeded):
+		return catTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return catTimeout
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || isWebSocketClose(err) {
+		return catClosed
+	}
+
+	return catOther
+}
+
+// isTransient reports whether err is a connection-level failure that
+// may well succeed if the request is simply sent again.
+func isTransient(err error) bool {
+	if st, ok := status.FromError(err); ok {
+		return st.Code() == codes.Unavailable
+	}
+	switch classifyError(err) {
+	case catRefused, catReset, catClosed, catTimeout:
+		return true
+	}
+	return false
+}
+
+// isTLSError reports whether err came from the TLS handshake or
+// certificate verification.
+func isTLSError(err error) bool {
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		recordErr   tls.RecordHeaderError
+		alertErr    tls.AlertError
+		unknownAuth x509.UnknownAuthorityError
+		hostnameErr x509.HostnameError
+		invalidCert x509.CertificateInvalidError
+	)
+	return errors.As(err, &verifyErr) ||
+		errors.As(err, &recordErr) ||
+		errors.As(err, &alertErr) ||
+		errors.As(err, &unknownAuth) ||
+		errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCert)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	wrap := func(err error) error {
+		return &url.Error{Op: "Get", URL: "http://h", Err: err}
+	}
+	dial := func(err error) error {
+		return wrap(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)})
+	}
+
+	tests := []struct {
+		name string
+		res  Result
+		want string
+	}{
+		{"success", Result{Status: 200}, ""},
+		{"dns", Result{Error: wrap(&net.DNSError{Err: "no such host", Name: "h", IsNotFound: true})}, catDNS},
+		{"dns timeout", Result{Error: wrap(&net.DNSError{Err: "timeout", Name: "h", IsTimeout: true})}, catTimeout},
+		{"refused", Result{Error: dial(syscall.ECONNREFUSED)}, catRefused},
+		{"reset", Result{Error: dial(syscall.ECONNRESET)}, catReset},
+		{"deadline", Result{Error: wrap(context.DeadlineExceeded)}, catTimeout},
+		{"tls", Result{Error: wrap(x509.UnknownAuthorityError{})}, catTLS},
+		{"eof", Result{Error: wrap(io.EOF)}, catClosed},
+		{"other", Result{Error: errors.New("boom")}, catOther},
+		{"4xx", Result{Status: 404}, catHTTP4xx},
+		{"5xx", Result{Status: 503}, catHTTP5xx},
+		{"3xx", Result{Status: 302}, catHTTPOther},
+		{"mismatch", Result{Status: 200, Mismatch: fmt.Errorf("nope")}, catBodyMismatch},
+		{"listed 404", Result{Status: 404, Check: statusListed}, ""},
+		{"listed mismatch", Result{Status: 404, Check: statusListed, Mismatch: fmt.Errorf("nope")}, catBodyMismatch},
+		{"unlisted 204", Result{Status: 204, Check: statusUnlisted}, catUnlisted},
+		{"unlisted 500", Result{Status: 500, Check: statusUnlisted}, catHTTP5xx},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.res); got != tt.want {
+				t.Errorf("classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// Probe is one fixed-rate step of a -find-max search.
+type Probe struct {
+	Rate      int // target requests/sec
+	Workers   int
+	RPS       float64 // achieved requests/sec
+	P99       time.Duration
+	ErrorRate float64
+	Reason    string // why the rate is not sustainable; empty when it is
+}
+
+// MaxSearch is the outcome of a -find-max search.
+type MaxSearch struct {
+	MaxRate int // highest sustainable rate found; 0 when none was
+	Probes  []Probe
+}
+
+// minAchievedRatio is the share of its scheduled requests a probe must
+// complete. Falling short means requests queued behind busy workers,
+// i.e. the target is saturated even if its latency still looks fine.
+const minAchievedRatio = 0.9
+
+// sustainLimits decide whether a probed rate is sustainable.
+type sustainLimits struct {
+	ErrorRate float64
+	P99       time.Duration // 0 for no latency limit
+}
+
+// check measures a probe's summary against the limits. expected is the
+// number of requests the rate should have produced.
+func (l sustainLimits) check(rate, expected int, s Summary) Probe {
+	p := Probe{Rate: rate, RPS: s.RPS, P99: s.Latency.P99}
+	// With pacing, corrected latency includes time spent queued.
+	if s.Corrected != nil {
+		p.P99 = s.Corrected.P99
+	}
+	if s.Total > 0 {
+		p.ErrorRate = float64(s.Failed) / float64(s.Total)
+	}
+
+	switch {
+	case s.Total == 0:
+		p.Reason = "no requests completed"
+	case s.Aborted != "":
+		p.Reason = "aborted: " + s.Aborted
+	case p.ErrorRate > l.ErrorRate:
+		p.Reason = fmt.Sprintf("error rate %.2f%% > %.2f%%", p.ErrorRate*100, l.ErrorRate*100)
+	case l.P99 > 0 && p.P99 > l.P99:
+		p.Reason = fmt.Sprintf("p99 %s > %s", p.P99.Round(time.Millisecond), l.P99)
+	case float64(s.Total) < float64(expected)*minAchievedRatio:
+		p.Reason = fmt.Sprintf("achieved %.1f of %d req/s", p.RPS, rate)
+	}
+	return p
+}
+
+// maxProbeRate bounds the doubling phase of a search.
+const maxProbeRate = 1 << 20
+
+// searchMax finds the highest rate for which probe reports success. It
+// doubles the rate from start until a probe fails, then binary searches
+// between the last good and first bad rate until they are within 5% of
+// each other. It stops early if ctx is cancelled or probe fails to run.
+func searchMax(ctx context.Context, start int, probe func(rate int) (bool, error)) (int, error) {
+	good, bad := 0, 0
+	for rate := max(start, 1); bad == 0 && rate <= maxProbeRate; rate *= 2 {
+		ok, err := probe(rate)
+		if err != nil || ctx.Err() != nil {
+			return good, err
+		}
+		if ok {
+			good = rate
+		} else {
+			bad = rate
+		}
+	}
+	if bad == 0 {
+		return good, nil
+	}
+	for bad-good > max(1, good/20) {
+		mid := (good + bad) / 2
+		ok, err := probe(mid)
+		if err != nil || ctx.Err() != nil {
+			return good, err
+		}
+		if ok {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+	return good, nil
+}
+
+// defaultProbeDuration is how long each rate is held when -duration is unset.
+const defaultProbeDuration = 10 * time.Second
+
+// findMax runs the -find-max search and returns the outcome of the best
+// sustainable probe (or the last one, if none was) with the search.
+func findMax(ctx context.Context, cfg config) (outcome, *MaxSearch, error) {
+	if err := validateFindMax(cfg); err != nil {
+		return outcome{}, nil, err
+	}
+	limits := sustainLimits{ErrorRate: cfg.MaxErrorRate, P99: cfg.MaxP99}
+	step := cfg.Duration
+	if step <= 0 {
+		step = defaultProbeDuration
+	}
+	start := int(math.Ceil(cfg.Rate))
+	if start <= 0 {
+		start = 10
+	}
+
+	search := &MaxSearch{}
+	var best, last outcome
+	var mean time.Duration // latest measured mean latency
+	// workersFor keeps enough workers to hold rate at the measured
+	// latency, with headroom, so -workers doesn't cap the search.
+	workersFor := func(rate int) int {
+		return max(cfg.Workers, int(math.Ceil(float64(rate)*mean.Seconds()*2)))
+	}
+	preflighted := false // the first probe checks the target first
+	run := func(rate, workers int) (outcome, Probe, error) {
+		pc := cfg
+		pc.Rate = float64(rate)
+		pc.Duration = step
+		pc.requestsSet = false
+		pc.Workers = workers
+		t, err := newLoadTest(pc)
+		if err != nil {
+			return outcome{}, Probe{}, err
+		}
+		defer t.Close()
+		if !preflighted && !cfg.NoPreflight {
+			if err := t.preflight(ctx); err != nil {
+				return outcome{}, Probe{}, err
+			}
+			preflighted = true
+		}
+		if !cfg.Quiet {
+			fmt.Fprintf(os.Stderr, "Probing %d req/s with %d workers for %s\n", rate, workers, step)
+		}
+		out := t.execute(ctx, cfg.newDisplay(0))
+
+		s := out.stats.Summary(out.elapsed)
+		s.Aborted = out.aborted
+		p := limits.check(rate, int(float64(rate)*step.Seconds()), s)
+		p.Workers = workers
+		if s.Total > 0 {
+			mean = s.Latency.Mean
+		}
+		return out, p, nil
+	}
+
+	probe := func(rate int) (bool, error) {
+		workers := workersFor(rate)
+		out, p, err := run(rate, workers)
+		// Before the first measurement the worker count is a guess; if it
+		// was too low, try again rather than blame the target.
+		if err == nil && p.Reason != "" && ctx.Err() == nil && workersFor(rate) > workers {
+			out, p, err = run(rate, workersFor(rate))
+		}
+		if err != nil {
+			return false, err
+		}
+		last = out
+		// A probe cut short by an interrupt says nothing about its rate.
+		if ctx.Err() != nil {
+			return false, nil
+		}
+		search.Probes = append(search.Probes, p)
+		if p.Reason != "" {
+			return false, nil
+		}
+		if rate > search.MaxRate {
+			search.MaxRate = rate
+			best = out
+		}
+		return true, nil
+	}
+
+	if _, err := searchMax(ctx, start, probe); err != nil {
+		return outcome{}, nil, err
+	}
+	if search.MaxRate == 0 {
+		return last, search, nil
+	}
+	return best, search, nil
+}
+
+// validateFindMax rejects options that conflict with -find-max.
+func validateFindMax(cfg config) error {
+	switch {
+	case cfg.Agents != "":
+		return usageError{errors.New("-find-max cannot be combined with -agents")}
+	case cfg.Stages != "" || cfg.RampUp > 0 || cfg.Burst != "":
+		return usageError{errors.New("-find-max sets its own rate; drop -stages, -ramp-up and -burst")}
+	case cfg.Record != "":
+		return usageError{errors.New("-find-max cannot be combined with -record")}
+	case cfg.MaxErrorRate < 0 || cfg.MaxErrorRate >= 1:
+		return usageError{errors.New("-max-error-rate must be a fraction between 0 and 1")}
+	}
+	return nil
+}
+
+// renderMaxSearch prints the probes of a -find-max search.
+func renderMaxSearch(w io.Writer, m *MaxSearch) {
+	table := cli.NewTable("Rate", "Workers", "Achieved", "P99", "Errors", "Result")
+	table.Writer = w
+	for _, p := range m.Probes {
+		result := cli.Success("ok")
+		if p.Reason != "" {
+			result = cli.Error(p.Reason)
+		}
+		table.AddRow(fmt.Sprintf("%d/s", p.Rate),
+			fmt.Sprintf("%d", p.Workers),
+			fmt.Sprintf("%.1f/s", p.RPS),
+			p.P99.Round(time.Millisecond).String(),
+			fmt.Sprintf("%.2f%%", p.ErrorRate*100),
+			result)
+	}
+	table.Render()
+	if m.MaxRate > 0 {
+		fmt.Fprintf(w, "Max sustainable rate: %s (results below are for that probe)\n",
+			cli.Success(fmt.Sprintf("%d req/s", m.MaxRate)))
+	} else {
+		fmt.Fprintln(w, cli.Error("No probed rate was sustainable (results below are for the last probe)"))
+	}
+}
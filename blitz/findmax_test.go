@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchMax(t *testing.T) {
+	const knee = 730
+	var probed []int
+	got, err := searchMax(context.Background(), 10, func(rate int) (bool, error) {
+		probed = append(probed, rate)
+		return rate <= knee, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got > knee || got < knee*95/100 {
+		t.Errorf("searchMax = %d, want within 5%% below %d", got, knee)
+	}
+	// Doubling: 10, 20, ..., 640, 1280, then bisecting 640..1280.
+	if probed[7] != 1280 || len(probed) > 15 {
+		t.Errorf("probed %v", probed)
+	}
+}
+
+func TestSearchMaxFirstProbeFails(t *testing.T) {
+	got, err := searchMax(context.Background(), 100, func(rate int) (bool, error) {
+		return rate <= 3, nil
+	})
+	if err != nil || got != 3 {
+		t.Errorf("searchMax = %d, %v; want 3", got, err)
+	}
+}
+
+func TestSearchMaxStopsOnError(t *testing.T) {
+	calls := 0
+	_, err := searchMax(context.Background(), 10, func(rate int) (bool, error) {
+		calls++
+		return false, errors.New("bad config")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v after %d calls; want an error after 1", err, calls)
+	}
+}
+
+func TestSustainLimitsCheck(t *testing.T) {
+	limits := sustainLimits{ErrorRate: 0.01, P99: 100 * time.Millisecond}
+	base := Summary{Total: 1000, RPS: 100, Latency: LatencyStats{P99: 50 * time.Millisecond}}
+
+	tests := []struct {
+		name   string
+		modify func(*Summary)
+		reason string
+	}{
+		{"sustainable", func(s *Summary) {}, ""},
+		{"errors", func(s *Summary) { s.Failed = 20 }, "error rate"},
+		{"latency", func(s *Summary) { s.Latency.P99 = 200 * time.Millisecond }, "p99"},
+		{"corrected latency", func(s *Summary) { s.Corrected = &LatencyStats{P99: time.Second} }, "p99"},
+		{"saturated", func(s *Summary) { s.Total = 850; s.RPS = 85 }, "achieved"},
+		{"empty", func(s *Summary) { s.Total = 0 }, "no requests"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := base
+			tt.modify(&s)
+			p := limits.check(100, 1000, s)
+			if tt.reason == "" && p.Reason != "" || !strings.Contains(p.Reason, tt.reason) {
+				t.Errorf("reason = %q, want containing %q", p.Reason, tt.reason)
+			}
+		})
+	}
+}
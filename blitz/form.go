@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildForm encodes -form fields and -file-upload files as a request
+// body. Without files the body is application/x-www-form-urlencoded;
+// with any it is multipart/form-data. It returns the body and its
+// Content-Type, which carries the multipart boundary.
+func buildForm(fields, files []string) ([]byte, string, error) {
+	values := url.Values{}
+	var keys []string // keep the order the flags were given in
+	for _, f := range fields {
+		key, value, ok := strings.Cut(f, "=")
+		if !ok || key == "" {
+			return nil, "", fmt.Errorf("invalid -form %q, expected key=value", f)
+		}
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values.Add(key, value)
+	}
+
+	if len(files) == 0 {
+		return []byte(values.Encode()), "application/x-www-form-urlencoded", nil
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, key := range keys {
+		for _, v := range values[key] {
+			if err := mw.WriteField(key, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for _, f := range files {
+		field, path, ok := strings.Cut(f, "=")
+		path = strings.TrimPrefix(path, "@")
+		if !ok || field == "" || path == "" {
+			return nil, "", fmt.Errorf("invalid -file-upload %q, expected field=@path", f)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			escapeQuotes(field), escapeQuotes(filepath.Base(path))))
+		h.Set("Content-Type", cmp.Or(mime.TypeByExtension(filepath.Ext(path)), "application/octet-stream"))
+		part, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		part.Write(data)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+// escapeQuotes escapes a Content-Disposition parameter value the same
+// way mime/multipart does.
+var escapeQuotes = strings.NewReplacer("\\", "\\\\", `"`, "\\\"").Replace
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildFormURLEncoded(t *testing.T) {
+	body, ctype, err := buildForm([]string{"user=ann", "q=a b&c"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctype != "application/x-www-form-urlencoded" {
+		t.Errorf("content type = %q", ctype)
+	}
+	if got, want := string(body), "q=a+b%26c&user=ann"; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestBuildFormMultipart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(path, []byte("PNGDATA"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	body, ctype, err := buildForm([]string{"title=holiday"}, []string{"image=@" + path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set("Content-Type", ctype)
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.FormValue("title"); got != "holiday" {
+		t.Errorf("title = %q", got)
+	}
+	f, hdr, err := req.FormFile("image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	if string(data) != "PNGDATA" || hdr.Filename != "photo.png" || hdr.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("file part = %q %q %q", data, hdr.Filename, hdr.Header.Get("Content-Type"))
+	}
+}
+
+func TestBuildFormErrors(t *testing.T) {
+	if _, _, err := buildForm([]string{"novalue"}, nil); err == nil || !strings.Contains(err.Error(), "key=value") {
+		t.Errorf("bad field: err = %v", err)
+	}
+	if _, _, err := buildForm(nil, []string{"file"}); err == nil || !strings.Contains(err.Error(), "field=@path") {
+		t.Errorf("bad file: err = %v", err)
+	}
+	if _, _, err := buildForm(nil, []string{"file=@/does/not/exist"}); err == nil {
+		t.Error("missing file: want an error")
+	}
+}
+
+func TestFormMethod(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-url", "http://h/", "-form", "q=1"}, http.MethodPost},
+		{[]string{"-url", "http://h/", "-form", "q=1", "-method", "GET"}, http.MethodGet},
+		{[]string{"-url", "http://h/", "-form", "q=1", "-method", "PUT"}, http.MethodPut},
+	} {
+		var cfg config
+		fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+		cfg.registerFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		cfg.methodSet = flagWasSet(fs, "method")
+
+		targets, err := buildTargets(cfg, http.Header{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := targets.scenarios[0].Steps[0].Tmpl.Method; got != tt.want {
+			t.Errorf("%q: method = %s, want %s", tt.args, got, tt.want)
+		}
+	}
+}
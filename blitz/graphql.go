@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// graphQLRequest is the standard body of a GraphQL-over-HTTP POST.
+type graphQLRequest struct {
+	Query     string          `json:"query"`
+	Variables json.RawMessage `json:"variables,omitempty"`
+}
+
+// buildGraphQLBody wraps the query in queryPath, and the JSON variables
+// in varsPath if given, into a request body.
+func buildGraphQLBody(queryPath, varsPath string) ([]byte, error) {
+	query, err := os.ReadFile(queryPath)
+	if err != nil {
+		return nil, err
+	}
+	req := graphQLRequest{Query: string(query)}
+	if varsPath != "" {
+		vars, err := os.ReadFile(varsPath)
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid(vars) {
+			return nil, fmt.Errorf("-graphql-vars %s is not valid JSON", varsPath)
+		}
+		req.Variables = vars
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(req); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// graphQLError reports the errors array of a GraphQL response. Servers
+// return these with HTTP 200, so the status alone hides them.
+type graphQLError struct {
+	Messages []string
+}
+
+func (e *graphQLError) Error() string {
+	return "GraphQL error: " + strings.Join(e.Messages, "; ")
+}
+
+// checkGraphQL returns a *graphQLError if body is a GraphQL response
+// with a non-empty errors array. Bodies that aren't JSON are left to
+// the status code to judge.
+func checkGraphQL(body []byte) error {
+	var resp struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || len(resp.Errors) == 0 {
+		return nil
+	}
+	e := &graphQLError{}
+	for _, item := range resp.Errors {
+		e.Messages = append(e.Messages, item.Message)
+	}
+	return e
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildGraphQLBody(t *testing.T) {
+	dir := t.TempDir()
+	query := filepath.Join(dir, "q.graphql")
+	vars := filepath.Join(dir, "vars.json")
+	os.WriteFile(query, []byte("query User($id: ID!) { user(id: $id) { name } }"), 0o644)
+	os.WriteFile(vars, []byte(`{"id": "{{.id}}"}`), 0o644)
+
+	body, err := buildGraphQLBody(query, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Query     string            `json:"query"`
+		Variables map[string]string `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("body %s: %v", body, err)
+	}
+	if got.Query == "" || got.Variables["id"] != "{{.id}}" {
+		t.Errorf("decoded body = %+v", got)
+	}
+
+	os.WriteFile(vars, []byte(`{"id": `), 0o644)
+	if _, err := buildGraphQLBody(query, vars); err == nil {
+		t.Error("invalid variables JSON: want an error")
+	}
+}
+
+func TestGraphQLErrorsFailRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" || len(body) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("fail") != "" {
+			io.WriteString(w, `{"data": null, "errors": [{"message": "not authorized"}]}`)
+			return
+		}
+		io.WriteString(w, `{"data": {"user": {"name": "ann"}}}`)
+	}))
+	defer srv.Close()
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	r := &requester{client: srv.Client(), expect: &expectations{GraphQL: true}}
+	ok := &requestTemplate{Name: "ok", Method: http.MethodPost, URL: srv.URL, Header: header, Body: []byte(`{"query":"{ user { name } }"}`)}
+	bad := &requestTemplate{Name: "bad", Method: http.MethodPost, URL: srv.URL + "/?fail=1", Header: header, Body: ok.Body}
+
+	if res := r.makeRequest(context.Background(), ok, nil); failed(res) {
+		t.Errorf("clean response failed: %d %v %v", res.Status, res.Error, res.Mismatch)
+	}
+	res := r.makeRequest(context.Background(), bad, nil)
+	if res.Status != http.StatusOK || !failed(res) {
+		t.Fatalf("errors array on 200 should fail: %d %v", res.Status, res.Mismatch)
+	}
+	if got := classify(res); got != catGraphQL {
+		t.Errorf("category = %q, want %q", got, catGraphQL)
+	}
+	if got := res.Mismatch.Error(); got != "GraphQL error: not authorized" {
+		t.Errorf("error = %q", got)
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	refv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcOptions configures -grpc mode.
+type grpcOptions struct {
+	Method      string     // "package.Service/Method"
+	ProtoFile   string     // .proto defining the service; empty uses server reflection
+	ImportPaths stringList // directories searched for ProtoFile's imports
+}
+
+// grpcClient drives unary calls to one method. Request and response
+// messages are built dynamically from the method's descriptor, so no
+// generated code is needed.
+type grpcClient struct {
+	conn   *grpc.ClientConn
+	method string // full method path, "/package.Service/Method"
+	input  protoreflect.MessageDescriptor
+	output protoreflect.MessageDescriptor
+}
+
+// newGRPCClient connects to target and resolves the method, from
+// opts.ProtoFile when given and otherwise via server reflection.
+// grpc:// and http:// targets use plaintext; grpcs:// and https:// use
+// TLS with tlsConfig.
+func newGRPCClient(ctx context.Context, target string, opts grpcOptions, tlsConfig *tls.Config) (*grpcClient, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid gRPC target %q, expected grpc://host:port or grpcs://host:port", target)
+	}
+	var creds credentials.TransportCredentials
+	switch u.Scheme {
+	case "grpc", "http":
+		creds = insecure.NewCredentials()
+	case "grpcs", "https":
+		creds = credentials.NewTLS(tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported gRPC target scheme %q (use grpc or grpcs)", u.Scheme)
+	}
+
+	service, method, err := splitMethod(opts.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := grpc.NewClient(u.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+
+	var resolver interface {
+		FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+	}
+	if opts.ProtoFile != "" {
+		resolver, err = compileProto(ctx, opts.ProtoFile, opts.ImportPaths)
+	} else {
+		resolver, err = reflectService(ctx, conn, service)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	desc, err := resolver.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("service %s: %w", service, err)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	switch {
+	case md == nil:
+		conn.Close()
+		return nil, fmt.Errorf("service %s has no method %s", service, method)
+	case md.IsStreamingClient() || md.IsStreamingServer():
+		conn.Close()
+		return nil, fmt.Errorf("%s is a streaming method; only unary calls are supported", opts.Method)
+	}
+
+	return &grpcClient{
+		conn:   conn,
+		method: "/" + service + "/" + method,
+		input:  md.Input(),
+		output: md.Output(),
+	}, nil
+}
+
+// splitMethod accepts "package.Service/Method" or "package.Service.Method".
+func splitMethod(full string) (service, method string, err error) {
+	full = strings.TrimPrefix(full, "/")
+	i := strings.LastIndexAny(full, "/.")
+	if i <= 0 || i == len(full)-1 {
+		return "", "", fmt.Errorf("invalid gRPC method %q, expected package.Service/Method", full)
+	}
+	return full[:i], full[i+1:], nil
+}
+
+// compileProto parses a .proto file and its imports. Without import
+// paths the file's own directory is searched.
+func compileProto(ctx context.Context, path string, importPaths []string) (protodesc.Resolver, error) {
+	name := path
+	if len(importPaths) == 0 {
+		importPaths = []string{filepath.Dir(path)}
+		name = filepath.Base(path)
+	}
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	files, err := compiler.Compile(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return files.AsResolver(), nil
+}
+
+// reflectService fetches the descriptors of service and its
+// dependencies from the server's reflection service.
+func reflectService(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	stream, err := refv1.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	defer stream.CloseSend()
+
+	files := new(protoregistry.Files)
+	var pending []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	request := func(req *refv1.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return status.Error(codes.Code(e.ErrorCode), e.ErrorMessage)
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(raw, fd); err != nil {
+				return err
+			}
+			if !seen[fd.GetName()] {
+				seen[fd.GetName()] = true
+				pending = append(pending, fd)
+			}
+		}
+		return nil
+	}
+
+	if err := request(&refv1.ServerReflectionRequest{
+		MessageRequest: &refv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	}); err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+
+	// Files must be registered after their dependencies. Servers usually
+	// send every dependency up front, but any that are missing and not
+	// linked into this binary are requested by name.
+	for len(pending) > 0 {
+		progress := false
+		for i := 0; i < len(pending); i++ {
+			fd := pending[i]
+			missing := missingDeps(fd, files)
+			if len(missing) > 0 {
+				for _, dep := range missing {
+					if seen[dep] {
+						continue
+					}
+					seen[dep] = true
+					if err := request(&refv1.ServerReflectionRequest{
+						MessageRequest: &refv1.ServerReflectionRequest_FileByFilename{FileByFilename: dep},
+					}); err != nil {
+						return nil, fmt.Errorf("server reflection: %s: %w", dep, err)
+					}
+					progress = true
+				}
+				continue
+			}
+			f, err := protodesc.NewFile(fd, files)
+			if err != nil {
+				return nil, fmt.Errorf("server reflection: %s: %w", fd.GetName(), err)
+			}
+			if err := files.RegisterFile(f); err != nil {
+				return nil, err
+			}
+			pending = append(pending[:i], pending[i+1:]...)
+			i--
+			progress = true
+		}
+		if !progress {
+			return nil, errors.New("server reflection: unresolvable file dependencies")
+		}
+	}
+	return files, nil
+}
+
+// missingDeps lists the imports of fd not yet registered in files. Well
+// known types linked into this binary are copied over on demand.
+func missingDeps(fd *descriptorpb.FileDescriptorProto, files *protoregistry.Files) []string {
+	var missing []string
+	for _, dep := range fd.GetDependency() {
+		if _, err := files.FindFileByPath(dep); err == nil {
+			continue
+		}
+		if global, err := protoregistry.GlobalFiles.FindFileByPath(dep); err == nil {
+			if files.RegisterFile(global) == nil {
+				continue
+			}
+		}
+		missing = append(missing, dep)
+	}
+	return missing
+}
+
+// call makes one unary call. The template body is the request message
+// as JSON and its headers are sent as metadata. A non-OK status is
+// reported as the result's error; the HTTP status of a completed call
+// is always 200.
+func (g *grpcClient) call(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
+	_, header, payload, err := tmpl.expand(vars)
+	if err != nil {
+		return Result{Step: tmpl.Name, Error: err, Timestamp: time.Now()}
+	}
+	in := dynamicpb.NewMessage(g.input)
+	if len(payload) > 0 {
+		if err := protojson.Unmarshal(payload, in); err != nil {
+			return Result{Step: tmpl.Name, Error: fmt.Errorf("request message: %w", err), Timestamp: time.Now()}
+		}
+	}
+	md := metadata.MD{}
+	for key, values := range header {
+		md.Append(key, values...)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	out := dynamicpb.NewMessage(g.output)
+	start := time.Now()
+	err = g.conn.Invoke(ctx, g.method, in, out)
+	end := time.Now()
+
+	res := Result{
+		Step:      tmpl.Name,
+		Proto:     "gRPC",
+		Latency:   end.Sub(start),
+		Timestamp: end,
+	}
+	if err != nil {
+		res.Error = err
+		return res
+	}
+	res.Status = 200
+	res.Bytes = int64(proto.Size(out))
+	return res
+}
+
+// Close releases the connection.
+func (g *grpcClient) Close() error {
+	return g.conn.Close()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	refv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const echoProto = `syntax = "proto3";
+package echo;
+
+message SayRequest { string text = 1; }
+message SayResponse { string text = 1; }
+
+service Echo {
+  rpc Say(SayRequest) returns (SayResponse);
+}
+`
+
+// startEchoServer serves echo.Echo/Say, built dynamically from
+// echoProto, with server reflection enabled. Saying "fail" returns
+// Unavailable, and saying "slow" takes a second to answer.
+func startEchoServer(t *testing.T) (target, protoFile string) {
+	t.Helper()
+	protoFile = filepath.Join(t.TempDir(), "echo.proto")
+	if err := os.WriteFile(protoFile, []byte(echoProto), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := compileProto(context.Background(), protoFile, nil)
+	if err != nil {
+		t.Fatalf("compileProto() error = %v", err)
+	}
+	desc, _ := files.FindDescriptorByName("echo.Echo")
+	say := desc.(protoreflect.ServiceDescriptor).Methods().ByName("Say")
+	inText := say.Input().Fields().ByName("text")
+	outText := say.Output().Fields().ByName("text")
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "echo.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Say",
+			Handler: func(_ any, _ context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				in := dynamicpb.NewMessage(say.Input())
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				text := in.Get(inText).String()
+				switch text {
+				case "fail":
+					return nil, status.Error(codes.Unavailable, "backend down")
+				case "slow":
+					time.Sleep(time.Second)
+				}
+				out := dynamicpb.NewMessage(say.Output())
+				out.Set(outText, protoreflect.ValueOfString(text))
+				return out, nil
+			},
+		}},
+	}, struct{}{})
+	refv1.RegisterServerReflectionServer(srv, reflection.NewServerV1(reflection.ServerOptions{
+		Services:           srv,
+		DescriptorResolver: files,
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return "grpc://" + ln.Addr().String(), protoFile
+}
+
+func TestGRPCClient(t *testing.T) {
+	target, protoFile := startEchoServer(t)
+
+	for name, opts := range map[string]grpcOptions{
+		"proto file": {Method: "echo.Echo/Say", ProtoFile: protoFile},
+		"reflection": {Method: "echo.Echo.Say"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, err := newGRPCClient(context.Background(), target, opts, nil)
+			if err != nil {
+				t.Fatalf("newGRPCClient() error = %v", err)
+			}
+			defer client.Close()
+
+			ok := client.call(context.Background(), &requestTemplate{Name: "say", Body: []byte(`{"text":"hello"}`)}, nil)
+			if ok.Error != nil || ok.Status != 200 || ok.Bytes == 0 {
+				t.Errorf("call() = status %d, %d bytes, error %v", ok.Status, ok.Bytes, ok.Error)
+			}
+
+			bad := client.call(context.Background(), &requestTemplate{Name: "say", Body: []byte(`{"text":"fail"}`)}, nil)
+			if got := classify(bad); got != "gRPC Unavailable" || !isTransient(bad.Error) {
+				t.Errorf("failed call classified as %q (transient %v)", got, isTransient(bad.Error))
+			}
+		})
+	}
+}
+
+func TestGRPCTimeout(t *testing.T) {
+	target, protoFile := startEchoServer(t)
+	client, err := newGRPCClient(context.Background(), target, grpcOptions{Method: "echo.Echo/Say", ProtoFile: protoFile}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	res := client.call(ctx, &requestTemplate{Name: "say", Body: []byte(`{"text":"slow"}`)}, nil)
+	if got := classify(res); got != catTimeout {
+		t.Errorf("call past its deadline classified as %q (error %v), want %q", got, res.Error, catTimeout)
+	}
+	c := newCollector()
+	c.Add(res)
+	if s := c.Summary(0); s.Timeouts != 1 {
+		t.Errorf("Timeouts = %d, want the call counted", s.Timeouts)
+	}
+}
+
+func TestGRPCClientErrors(t *testing.T) {
+	target, protoFile := startEchoServer(t)
+	for _, tt := range []struct {
+		target string
+		opts   grpcOptions
+	}{
+		{"localhost:50051", grpcOptions{Method: "echo.Echo/Say"}},
+		{target, grpcOptions{Method: "Say"}},
+		{target, grpcOptions{Method: "echo.Echo/Shout", ProtoFile: protoFile}},
+		{target, grpcOptions{Method: "echo.Missing/Say"}},
+	} {
+		if c, err := newGRPCClient(context.Background(), tt.target, tt.opts, nil); err == nil {
+			c.Close()
+			t.Errorf("newGRPCClient(%q, %+v) expected error", tt.target, tt.opts)
+		}
+	}
+}
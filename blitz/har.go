@@ -0,0 +1,147 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// harFile is the subset of the HTTP Archive format blitz replays.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	Started time.Time `json:"startedDateTime"`
+	Time    float64   `json:"time"` // total milliseconds, -1 if unknown
+	Request struct {
+		Method  string `json:"method"`
+		URL     string `json:"url"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+		} `json:"postData"`
+	} `json:"request"`
+}
+
+// harOptions control how a HAR file becomes a scenario.
+type harOptions struct {
+	Speed    float64 // divides the recorded gaps; 0 replays back to back
+	AllHosts bool    // also replay requests to hosts other than the first
+	BaseURL  string  // if set, replaces the scheme and host of replayed requests
+}
+
+// harSkipHeaders are recorded headers that the transport sets itself or
+// that would be wrong for a replayed request.
+var harSkipHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"Connection":        true,
+	"Accept-Encoding":   true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// loadHAR converts a browser-exported HAR file into a scenario that
+// replays its requests in order. The pause after each step is the idle
+// time the browser spent between that response finishing and the next
+// request starting, divided by Speed. Unless AllHosts is set, requests
+// to hosts other than the first request's (analytics, CDNs) are left
+// out, so a replay doesn't load test third parties.
+func loadHAR(path string, opts harOptions, common http.Header) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file harFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing HAR %s: %w", path, err)
+	}
+	entries := file.Log.Entries
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("HAR %s has no entries", path)
+	}
+	slices.SortStableFunc(entries, func(a, b harEntry) int {
+		return a.Started.Compare(b.Started)
+	})
+
+	var base *url.URL
+	if opts.BaseURL != "" {
+		if base, err = url.Parse(opts.BaseURL); err != nil {
+			return nil, fmt.Errorf("invalid base URL: %w", err)
+		}
+	}
+
+	var host string
+	var kept []harEntry
+	sc := &scenario{}
+	for i, e := range entries {
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("HAR entry %d: invalid url %q", i+1, e.Request.URL)
+		}
+		if host == "" {
+			host = u.Host
+		}
+		if u.Host != host && !opts.AllHosts {
+			continue
+		}
+		if base != nil {
+			u.Scheme, u.Host = base.Scheme, base.Host
+		}
+
+		header := make(http.Header)
+		for _, h := range e.Request.Headers {
+			name := http.CanonicalHeaderKey(h.Name)
+			// HTTP/2 exports include pseudo-headers such as ":path".
+			if strings.HasPrefix(h.Name, ":") || harSkipHeaders[name] {
+				continue
+			}
+			header.Add(name, h.Value)
+		}
+		for k, v := range common {
+			header[k] = v
+		}
+
+		var body []byte
+		if pd := e.Request.PostData; pd != nil {
+			body = []byte(pd.Text)
+			if pd.MimeType != "" && header.Get("Content-Type") == "" {
+				header.Set("Content-Type", pd.MimeType)
+			}
+		}
+
+		method := cmp.Or(strings.ToUpper(e.Request.Method), http.MethodGet)
+		sc.Steps = append(sc.Steps, step{Tmpl: &requestTemplate{
+			Name:   method + " " + u.Path,
+			Method: method,
+			URL:    u.String(),
+			Header: header,
+			Body:   body,
+		}})
+		kept = append(kept, e)
+	}
+
+	if opts.Speed > 0 {
+		for i := range len(kept) - 1 {
+			done := kept[i].Started.Add(time.Duration(max(kept[i].Time, 0) * float64(time.Millisecond)))
+			if gap := kept[i+1].Started.Sub(done); gap > 0 {
+				sc.Steps[i].Think = time.Duration(float64(gap) / opts.Speed)
+			}
+		}
+	}
+	return sc, nil
+}
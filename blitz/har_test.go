@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const sampleHAR = `{"log": {"entries": [
+  {"startedDateTime": "2024-05-01T10:00:00.000Z", "time": 100,
+   "request": {"method": "GET", "url": "https://shop.example/", "headers": [
+     {"name": ":authority", "value": "shop.example"},
+     {"name": "Accept", "value": "text/html"},
+     {"name": "Accept-Encoding", "value": "gzip, br"}]}},
+  {"startedDateTime": "2024-05-01T10:00:00.050Z", "time": 20,
+   "request": {"method": "GET", "url": "https://analytics.example/collect", "headers": []}},
+  {"startedDateTime": "2024-05-01T10:00:02.100Z", "time": 50,
+   "request": {"method": "POST", "url": "https://shop.example/cart?id=1", "headers": [],
+     "postData": {"mimeType": "application/json", "text": "{\"sku\":42}"}}},
+  {"startedDateTime": "2024-05-01T10:00:02.150Z", "time": 30,
+   "request": {"method": "GET", "url": "https://shop.example/cart", "headers": []}}
+]}}`
+
+func writeHAR(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session.har")
+	if err := os.WriteFile(path, []byte(sampleHAR), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadHAR(t *testing.T) {
+	common := http.Header{"Authorization": {"Bearer t"}}
+	sc, err := loadHAR(writeHAR(t), harOptions{Speed: 1}, common)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Steps) != 3 {
+		t.Fatalf("got %d steps, want 3 (third-party host skipped)", len(sc.Steps))
+	}
+
+	first := sc.Steps[0].Tmpl
+	if first.Name != "GET /" || first.Header.Get("Accept") != "text/html" || first.Header.Get("Authorization") != "Bearer t" {
+		t.Errorf("first step = %s %v", first.Name, first.Header)
+	}
+	if _, ok := first.Header[":authority"]; ok || first.Header.Get("Accept-Encoding") != "" {
+		t.Errorf("pseudo and transport headers should be dropped: %v", first.Header)
+	}
+
+	post := sc.Steps[1].Tmpl
+	if post.Method != http.MethodPost || string(post.Body) != `{"sku":42}` || post.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("post step = %s %q %v", post.Method, post.Body, post.Header)
+	}
+
+	// First response ends at 100ms and the next request starts at 2.1s.
+	if got := sc.Steps[0].Think; got != 2*time.Second {
+		t.Errorf("think after step 1 = %v, want 2s", got)
+	}
+	// The second request ends at 2.15s, exactly when the third starts.
+	if got := sc.Steps[1].Think; got != 0 {
+		t.Errorf("think after step 2 = %v, want 0", got)
+	}
+}
+
+func TestLoadHAROptions(t *testing.T) {
+	path := writeHAR(t)
+
+	sc, err := loadHAR(path, harOptions{Speed: 4, AllHosts: true, BaseURL: "http://localhost:8080"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Steps) != 4 {
+		t.Fatalf("got %d steps, want 4 with AllHosts", len(sc.Steps))
+	}
+	if got := sc.Steps[2].Tmpl.URL; got != "http://localhost:8080/cart?id=1" {
+		t.Errorf("retargeted URL = %q", got)
+	}
+	// 2.1s - 0.07s = 2.03s of idle time after the analytics call, at 4x.
+	if got, want := sc.Steps[1].Think, 2030*time.Millisecond/4; got != want {
+		t.Errorf("think at 4x = %v, want %v", got, want)
+	}
+
+	sc, err = loadHAR(path, harOptions{Speed: 0}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, st := range sc.Steps {
+		if st.Think != 0 {
+			t.Errorf("step %d think = %v, want none at speed 0", i+1, st.Think)
+		}
+	}
+}
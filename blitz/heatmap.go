@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"time"
+)
+
+// heatmapHeader names the -heatmap columns. Each row is one cell of the
+// heatmap: the requests completed within one second whose latency fell
+// in [latency_min_ms, latency_max_ms). Empty cells are left out.
+var heatmapHeader = []string{"time", "offset_s", "latency_min_ms", "latency_max_ms", "count"}
+
+// heatmapRows converts the run's timeline into -heatmap rows. Latency
+// buckets are those of timeSlot, four per doubling.
+func heatmapRows(points []TimelinePoint) [][]string {
+	var rows [][]string
+	for _, p := range points {
+		for i, n := range p.Latencies {
+			if n == 0 {
+				continue
+			}
+			var from time.Duration
+			if i > 0 {
+				from = slotBucketMax(i - 1)
+			}
+			rows = append(rows, []string{
+				p.Time.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(p.Offset.Seconds(), 'f', -1, 64),
+				strconv.FormatFloat(ms(from), 'f', 3, 64),
+				strconv.FormatFloat(ms(slotBucketMax(i)), 'f', 3, 64),
+				strconv.FormatUint(uint64(n), 10),
+			})
+		}
+	}
+	return rows
+}
+
+// writeHeatmap writes the -heatmap CSV for points to path.
+func writeHeatmap(path string, points []TimelinePoint) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(heatmapHeader)
+	w.WriteAll(heatmapRows(points))
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
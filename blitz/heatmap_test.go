@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHeatmapRows(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	var tl timeline
+	tl.Record(base, false, 10*time.Millisecond)
+	tl.Record(base.Add(100*time.Millisecond), false, 10*time.Millisecond)
+	tl.Record(base.Add(2*time.Second), true, 200*time.Millisecond)
+
+	path := filepath.Join(t.TempDir(), "heatmap.csv")
+	if err := writeHeatmap(path, tl.Points()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A header, then one row per occupied cell; the empty second is left out.
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3: %v", len(rows), rows)
+	}
+	first, last := rows[1], rows[2]
+	if first[0] != "2023-11-14T22:13:20Z" || first[1] != "0" || first[4] != "2" {
+		t.Errorf("first cell = %v, want both 10ms requests in second 0", first)
+	}
+	if last[1] != "2" || last[4] != "1" {
+		t.Errorf("last cell = %v, want the 200ms request in second 2", last)
+	}
+	from, _ := strconv.ParseFloat(first[2], 64)
+	to, _ := strconv.ParseFloat(first[3], 64)
+	if from > 10 || to <= 10 {
+		t.Errorf("latency bucket [%s, %s) ms does not hold 10ms", first[2], first[3])
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// defaultHistoryFile is where tagged runs are recorded unless
+// -history-file says otherwise.
+const defaultHistoryFile = "blitz-history.jsonl"
+
+// historyEntry is one line of the history file: the headline numbers
+// of a run recorded with -tag.
+type historyEntry struct {
+	Time       time.Time   `json:"time"`
+	Tag        string      `json:"tag"`
+	Target     string      `json:"target"`
+	Requests   int         `json:"requests"`
+	Failed     int         `json:"failed"`
+	DurationMs float64     `json:"duration_ms"`
+	RPS        float64     `json:"rps"`
+	Latency    jsonLatency `json:"latency_ms"`
+	Partial    bool        `json:"partial,omitempty"`
+}
+
+func newHistoryEntry(tag, target string, s Summary) historyEntry {
+	return historyEntry{
+		Time:       time.Now().UTC().Truncate(time.Second),
+		Tag:        tag,
+		Target:     target,
+		Requests:   s.Total,
+		Failed:     s.Failed,
+		DurationMs: ms(s.Duration),
+		RPS:        s.RPS,
+		Latency:    newJSONLatency(s.Latency),
+		Partial:    s.Partial,
+	}
+}
+
+// target describes what the run loaded, for the history table.
+func (c config) target() string {
+	switch {
+	case c.HAR != "":
+		return c.HAR
+	case c.Scenario != "":
+		return c.Scenario
+	case c.Sitemap != "":
+		return c.Sitemap
+	case len(c.URLs) > 1:
+		return fmt.Sprintf("%s (+%d)", c.URLs[0].URL, len(c.URLs)-1)
+	case len(c.URLs) == 1:
+		return c.URLs[0].URL
+	}
+	return ""
+}
+
+// appendHistory adds e to the history file at path, creating it if
+// needed. Entries are only ever appended, so the file is a log of
+// every tagged run.
+func appendHistory(path string, e historyEntry) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(e); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadHistory reads every entry of the history file at path.
+func loadHistory(path string) ([]historyEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var e historyEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// runHistory implements "blitz history": the recorded tagged runs as a
+// table, oldest first, for a quick look at the trend.
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("blitz history", flag.ExitOnError)
+	path := fs.String("history-file", defaultHistoryFile, "History file written by runs with -tag")
+	tag := fs.String("tag", "", "Only show runs whose tag starts with this prefix")
+	last := fs.Int("last", 0, "Only show the most recent N runs (0 for all)")
+	fs.Parse(args)
+
+	entries, err := loadHistory(*path)
+	if err != nil {
+		return fail(err)
+	}
+	var shown []historyEntry
+	for _, e := range entries {
+		if strings.HasPrefix(e.Tag, *tag) {
+			shown = append(shown, e)
+		}
+	}
+	if *last > 0 && len(shown) > *last {
+		shown = shown[len(shown)-*last:]
+	}
+	renderHistory(os.Stdout, shown)
+	return exitOK
+}
+
+// renderHistory prints entries as a table, one run per row.
+func renderHistory(w io.Writer, entries []historyEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No tagged runs recorded.")
+		return
+	}
+	msText := func(v float64) string {
+		return time.Duration(v * float64(time.Millisecond)).Round(time.Millisecond).String()
+	}
+	table := cli.NewTable("Time", "Tag", "Target", "Requests", "Req/s", "Fail %", "P50", "P95", "P99")
+	table.Writer = w
+	for _, e := range entries {
+		tag := e.Tag
+		if e.Partial {
+			tag += " (partial)"
+		}
+		table.AddRow(e.Time.Local().Format("2006-01-02 15:04"),
+			tag,
+			truncate(e.Target, 40),
+			fmt.Sprintf("%d", e.Requests),
+			fmt.Sprintf("%.2f", e.RPS),
+			percentOf(e.Failed, e.Requests),
+			msText(e.Latency.P50),
+			msText(e.Latency.P95),
+			msText(e.Latency.P99))
+	}
+	table.Render()
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	c := newCollector()
+	c.Add(Result{Status: 200, Latency: 10 * time.Millisecond})
+	c.Add(Result{Status: 500, Latency: 30 * time.Millisecond})
+	s := c.Summary(time.Second)
+
+	cfg := config{URLs: urlList{{URL: "http://api/a"}, {URL: "/b"}}}
+	for _, tag := range []string{"release-1.3", "release-1.4"} {
+		if err := appendHistory(path, newHistoryEntry(tag, cfg.target(), s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Tag != "release-1.3" || entries[1].Tag != "release-1.4" {
+		t.Fatalf("entries = %+v, want both runs in order", entries)
+	}
+	e := entries[1]
+	if e.Target != "http://api/a (+1)" || e.Requests != 2 || e.Failed != 1 || e.Latency.P99 != 30 {
+		t.Errorf("entry = %+v", e)
+	}
+
+	var buf bytes.Buffer
+	renderHistory(&buf, entries)
+	if out := buf.String(); !strings.Contains(out, "release-1.4") || !strings.Contains(out, "50.00%") {
+		t.Errorf("history table:\n%s", out)
+	}
+}
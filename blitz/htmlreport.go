@@ -0,0 +1,360 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The -report page is a single self-contained file: charts are inline
+// SVG drawn here, so it opens anywhere without network access.
+
+// Chart geometry, in SVG user units.
+const (
+	chartWidth   = 760
+	chartHeight  = 240
+	chartLeft    = 64 // room for y-axis labels
+	chartRight   = 16
+	chartTop     = 16
+	chartBottom  = 32 // room for x-axis labels
+	chartYTicks  = 4
+	chartXLabels = 8
+)
+
+// chartSeries is one line of a time chart.
+type chartSeries struct {
+	Name   string
+	Color  string
+	Values []float64
+}
+
+// niceCeil rounds v up to 1, 2 or 5 times a power of ten, so axis
+// labels come out round.
+func niceCeil(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	step := 1.0
+	for step*10 <= v {
+		step *= 10
+	}
+	for step > v {
+		step /= 10
+	}
+	for _, m := range []float64{1, 2, 5, 10} {
+		if step*m >= v {
+			return step * m
+		}
+	}
+	return step * 10
+}
+
+// formatAxis renders an axis value without needless decimals.
+func formatAxis(v float64, unit string) string {
+	if v == float64(int64(v)) {
+		return fmt.Sprintf("%d%s", int64(v), unit)
+	}
+	return fmt.Sprintf("%.1f%s", v, unit)
+}
+
+// timeChart draws series that share a per-second x axis.
+func timeChart(unit string, series ...chartSeries) template.HTML {
+	n := 0
+	peak := 0.0
+	for _, s := range series {
+		n = max(n, len(s.Values))
+		for _, v := range s.Values {
+			peak = max(peak, v)
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	top := niceCeil(peak)
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	x := func(i int) float64 {
+		if n == 1 {
+			return chartLeft + plotW/2
+		}
+		return chartLeft + plotW*float64(i)/float64(n-1)
+	}
+	y := func(v float64) float64 { return chartTop + plotH*(1-v/top) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+	for i := 0; i <= chartYTicks; i++ {
+		v := top * float64(i) / chartYTicks
+		fmt.Fprintf(&b, `<line x1="%d" x2="%d" y1="%.1f" y2="%.1f" class="grid"/>`, chartLeft, chartWidth-chartRight, y(v), y(v))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="ylabel">%s</text>`, chartLeft-6, y(v)+4, template.HTMLEscapeString(formatAxis(v, unit)))
+	}
+	every := max(1, (n+chartXLabels-1)/chartXLabels)
+	for i := 0; i < n; i += every {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="xlabel">%ds</text>`, x(i), chartHeight-10, i)
+	}
+	for _, s := range series {
+		points := make([]string, len(s.Values))
+		for i, v := range s.Values {
+			points[i] = fmt.Sprintf("%.1f,%.1f", x(i), y(v))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), s.Color)
+	}
+	b.WriteString(`</svg><div class="legend">`)
+	for _, s := range series {
+		fmt.Fprintf(&b, `<span><i style="background:%s"></i>%s</span>`, s.Color, template.HTMLEscapeString(s.Name))
+	}
+	b.WriteString(`</div>`)
+	return template.HTML(b.String())
+}
+
+// distributionChart draws the latency buckets as vertical bars.
+func distributionChart(buckets []LatencyBucket) template.HTML {
+	if len(buckets) == 0 {
+		return ""
+	}
+	peak := 0
+	for _, bk := range buckets {
+		peak = max(peak, bk.Count)
+	}
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	slot := plotW / float64(len(buckets))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg viewBox="0 0 %d %d" class="chart">`, chartWidth, chartHeight)
+	for i, bk := range buckets {
+		h := plotH * float64(bk.Count) / float64(peak)
+		bx := chartLeft + slot*float64(i)
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" class="bar"><title>%d requests</title></rect>`,
+			bx+slot*0.1, chartTop+plotH-h, slot*0.8, h, bk.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" class="count">%d</text>`, bx+slot/2, chartTop+plotH-h-4, bk.Count)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="xlabel">%s</text>`, bx+slot/2, chartHeight-10, template.HTMLEscapeString(bucketLabel(bk)))
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// htmlRow is one row of a two-or-more column table in the report.
+type htmlRow []string
+
+// htmlReport is the data behind the -report template.
+type htmlReport struct {
+	Generated    string
+	Partial      bool
+	Aborted      string
+	Summary      []htmlRow
+	Latency      []htmlRow
+	Corrected    bool
+	StatusCodes  []htmlRow
+	Errors       []htmlRow
+	Endpoints    []htmlRow
+	Bodies       []htmlRow
+	AB           *ABComparison
+	ABRows       []htmlRow
+	ByStatus     []htmlRow
+	Assertions   []AssertionResult
+	Throughput   template.HTML
+	LatencyChart template.HTML
+	Distribution template.HTML
+}
+
+func newHTMLReport(s Summary, generated time.Time) htmlReport {
+	r := htmlReport{
+		Generated: generated.Format(time.RFC1123),
+		Partial:   s.Partial,
+		Aborted:   s.Aborted,
+		Corrected: s.Corrected != nil,
+		Summary: []htmlRow{
+			{"Total Requests", fmt.Sprintf("%d", s.Total)},
+			{"Successful", fmt.Sprintf("%d", s.Successful)},
+			{"Failed", fmt.Sprintf("%d", s.Failed)},
+			{"Duration", s.Duration.Round(time.Millisecond).String()},
+			{"Requests/sec", fmt.Sprintf("%.2f", s.RPS)},
+			{"Data Received", formatBytes(s.Bytes.Total)},
+			{"Throughput", formatBytes(int64(s.Bytes.Throughput)) + "/s"},
+		},
+		Assertions: s.Assertions,
+	}
+	if s.Apdex != nil {
+		r.Summary = append(r.Summary, htmlRow{"Apdex", apdexRow(*s.Apdex)})
+	}
+	if s.Timeouts > 0 {
+		r.Summary = append(r.Summary, htmlRow{"Timeouts", fmt.Sprintf("%d", s.Timeouts)})
+	}
+	if wire, ok := wireRow(s.Bytes); ok {
+		r.Summary = append(r.Summary, htmlRow{"Data on Wire", wire})
+	}
+	if len(s.Protocols) > 0 {
+		r.Summary = append(r.Summary, htmlRow{"Protocol", formatCounts(s.Protocols)})
+	}
+	if s.Self != nil {
+		for _, row := range selfStatsRows(*s.Self) {
+			r.Summary = append(r.Summary, htmlRow{"blitz " + row[0], row[1]})
+		}
+	}
+
+	latencyRow := func(name string, get func(LatencyStats) time.Duration) {
+		row := htmlRow{name, get(s.Latency).Round(time.Microsecond).String()}
+		if s.Corrected != nil {
+			row = append(row, get(*s.Corrected).Round(time.Microsecond).String())
+		}
+		r.Latency = append(r.Latency, row)
+	}
+	latencyRow("Min", func(l LatencyStats) time.Duration { return l.Min })
+	latencyRow("Average", func(l LatencyStats) time.Duration { return l.Mean })
+	for _, p := range latencyPercentiles(s.Latency) {
+		latencyRow(p.Name, p.Get)
+	}
+	latencyRow("Max", func(l LatencyStats) time.Duration { return l.Max })
+
+	for _, code := range slices.Sorted(maps.Keys(s.StatusCodes)) {
+		count := s.StatusCodes[code]
+		r.StatusCodes = append(r.StatusCodes, htmlRow{
+			fmt.Sprintf("%d %s", code, http.StatusText(code)), fmt.Sprintf("%d", count), percentOf(count, s.Total),
+		})
+	}
+	for _, cat := range s.ErrorKinds {
+		r.Errors = append(r.Errors, htmlRow{cat.Name, fmt.Sprintf("%d", cat.Count), percentOf(cat.Count, s.Total), cat.Example})
+	}
+	for _, e := range s.Endpoints {
+		r.Endpoints = append(r.Endpoints, endpointRow(e))
+	}
+	for _, row := range bodyHashRows(s.Endpoints) {
+		r.Bodies = append(r.Bodies, row)
+	}
+	if s.AB != nil {
+		r.AB = s.AB
+		for _, row := range abRows(s.AB) {
+			r.ABRows = append(r.ABRows, row)
+		}
+	}
+	for _, sl := range s.ByStatus {
+		r.ByStatus = append(r.ByStatus, statusLatencyRow(sl))
+	}
+
+	if len(s.Timeline) > 0 {
+		requests := make([]float64, len(s.Timeline))
+		failures := make([]float64, len(s.Timeline))
+		mean := make([]float64, len(s.Timeline))
+		worst := make([]float64, len(s.Timeline))
+		for i, p := range s.Timeline {
+			requests[i] = float64(p.Requests)
+			failures[i] = float64(p.Failed)
+			mean[i] = ms(p.Mean)
+			worst[i] = ms(p.Max)
+		}
+		r.Throughput = timeChart("/s",
+			chartSeries{Name: "Requests/sec", Color: "#2b7bb9", Values: requests},
+			chartSeries{Name: "Failures/sec", Color: "#d9534f", Values: failures})
+		r.LatencyChart = timeChart("ms",
+			chartSeries{Name: "Mean", Color: "#2b7bb9", Values: mean},
+			chartSeries{Name: "Max", Color: "#f0ad4e", Values: worst})
+	}
+	r.Distribution = distributionChart(s.Distribution)
+	return r
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>blitz report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em auto; max-width: 820px; color: #222; }
+h1 { margin-bottom: 0; }
+h2 { border-bottom: 1px solid #ddd; padding-bottom: .2em; margin-top: 1.6em; }
+.meta { color: #777; margin-top: .2em; }
+.warning { background: #fcf8e3; border: 1px solid #f0ad4e; padding: .6em 1em; }
+table { border-collapse: collapse; }
+th, td { text-align: left; padding: .25em 1.2em .25em 0; border-bottom: 1px solid #eee; }
+th { font-weight: 600; }
+.pass { color: #3c763d; font-weight: 600; }
+.fail { color: #d9534f; font-weight: 600; }
+.chart { width: 100%; height: auto; }
+.chart .grid { stroke: #eee; }
+.chart text { font-size: 11px; fill: #777; }
+.chart .ylabel { text-anchor: end; }
+.chart .xlabel, .chart .count { text-anchor: middle; }
+.chart .bar { fill: #2b7bb9; }
+.legend span { margin-right: 1.5em; font-size: 13px; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: .4em; vertical-align: -1px; }
+</style>
+</head>
+<body>
+<h1>blitz report</h1>
+<p class="meta">Generated {{.Generated}}</p>
+{{if .Aborted}}<p class="warning">Run aborted: {{.Aborted}}</p>
+{{else if .Partial}}<p class="warning">Run interrupted: results cover completed requests only</p>{{end}}
+
+<h2>Summary</h2>
+<table>{{range .Summary}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>{{end}}</table>
+
+{{with .Throughput}}<h2>Throughput</h2>
+{{.}}{{end}}
+
+<h2>Latency</h2>
+<table>
+<tr><th>Percentile</th><th>Duration</th>{{if .Corrected}}<th>Corrected</th>{{end}}</tr>
+{{range .Latency}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}
+</table>
+{{if .Corrected}}<p class="meta">Corrected latency is measured from each request's scheduled send time (coordinated omission).</p>{{end}}
+{{with .LatencyChart}}<h3>Latency over time</h3>
+{{.}}{{end}}
+{{with .Distribution}}<h3>Distribution</h3>
+{{.}}{{end}}
+
+{{with .StatusCodes}}<h2>Status Codes</h2>
+<table><tr><th>Status</th><th>Count</th><th>Percent</th></tr>
+{{range .}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{with .Errors}}<h2>Errors</h2>
+<table><tr><th>Category</th><th>Count</th><th>Percent</th><th>Example</th></tr>
+{{range .}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{if gt (len .ByStatus) 1}}<h2>Latency by Status</h2>
+<table><tr><th>Status</th><th>Count</th><th>Mean</th><th>P50</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{range .ByStatus}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{with .AB}}<h2>A/B Comparison</h2>
+<p class="meta">A: {{.URLA}}<br>B: {{.URLB}}</p>
+<table><tr><th>Metric</th><th>A</th><th>B</th><th>B vs A</th></tr>
+{{range $.ABRows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{if gt (len .Endpoints) 1}}<h2>Per Endpoint</h2>
+<table><tr><th>Endpoint</th><th>Requests</th><th>Failed</th><th>Fail %</th><th>Mean</th><th>P50</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{range .Endpoints}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{with .Bodies}}<h2>Response Bodies</h2>
+<table><tr><th>Endpoint</th><th>Body SHA-256</th><th>Status</th><th>Size</th><th>Count</th><th>Percent</th></tr>
+{{range .}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table>{{end}}
+
+{{with .Assertions}}<h2>Assertions</h2>
+<table><tr><th>Assertion</th><th>Result</th><th>Detail</th></tr>
+{{range .}}<tr><td>{{.Name}}</td>{{if .Passed}}<td class="pass">PASS</td>{{else}}<td class="fail">FAIL</td>{{end}}<td>{{.Detail}}</td></tr>{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+// renderHTML writes s as a self-contained HTML page.
+func renderHTML(w io.Writer, s Summary) error {
+	return htmlReportTemplate.Execute(w, newHTMLReport(s, time.Now()))
+}
+
+// writeHTMLReport writes the -report page to path.
+func writeHTMLReport(path string, s Summary) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := renderHTML(f, s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
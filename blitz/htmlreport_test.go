@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderHTML(t *testing.T) {
+	c := newCollector()
+	start := time.Now()
+	for i := range 20 {
+		res := Result{Step: "GET /", Status: 200, Latency: time.Duration(i+1) * time.Millisecond, Timestamp: start.Add(time.Duration(i) * 100 * time.Millisecond)}
+		if i == 7 {
+			res = Result{Step: "GET /", Status: 500, Latency: time.Millisecond, Timestamp: res.Timestamp}
+		}
+		c.Add(res)
+	}
+	s := c.Summary(2 * time.Second)
+	s.ErrorKinds[0].Example = "<script>alert(1)</script>"
+	s.Assertions = []AssertionResult{{Name: "p95 < 1s", Passed: true}}
+
+	var buf bytes.Buffer
+	if err := renderHTML(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<h2>Summary</h2>", "<h2>Throughput</h2>", "<polyline", "<h3>Distribution</h3>", "500 Internal Server Error", `class="pass"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("error examples must be escaped")
+	}
+}
+
+func TestNiceCeil(t *testing.T) {
+	tests := []struct{ in, want float64 }{
+		{0, 1}, {0.3, 0.5}, {1, 1}, {7, 10}, {13, 20}, {42, 50}, {180, 200}, {999, 1000},
+	}
+	for _, tt := range tests {
+		if got := niceCeil(tt.in); got != tt.want {
+			t.Errorf("niceCeil(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
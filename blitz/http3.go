@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// newHTTP3Transport builds the round tripper for -http3: HTTP/3 over
+// QUIC, with the same TLS options as the TCP transport. QUIC dials over
+// UDP, so the options that shape TCP connections are rejected rather
+// than silently ignored; -resolve overrides still apply.
+func newHTTP3Transport(opts transportOptions) (*http3.Transport, error) {
+	switch {
+	case opts.HTTP1 || opts.HTTP2:
+		return nil, errors.New("-http3 is mutually exclusive with -http1 and -http2")
+	case opts.Proxy != "":
+		return nil, errors.New("-http3 requests cannot go through a -proxy")
+	case opts.UnixSocket != "":
+		return nil, errors.New("-http3 and -unix-socket are mutually exclusive")
+	case opts.CertDir != "":
+		return nil, errors.New("-cert-dir does not support -http3; use -cert and -key")
+	case opts.IPv4Only || opts.IPv6Only || opts.DNSServer != "" || opts.DNSCache || opts.DNSFresh:
+		return nil, errors.New("-http3 cannot be combined with -4, -6, -dns-server, -dns-cache or -dns-fresh")
+	case opts.DisableKeepAlive || opts.MaxConnsPerHost > 0:
+		return nil, errors.New("-http3 multiplexes requests over one connection per host; drop -disable-keepalive and -max-conns-per-host")
+	}
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := &http3.Transport{TLSClientConfig: tlsConfig}
+
+	overrides, err := parseResolve(opts.Resolve)
+	if err != nil {
+		return nil, err
+	}
+	if len(overrides) > 0 {
+		// The TLS server name is taken from the URL before dialing, so
+		// pinning the address leaves it unchanged.
+		transport.Dial = func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			if pinned, ok := overrides[addr]; ok {
+				addr = pinned
+			}
+			return quic.DialAddrEarly(ctx, addr, tlsCfg, cfg)
+		}
+	}
+	return transport, nil
+}
+
+// checkHTTP3 validates -http3 against the run's targets. nonHTTP
+// reports a WebSocket, tcp:// or gRPC run.
+func checkHTTP3(cfg config, nonHTTP bool) error {
+	if !cfg.Transport.HTTP3 {
+		return nil
+	}
+	switch {
+	case nonHTTP:
+		return errors.New("-http3 only applies to HTTP targets")
+	case cfg.Connections > 0:
+		return errors.New("-connections sends HTTP/1.1 and cannot be combined with -http3")
+	}
+	for _, u := range cfg.URLs {
+		if !strings.HasPrefix(u.URL, "https://") {
+			return fmt.Errorf("-http3 needs https:// targets, not %s", u.URL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3Transport(t *testing.T) {
+	// httptest supplies a certificate; the HTTP/3 server reuses it.
+	tlsSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsSrv.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no UDP: %v", err)
+	}
+	srv := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(tlsSrv.TLS.Clone()),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}),
+	}
+	go srv.Serve(conn)
+	defer srv.Close()
+
+	// -resolve sends the named host to the server's address.
+	_, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	transport, err := newHTTP3Transport(transportOptions{
+		Insecure: true,
+		Resolve:  stringList{"h3.test:" + port + ":127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.Close()
+
+	r := &requester{client: &http.Client{Transport: transport}}
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: "https://h3.test:" + port + "/"}
+	for i := range 2 {
+		res := r.makeRequest(context.Background(), tmpl, nil)
+		if res.Error != nil {
+			t.Fatalf("request %d: %v", i, res.Error)
+		}
+		if res.Proto != "HTTP/3.0" || res.Status != http.StatusOK || res.Bytes != 2 {
+			t.Errorf("request %d: proto %s, status %d, %d bytes; want HTTP/3.0, 200, 2", i, res.Proto, res.Status, res.Bytes)
+		}
+		if res.Timing.Family != "IPv4" || res.Timing.Reused != (i > 0) {
+			t.Errorf("request %d: timing = %+v, want an IPv4 connection reused after the first request", i, res.Timing)
+		}
+	}
+}
+
+func TestCheckHTTP3(t *testing.T) {
+	base := config{URLs: urlList{{URL: "https://api.test/"}}}
+	base.Transport.HTTP3 = true
+	if err := checkHTTP3(base, false); err != nil {
+		t.Errorf("checkHTTP3() error = %v", err)
+	}
+	plain := base
+	plain.URLs = urlList{{URL: "http://api.test/"}}
+	if err := checkHTTP3(plain, false); err == nil {
+		t.Error("-http3 with an http:// target should be rejected")
+	}
+	if err := checkHTTP3(base, true); err == nil {
+		t.Error("-http3 with a non-HTTP target should be rejected")
+	}
+	if _, err := newHTTP3Transport(transportOptions{HTTP3: true, Proxy: "http://proxy:3128"}); err == nil {
+		t.Error("-http3 with -proxy should be rejected")
+	}
+}
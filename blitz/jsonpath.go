@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonPath is a path into a JSON response body, such as
+// $.data.items[0].id, used by -match-jsonpath checks and scenario
+// captures. A check may compare the value with a JSON literal using ==
+// or !=; without a comparison the value must be present and not null.
+type jsonPath struct {
+	expr     string
+	segments []any // string keys and int indexes
+	op       string
+	want     any
+}
+
+// parseJSONPath parses expressions like `$.status=="ok"`. Keys are
+// written .name or ['name']; indexes [n]. Literals are JSON, except
+// that strings may also be single-quoted.
+func parseJSONPath(expr string) (*jsonPath, error) {
+	p := &jsonPath{expr: expr}
+	path := strings.TrimSpace(expr)
+	if i := findOperator(path); i >= 0 {
+		p.op = path[i : i+2]
+		literal := strings.TrimSpace(path[i+2:])
+		path = strings.TrimSpace(path[:i])
+		if len(literal) >= 2 && literal[0] == '\'' && literal[len(literal)-1] == '\'' {
+			literal = strconv.Quote(literal[1 : len(literal)-1])
+		}
+		if err := json.Unmarshal([]byte(literal), &p.want); err != nil {
+			return nil, fmt.Errorf("invalid JSON path %q: %s is not a JSON value", expr, literal)
+		}
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSON path %q: path must start with $", expr)
+	}
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := 1
+			for end < len(rest) && isKeyChar(rest[end]) {
+				end++
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("invalid JSON path %q: empty key", expr)
+			}
+			p.segments = append(p.segments, rest[1:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q: unclosed [", expr)
+			}
+			inner := rest[1:end]
+			if n := len(inner); n >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[n-1] == inner[0] {
+				p.segments = append(p.segments, inner[1:n-1])
+			} else if idx, err := strconv.Atoi(inner); err == nil && idx >= 0 {
+				p.segments = append(p.segments, idx)
+			} else {
+				return nil, fmt.Errorf("invalid JSON path %q: bad index [%s]", expr, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid JSON path %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// findOperator returns the offset of the first == or != outside a
+// bracketed key, or -1.
+func findOperator(s string) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '=', '!':
+			if depth == 0 && s[i+1] == '=' {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isKeyChar reports whether c may appear in a .name key; other keys
+// need the ['name'] form.
+func isKeyChar(c byte) bool {
+	return c == '_' || c == '-' || c == '$' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// lookup returns the value the path points to in body.
+func (p *jsonPath) lookup(body []byte) (any, error) {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, fmt.Errorf("%s: body is not JSON", p.expr)
+	}
+	for _, seg := range p.segments {
+		switch seg := seg.(type) {
+		case string:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q", p.expr, seg)
+			}
+			if v, ok = obj[seg]; !ok {
+				return nil, fmt.Errorf("%s: no key %q", p.expr, seg)
+			}
+		case int:
+			arr, ok := v.([]any)
+			if !ok || seg >= len(arr) {
+				return nil, fmt.Errorf("%s: no index %d", p.expr, seg)
+			}
+			v = arr[seg]
+		}
+	}
+	return v, nil
+}
+
+// check evaluates the path against body, returning why it failed.
+func (p *jsonPath) check(body []byte) error {
+	v, err := p.lookup(body)
+	if err != nil {
+		return err
+	}
+
+	switch p.op {
+	case "":
+		if v == nil {
+			return fmt.Errorf("%s: value is null", p.expr)
+		}
+	case "==":
+		if !reflect.DeepEqual(v, p.want) {
+			return fmt.Errorf("%s: got %s", p.expr, compactJSON(v))
+		}
+	case "!=":
+		if reflect.DeepEqual(v, p.want) {
+			return fmt.Errorf("%s: got %s", p.expr, compactJSON(v))
+		}
+	}
+	return nil
+}
+
+// compactJSON renders v for an error message, shortened if long.
+func compactJSON(v any) string {
+	data, _ := json.Marshal(v)
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
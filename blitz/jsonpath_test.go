@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestJSONPath(t *testing.T) {
+	body := []byte(`{"status":"ok","count":3,"data":{"items":[{"id":"a"},{"id":null}],"weird key":true}}`)
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{`$.status=="ok"`, true},
+		{`$.status == 'ok'`, true},
+		{`$.status=="error"`, false},
+		{`$.status!="error"`, true},
+		{`$.count==3`, true},
+		{`$.data.items[0].id=="a"`, true},
+		{`$.data.items[0]`, true},
+		{`$.data.items[1].id`, false}, // null
+		{`$.data.items[5]`, false},
+		{`$.data['weird key']==true`, true},
+		{`$.missing`, false},
+		{`$.status.nested`, false},
+	}
+	for _, tt := range tests {
+		p, err := parseJSONPath(tt.expr)
+		if err != nil {
+			t.Errorf("parseJSONPath(%q): %v", tt.expr, err)
+			continue
+		}
+		if err := p.check(body); (err == nil) != tt.ok {
+			t.Errorf("%s: check() = %v, want ok=%v", tt.expr, err, tt.ok)
+		}
+	}
+
+	p, _ := parseJSONPath(`$.status=="ok"`)
+	if err := p.check([]byte("<html>")); err == nil {
+		t.Error("non-JSON body should fail")
+	}
+}
+
+func TestParseJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		`status=="ok"`,
+		`$.status==ok`,
+		`$..status`,
+		`$.items[x]`,
+		`$.items[0`,
+	} {
+		if _, err := parseJSONPath(expr); err == nil {
+			t.Errorf("parseJSONPath(%q) should fail", expr)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"time"
+)
+
+// JUnit XML, as read by Jenkins, GitLab and most CI test dashboards.
+// Only the parts those tools display are written.
+type (
+	junitSuites struct {
+		XMLName  xml.Name     `xml:"testsuites"`
+		Name     string       `xml:"name,attr"`
+		Tests    int          `xml:"tests,attr"`
+		Failures int          `xml:"failures,attr"`
+		Errors   int          `xml:"errors,attr"`
+		Time     string       `xml:"time,attr"`
+		Suites   []junitSuite `xml:"testsuite"`
+	}
+	junitSuite struct {
+		Name       string          `xml:"name,attr"`
+		Tests      int             `xml:"tests,attr"`
+		Failures   int             `xml:"failures,attr"`
+		Errors     int             `xml:"errors,attr"`
+		Time       string          `xml:"time,attr"`
+		Properties []junitProperty `xml:"properties>property,omitempty"`
+		Cases      []junitCase     `xml:"testcase"`
+	}
+	junitProperty struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	junitCase struct {
+		Name      string        `xml:"name,attr"`
+		Classname string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitProblem `xml:"failure,omitempty"`
+		Error     *junitProblem `xml:"error,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+	}
+	junitProblem struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+	}
+)
+
+// newJUnit converts the run's assertion and -threshold results into a
+// test suite named after target, one test case each. A "run" case
+// comes first and errors if the run was aborted, so a CI job fails on
+// that even without assertions. The headline numbers are attached as
+// suite properties.
+func newJUnit(target string, s Summary) junitSuites {
+	seconds := fmt.Sprintf("%.3f", s.Duration.Seconds())
+	suite := junitSuite{
+		Name: "blitz " + target,
+		Time: seconds,
+		Properties: []junitProperty{
+			{"total_requests", fmt.Sprint(s.Total)},
+			{"failed_requests", fmt.Sprint(s.Failed)},
+			{"requests_per_second", fmt.Sprintf("%.2f", s.RPS)},
+			{"latency_p50", s.Latency.P50.Round(time.Millisecond).String()},
+			{"latency_p95", s.Latency.P95.Round(time.Millisecond).String()},
+			{"latency_p99", s.Latency.P99.Round(time.Millisecond).String()},
+		},
+	}
+
+	run := junitCase{
+		Name:      "run",
+		Classname: "blitz",
+		Time:      seconds,
+		SystemOut: fmt.Sprintf("%d requests, %d failed, %.2f req/s", s.Total, s.Failed, s.RPS),
+	}
+	if s.Aborted != "" {
+		run.Error = &junitProblem{Message: s.Aborted, Type: "aborted"}
+		suite.Errors++
+	}
+	suite.Cases = append(suite.Cases, run)
+
+	for _, a := range s.Assertions {
+		c := junitCase{Name: a.Name, Classname: "blitz.assertions", Time: "0", SystemOut: a.Detail}
+		if !a.Passed {
+			c.Failure = &junitProblem{Message: a.Detail, Type: "assertion"}
+			suite.Failures++
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+
+	return junitSuites{
+		Name:     "blitz",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     seconds,
+		Suites:   []junitSuite{suite},
+	}
+}
+
+// writeJUnit writes the -junit report for s to path.
+func writeJUnit(path, target string, s Summary) error {
+	data, err := xml.MarshalIndent(newJUnit(target, s), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	s := Summary{
+		Total:    100,
+		Failed:   3,
+		Duration: 2500 * time.Millisecond,
+		Aborted:  "error rate 40% over the last 100 requests",
+		Assertions: []AssertionResult{
+			{Name: "p95<500ms", Passed: true, Detail: "p95 was 120ms"},
+			{Name: "failed<1%", Passed: false, Detail: "failed was 3%"},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	if err := writeJUnit(path, "http://api.test/", s); err != nil {
+		t.Fatalf("writeJUnit() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "<?xml") {
+		t.Error("report lacks the XML declaration")
+	}
+
+	var got junitSuites
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatalf("report is not valid XML: %v", err)
+	}
+	if got.Tests != 3 || got.Failures != 1 || got.Errors != 1 || got.Time != "2.500" {
+		t.Errorf("totals = %d tests, %d failures, %d errors, time %s; want 3, 1, 1, 2.500",
+			got.Tests, got.Failures, got.Errors, got.Time)
+	}
+	suite := got.Suites[0]
+	if suite.Name != "blitz http://api.test/" {
+		t.Errorf("suite name = %q", suite.Name)
+	}
+	run, pass, fail := suite.Cases[0], suite.Cases[1], suite.Cases[2]
+	if run.Error == nil || run.Error.Message != s.Aborted {
+		t.Errorf("run case error = %+v, want the abort reason", run.Error)
+	}
+	if pass.Name != "p95<500ms" || pass.Failure != nil {
+		t.Errorf("passing case = %+v", pass)
+	}
+	if fail.Failure == nil || fail.Failure.Message != "failed was 3%" {
+		t.Errorf("failing case = %+v, want a failure with the detail", fail)
+	}
+}
+
+func TestJUnitCleanRun(t *testing.T) {
+	got := newJUnit("scenario.yaml", Summary{Total: 10})
+	if got.Tests != 1 || got.Failures != 0 || got.Errors != 0 || got.Suites[0].Cases[0].Error != nil {
+		t.Errorf("clean run without assertions = %+v, want one passing run case", got)
+	}
+}
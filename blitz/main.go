@@ -2,120 +2,392 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
-	"slices"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/NickDiPreta/gokit/cli"
+	"github.com/NickDiPreta/gokit/loadtest"
+)
+
+// Process exit codes.
+const (
+	exitOK              = 0
+	exitError           = 1 // invalid usage or a setup/output failure
+	exitAssertionFailed = 2 // the run completed but an assertion failed
+	exitAborted         = 3 // the run was stopped by -abort-on-error-rate
 )
 
 func main() {
-	requests := flag.Int("requests", 50, "How many requests to send")
-	workers := flag.Int("workers", 10, "How many workers to use")
-	url := flag.String("url", "", "Target URL to stress test")
-	rate := flag.Int("rate", 0, "Set the maximum requests per second")
+	os.Exit(run(os.Args[1:]))
+}
+
+// fail prints err in red and returns the generic error exit code.
+func fail(err error) int {
+	fmt.Fprintln(os.Stderr, cli.Error("Error: "+err.Error()))
+	return exitError
+}
 
-	flag.Parse()
+// usageFail is like fail but also prints flag usage.
+func usageFail(err error) int {
+	code := fail(err)
+	flag.Usage()
+	return code
+}
 
-	if *url == "" {
-		fmt.Println(cli.Error("Error: URL is required"))
-		flag.Usage()
-		return
+// setupFail reports an error from preparing a run, with usage for
+// mistakes in the options.
+func setupFail(err error) int {
+	var usage usageError
+	if errors.As(err, &usage) {
+		return usageFail(usage.error)
+	}
+	var pre *preflightError
+	if errors.As(err, &pre) {
+		pre.render(os.Stderr)
+		return exitError
 	}
+	return fail(err)
+}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+// run dispatches the "agent", "serve" and "history" subcommands and
+// otherwise runs a load test.
+// "blitz run ..." is accepted as a synonym for "blitz ...".
+func run(args []string) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "agent":
+			return runAgent(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "history":
+			return runHistory(args[1:])
+		case "run":
+			args = args[1:]
+		}
 	}
 
-	jobsChan := jobGenerator(*requests, *rate)
-	resultsChan := make(chan Result)
+	fs := flag.CommandLine
+	var cfg config
+	cfg.registerFlags(fs)
+	fs.Parse(args)
 
-	start := time.Now()
+	if cfg.ConfigFile != "" {
+		if err := applyConfigFile(fs, cfg.ConfigFile, cfg.Profile); err != nil {
+			return fail(err)
+		}
+	} else if cfg.Profile != "" {
+		return usageFail(errors.New("-profile selects a profile from the -config file"))
+	}
+	if cfg.URLFile != "" || cfg.Sitemap != "" {
+		if cfg.Agents != "" {
+			return usageFail(errors.New("-url-file and -sitemap are read on this machine and do not apply to -agents"))
+		}
+		var err error
+		if cfg.Sitemap != "" {
+			err = cfg.loadSitemap(context.Background())
+		} else {
+			err = cfg.loadURLFile(os.Stdin)
+		}
+		if err != nil {
+			return setupFail(err)
+		}
+	}
+	cfg.requestsSet = flagWasSet(fs, "requests")
+	cfg.methodSet = flagWasSet(fs, "method")
+	// Like wrk, -connections alone also sets the concurrency.
+	if cfg.Connections > 0 && !flagWasSet(fs, "workers") {
+		cfg.Workers = cfg.Connections
+	}
 
-	for i := 0; i < *workers; i++ {
-		go worker(context.Background(), client, *url, jobsChan, resultsChan)
+	if cfg.Output != "text" && cfg.Output != "json" && cfg.Output != "markdown" {
+		return usageFail(fmt.Errorf("unknown output format %q", cfg.Output))
+	}
+	if cfg.Quiet {
+		if cfg.Live {
+			return usageFail(errors.New("-quiet and -live are mutually exclusive"))
+		}
+		cli.SetColorsEnabled(false)
 	}
 
-	var results []Result
-	var errs int
+	percentiles, err := parsePercentiles(cfg.Percentiles)
+	if err != nil {
+		return usageFail(err)
+	}
+	statuses, err := parseStatusList(cfg.ExpectStatus)
+	if err != nil {
+		return usageFail(err)
+	}
+	thresholds, err := parseThresholds(cfg.Thresholds)
+	if err != nil {
+		return usageFail(err)
+	}
+	if cfg.ApdexT < 0 {
+		return usageFail(errors.New("-apdex-t must not be negative"))
+	}
+	for _, th := range thresholds {
+		if th.Metric == "apdex" && cfg.ApdexT == 0 {
+			return usageFail(errors.New("an apdex -threshold requires -apdex-t"))
+		}
+	}
+	asserts := assertions{
+		Statuses:     statuses,
+		BodyContains: cfg.ExpectBody,
+		MaxP95:       cfg.ExpectP95,
+		Thresholds:   thresholds,
+	}
 
-	for i := 1; i <= *requests; i++ {
-		res := <-resultsChan
-		if res.Error != nil {
-			errs++
+	// Load the baseline up front so a bad path fails before the run.
+	var baseline *jsonReport
+	if cfg.Compare != "" {
+		if baseline, err = loadBaseline(cfg.Compare); err != nil {
+			return fail(err)
 		}
-		results = append(results, res)
-		duration := time.Since(start)
-		rps := float64(i) / duration.Seconds()
-		fmt.Printf("Running: %d/%d | %.2f req/s | Errors: %d\r",
-			i, *requests, rps, errs)
 	}
-	fmt.Println() // Clear the progress line
 
-	close(resultsChan)
+	ctx, stop := interruptContext()
+	defer stop()
 
-	duration := time.Since(start)
+	if (cfg.Checkpoint != "" || cfg.Resume != "") && (cfg.FindMax || cfg.Agents != "") {
+		return usageFail(errors.New("-checkpoint and -resume do not apply to -find-max or -agents"))
+	}
+	if cfg.Connections > 0 && cfg.Agents != "" {
+		return usageFail(errors.New("-connections does not apply to -agents"))
+	}
+	if cfg.SelfStats && cfg.Agents != "" {
+		return usageFail(errors.New("-selfstats measures this process, which sends no load with -agents"))
+	}
 
-	var success, failed int
-	var totalLatency time.Duration
-	var latencyList []time.Duration
+	var self *selfSampler
+	if cfg.SelfStats {
+		self = startSelfStats()
+	}
+	var out outcome
+	var otlp *otlpExporter
+	var search *MaxSearch
+	switch {
+	case cfg.FindMax:
+		out, search, err = findMax(ctx, cfg)
+		if err != nil {
+			return setupFail(err)
+		}
+	case cfg.Agents != "":
+		out, err = runDistributed(ctx, cfg, fs)
+		if err != nil {
+			return setupFail(err)
+		}
+	default:
+		t, err := newLoadTest(cfg)
+		if err != nil {
+			return setupFail(err)
+		}
+		defer t.Close()
+		if !cfg.NoPreflight {
+			if err := t.preflight(ctx); err != nil {
+				return setupFail(err)
+			}
+		}
 
-	for _, r := range results {
-		if r.Error != nil || r.Status < 200 || r.Status >= 300 {
-			failed++
-		} else {
-			success++
+		out = t.execute(ctx, cfg.newDisplay(t.steps()))
+		otlp = t.otlp
+	}
+	interrupted := stop()
+
+	summary := out.stats.Summary(out.elapsed)
+	summary.Partial = interrupted || out.partial || out.aborted != ""
+	summary.Aborted = out.aborted
+	summary.MaxSearch = search
+	if self != nil {
+		st := self.Stop()
+		summary.Self = &st
+	}
+	summary.AB = compareAB(cfg.URLs, summary)
+	out.stats.addPercentiles(&summary, percentiles)
+	out.stats.addApdex(&summary, cfg.ApdexT)
+	summary.Assertions = asserts.evaluate(summary)
+	if baseline != nil {
+		summary.Comparison = compareBaseline(baseline, summary)
+	}
+
+	if otlp != nil {
+		if err := otlp.Close(summary); err != nil {
+			fmt.Fprintln(os.Stderr, cli.Error("Error: OTLP export: "+err.Error()))
 		}
-		latencyList = append(latencyList, r.Latency)
-		totalLatency += r.Latency
 	}
 
-	rps := float64(*requests) / duration.Seconds()
+	if err := writeReport(cfg.Output, cfg.OutputFile, summary); err != nil {
+		return fail(err)
+	}
+	if cfg.Report != "" {
+		if err := writeHTMLReport(cfg.Report, summary); err != nil {
+			return fail(fmt.Errorf("writing HTML report: %w", err))
+		}
+	}
+	if cfg.Heatmap != "" {
+		if err := writeHeatmap(cfg.Heatmap, summary.Timeline); err != nil {
+			return fail(fmt.Errorf("writing heatmap: %w", err))
+		}
+	}
+	if cfg.JUnit != "" {
+		if err := writeJUnit(cfg.JUnit, cfg.target(), summary); err != nil {
+			return fail(fmt.Errorf("writing JUnit report: %w", err))
+		}
+	}
+	if cfg.SaveBaseline != "" {
+		if err := saveBaseline(cfg.SaveBaseline, summary); err != nil {
+			return fail(fmt.Errorf("saving baseline: %w", err))
+		}
+	}
+	if cfg.Tag != "" {
+		if err := appendHistory(cfg.HistoryFile, newHistoryEntry(cfg.Tag, cfg.target(), summary)); err != nil {
+			return fail(fmt.Errorf("recording history: %w", err))
+		}
+	}
 
-	// Summary Section
-	fmt.Println("\n" + cli.Bold + "=== SUMMARY ===" + cli.Reset)
-	summaryTable := cli.NewTable("Metric", "Value")
-	summaryTable.AddRow("Total Requests", fmt.Sprintf("%d", *requests))
-	summaryTable.AddRow("Successful", cli.Success(fmt.Sprintf("%d", success)))
-	summaryTable.AddRow("Failed", cli.Error(fmt.Sprintf("%d", failed)))
-	summaryTable.AddRow("Duration", duration.Round(time.Millisecond).String())
-	summaryTable.AddRow("Requests/sec", fmt.Sprintf("%.2f", rps))
-	summaryTable.Render()
+	if out.aborted != "" {
+		return exitAborted
+	}
+	if !allPassed(summary.Assertions) {
+		return exitAssertionFailed
+	}
+	return exitOK
+}
 
-	// Latency Section
-	if len(latencyList) > 0 {
-		slices.Sort(latencyList)
-		avgLatency := totalLatency / time.Duration(len(latencyList))
+// interruptContext returns a context that the first SIGINT/SIGTERM
+// cancels. The stop func restores default handling, so a second signal
+// kills the process immediately, and reports whether a signal arrived
+// before it was called; stopping cancels the context too, so ctx.Err()
+// afterwards would flag every run as interrupted.
+func interruptContext() (context.Context, func() bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	return ctx, func() bool {
+		interrupted := ctx.Err() != nil
+		stop()
+		return interrupted
+	}
+}
 
-		p50Idx := len(latencyList) * 50 / 100
-		p95Idx := len(latencyList) * 95 / 100
-		p99Idx := len(latencyList) * 99 / 100
+// buildTargets assembles the weighted set of scenarios to run, from a
+// HAR file, a scenario file or the -url flags.
+func buildTargets(cfg config, header http.Header) (*mix, error) {
+	urls, err := cfg.URLs.resolve()
+	if err != nil {
+		return nil, err
+	}
 
-		// Clamp to valid range
-		if p50Idx >= len(latencyList) {
-			p50Idx = len(latencyList) - 1
+	targets := &mix{}
+	if cfg.HAR != "" {
+		opts := harOptions{Speed: cfg.HARSpeed, AllHosts: cfg.HARAllHosts}
+		if len(urls) > 0 {
+			opts.BaseURL = urls[0].URL
 		}
-		if p95Idx >= len(latencyList) {
-			p95Idx = len(latencyList) - 1
+		sc, err := loadHAR(cfg.HAR, opts, header)
+		if err != nil {
+			return nil, err
 		}
-		if p99Idx >= len(latencyList) {
-			p99Idx = len(latencyList) - 1
+		targets.add(sc, 1)
+		return targets, nil
+	}
+	if cfg.Scenario != "" {
+		var base string
+		if len(urls) > 0 {
+			base = urls[0].URL
+		}
+		sc, err := loadScenario(cfg.Scenario, base, header)
+		if err != nil {
+			return nil, err
+		}
+		targets.add(sc, 1)
+		return targets, nil
+	}
+
+	payload, err := loadBody(cfg.Body, cfg.BodyFile)
+	if err != nil {
+		return nil, err
+	}
+	method := strings.ToUpper(cfg.Method)
+	if len(cfg.Form) > 0 || len(cfg.FileUploads) > 0 {
+		var contentType string
+		payload, contentType, err = buildForm(cfg.Form, cfg.FileUploads)
+		if err != nil {
+			return nil, err
+		}
+		header = header.Clone()
+		header.Set("Content-Type", contentType)
+		// Forms are submitted with POST unless -method chose another.
+		if !cfg.methodSet {
+			method = http.MethodPost
+		}
+	}
+	if cfg.GraphQLQuery != "" {
+		if payload, err = buildGraphQLBody(cfg.GraphQLQuery, cfg.GraphQLVars); err != nil {
+			return nil, err
 		}
+		header = header.Clone()
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "application/json")
+		}
+		if header.Get("Accept") == "" {
+			header.Set("Accept", "application/graphql-response+json, application/json")
+		}
+		method = http.MethodPost
+	}
+	// A -url-file or -sitemap list is sent in turn and reported as one
+	// endpoint, however many URLs it holds.
+	targets.rotate = cfg.listedFrom != ""
+	for _, u := range urls {
+		name := method + " " + u.URL
+		switch {
+		case u.Name != "":
+			name = u.Name
+		case cfg.GRPC.Method != "":
+			name = cfg.GRPC.Method
+		case cfg.listedFrom != "":
+			name = fmt.Sprintf("%s (%d URLs from %s)", method, len(urls), cfg.listedFrom)
+		}
+		targets.add(singleStep(&requestTemplate{
+			Name:   name,
+			Method: method,
+			URL:    u.URL,
+			Header: header,
+			Body:   payload,
+		}), u.Weight)
+	}
+	return targets, nil
+}
 
-		fmt.Println("\n" + cli.Bold + "=== LATENCY ===" + cli.Reset)
-		latencyTable := cli.NewTable("Percentile", "Duration")
-		latencyTable.AddRow("Min", latencyList[0].Round(time.Millisecond).String())
-		latencyTable.AddRow("Average", avgLatency.Round(time.Millisecond).String())
-		latencyTable.AddRow("P50 (Median)", latencyList[p50Idx].Round(time.Millisecond).String())
-		latencyTable.AddRow("P95", latencyList[p95Idx].Round(time.Millisecond).String())
-		latencyTable.AddRow("P99", latencyList[p99Idx].Round(time.Millisecond).String())
-		latencyTable.AddRow("Max", latencyList[len(latencyList)-1].Round(time.Millisecond).String())
-		latencyTable.Render()
-	} else {
-		fmt.Println("\n" + cli.Error("No successful requests"))
+// buildSchedule turns the pacing flags into a load schedule.
+// It returns nil when requests should be sent as fast as possible.
+func buildSchedule(rate float64, stages string, ramp time.Duration) (schedule, error) {
+	switch {
+	case stages != "" && ramp > 0:
+		return nil, fmt.Errorf("-stages and -ramp-up are mutually exclusive")
+	case stages != "":
+		return loadtest.ParseStages(stages)
+	case ramp > 0:
+		if rate <= 0 {
+			return nil, fmt.Errorf("-ramp-up requires -rate")
+		}
+		return loadtest.RampUp(ramp, rate), nil
+	case rate > 0:
+		return loadtest.ConstantRate(rate), nil
 	}
+	return nil, nil
+}
 
-	fmt.Println() // Final blank line for spacing
+// flagWasSet reports whether the named flag was given on fs.
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
 }
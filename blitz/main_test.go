@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInterruptContextCompletedRun(t *testing.T) {
+	ctx, stop := interruptContext()
+	if stop() {
+		t.Error("a run that finished on its own was reported as interrupted")
+	}
+	if ctx.Err() == nil {
+		t.Error("stop should cancel the context")
+	}
+}
+
+func TestInterruptContextSignal(t *testing.T) {
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGTERM did not cancel the context")
+	}
+	if !stop() {
+		t.Error("a run cut short by SIGTERM was not reported as interrupted")
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// renderMarkdown writes the summary as GitHub-flavored markdown, for
+// pasting into pull requests and incident docs.
+func renderMarkdown(w io.Writer, s Summary) {
+	title := "## Load test summary"
+	if s.Partial {
+		title += " (partial)"
+	}
+	fmt.Fprintln(w, title)
+	switch {
+	case s.Aborted != "":
+		fmt.Fprintf(w, "\n> **Run aborted:** %s\n", s.Aborted)
+	case s.Partial:
+		fmt.Fprintln(w, "\n> **Run interrupted:** results cover completed requests only")
+	}
+	if m := s.MaxSearch; m != nil {
+		if m.MaxRate > 0 {
+			fmt.Fprintf(w, "\n**Max sustainable rate:** %d req/s (results below are for that probe)\n", m.MaxRate)
+		} else {
+			fmt.Fprintln(w, "\n**No probed rate was sustainable** (results below are for the last probe)")
+		}
+	}
+
+	summary := [][]string{
+		{"Total Requests", fmt.Sprintf("%d", s.Total)},
+		{"Successful", fmt.Sprintf("%d", s.Successful)},
+		{"Failed", fmt.Sprintf("%d (%s)", s.Failed, percentOf(s.Failed, s.Total))},
+		{"Duration", s.Duration.Round(time.Millisecond).String()},
+		{"Requests/sec", fmt.Sprintf("%.2f", s.RPS)},
+	}
+	if s.Apdex != nil {
+		summary = append(summary, []string{"Apdex", apdexRow(*s.Apdex)})
+	}
+	if s.Timeouts > 0 {
+		summary = append(summary, []string{"Timeouts", fmt.Sprintf("%d", s.Timeouts)})
+	}
+	summary = append(summary, []string{"Data Received", formatBytes(s.Bytes.Total)})
+	if wire, ok := wireRow(s.Bytes); ok {
+		summary = append(summary, []string{"Data on Wire", wire})
+	}
+	summary = append(summary, []string{"Throughput", formatBytes(int64(s.Bytes.Throughput)) + "/s"})
+	if len(s.Protocols) > 0 {
+		summary = append(summary, []string{"Protocol", formatCounts(s.Protocols)})
+	}
+	markdownTable(w, []string{"Metric", "Value"}, summary)
+
+	if s.Total == 0 {
+		return
+	}
+
+	fmt.Fprintln(w, "\n### Latency")
+	headers := []string{"Percentile", "Duration"}
+	if s.Corrected != nil {
+		headers = append(headers, "Corrected")
+	}
+	var latency [][]string
+	addRow := func(name string, get func(LatencyStats) time.Duration) {
+		row := []string{name, get(s.Latency).Round(time.Millisecond).String()}
+		if s.Corrected != nil {
+			row = append(row, get(*s.Corrected).Round(time.Millisecond).String())
+		}
+		latency = append(latency, row)
+	}
+	addRow("Min", func(l LatencyStats) time.Duration { return l.Min })
+	addRow("Average", func(l LatencyStats) time.Duration { return l.Mean })
+	for _, p := range latencyPercentiles(s.Latency) {
+		addRow(p.Name, p.Get)
+	}
+	addRow("Max", func(l LatencyStats) time.Duration { return l.Max })
+	markdownTable(w, headers, latency)
+	if line, caption, ok := p95Sparkline(s.Timeline); ok {
+		fmt.Fprintf(w, "\n**P95 over time:** `%s` (%s)\n", line, caption)
+	}
+
+	if len(s.StatusCodes) > 0 || s.Errors > 0 {
+		fmt.Fprintln(w, "\n### Status codes")
+		var codes [][]string
+		for _, code := range slices.Sorted(maps.Keys(s.StatusCodes)) {
+			count := s.StatusCodes[code]
+			codes = append(codes, []string{fmt.Sprintf("%d %s", code, http.StatusText(code)),
+				fmt.Sprintf("%d", count), percentOf(count, s.Total)})
+		}
+		if s.Errors > 0 {
+			codes = append(codes, []string{"Errors", fmt.Sprintf("%d", s.Errors), percentOf(s.Errors, s.Total)})
+		}
+		markdownTable(w, []string{"Status", "Count", "Percent"}, codes)
+	}
+
+	if len(s.ErrorKinds) > 0 {
+		fmt.Fprintln(w, "\n### Errors")
+		var rows [][]string
+		for _, cat := range s.ErrorKinds {
+			rows = append(rows, []string{cat.Name, fmt.Sprintf("%d", cat.Count),
+				percentOf(cat.Count, s.Total), "`" + truncate(cat.Example, 80) + "`"})
+		}
+		markdownTable(w, []string{"Category", "Count", "Percent", "Example"}, rows)
+	}
+
+	if len(s.ByStatus) > 1 {
+		fmt.Fprintln(w, "\n### Latency by status")
+		var rows [][]string
+		for _, sl := range s.ByStatus {
+			rows = append(rows, statusLatencyRow(sl))
+		}
+		markdownTable(w, []string{"Status", "Count", "Mean", "P50", "P95", "P99", "Max"}, rows)
+	}
+
+	if s.AB != nil {
+		fmt.Fprintln(w, "\n### A/B comparison")
+		fmt.Fprintf(w, "\n- **A:** %s\n- **B:** %s\n", s.AB.URLA, s.AB.URLB)
+		markdownTable(w, abHeaders(), abRows(s.AB))
+	}
+
+	if len(s.Endpoints) > 1 {
+		fmt.Fprintln(w, "\n### Per endpoint")
+		var rows [][]string
+		for _, e := range s.Endpoints {
+			rows = append(rows, endpointRow(e))
+		}
+		markdownTable(w, []string{"Endpoint", "Requests", "Failed", "Fail %", "Mean", "P50", "P95", "P99", "Max"}, rows)
+	}
+
+	if rows := bodyHashRows(s.Endpoints); len(rows) > 0 {
+		fmt.Fprintln(w, "\n### Response bodies")
+		markdownTable(w, []string{"Endpoint", "Body SHA-256", "Status", "Size", "Count", "Percent"}, rows)
+	}
+
+	if s.Self != nil {
+		fmt.Fprintln(w, "\n### Load generator")
+		markdownTable(w, []string{"Metric", "Value"}, selfStatsRows(*s.Self))
+		if s.Self.Saturated() {
+			fmt.Fprintf(w, "\n> **Warning:** %s\n", selfStatsWarning)
+		}
+	}
+
+	if len(s.Comparison) > 0 {
+		fmt.Fprintln(w, "\n### Baseline comparison")
+		var rows [][]string
+		for _, d := range s.Comparison {
+			var change string
+			switch pct := d.Change(); {
+			case d.Current == d.Baseline:
+				change = "="
+			case math.IsNaN(pct):
+				change = "new"
+			case d.Improved():
+				change = fmt.Sprintf("%+.1f%%", pct)
+			default:
+				change = fmt.Sprintf("**%+.1f%%**", pct) // regressions stand out in bold
+			}
+			rows = append(rows, []string{d.Name, formatDeltaValue(d.Baseline, d.Unit), formatDeltaValue(d.Current, d.Unit), change})
+		}
+		markdownTable(w, []string{"Metric", "Baseline", "Current", "Change"}, rows)
+	}
+
+	if len(s.Assertions) > 0 {
+		fmt.Fprintln(w, "\n### Assertions")
+		var rows [][]string
+		for _, a := range s.Assertions {
+			result := "PASS"
+			if !a.Passed {
+				result = "**FAIL**"
+			}
+			rows = append(rows, []string{a.Name, result, a.Detail})
+		}
+		markdownTable(w, []string{"Assertion", "Result", "Detail"}, rows)
+	}
+}
+
+// markdownTable writes a GitHub-flavored markdown table preceded by a
+// blank line.
+func markdownTable(w io.Writer, headers []string, rows [][]string) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, markdownRow(headers))
+	sep := make([]string, len(headers))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	fmt.Fprintln(w, markdownRow(sep))
+	for _, row := range rows {
+		fmt.Fprintln(w, markdownRow(row))
+	}
+}
+
+// markdownCell escapes characters that would end a cell or the row.
+var markdownCell = strings.NewReplacer("|", `\|`, "\n", " ", "\r", "")
+
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = markdownCell.Replace(c)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |"
+}
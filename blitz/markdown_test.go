@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	c := newCollector()
+	c.Add(Result{Step: "a", Status: 200, Latency: 10 * time.Millisecond})
+	c.Add(Result{Step: "b|c", Status: 503, Latency: 20 * time.Millisecond})
+	s := c.Summary(time.Second)
+	s.Assertions = []AssertionResult{{Name: "threshold p99<5ms", Passed: false, Detail: "p99 was 20ms"}}
+
+	var buf bytes.Buffer
+	renderMarkdown(&buf, s)
+	out := buf.String()
+	for _, want := range []string{
+		"## Load test summary\n",
+		"| Metric | Value |\n| --- | --- |\n",
+		"| Failed | 1 (50.00%) |",
+		"| 503 Service Unavailable | 1 | 50.00% |",
+		`| b\|c | 1 | 1 | 100.00% |`,
+		"| threshold p99<5ms | **FAIL** | p99 was 20ms |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\033[") {
+		t.Error("markdown must not contain ANSI escapes")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the exported
+// request duration histogram.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// metrics holds live counters for the -metrics-addr endpoint. Results
+// are added by the collect loop while scrapes read concurrently.
+type metrics struct {
+	mu       sync.Mutex
+	statuses map[string]int // by status code, or "error" for transport failures
+	failures int
+	retries  int
+	bytes    int64
+	buckets  []int // cumulative counts per latencyBuckets entry
+	count    int
+	sum      time.Duration
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		statuses: make(map[string]int),
+		buckets:  make([]int, len(latencyBuckets)),
+	}
+}
+
+// Add folds one result into the counters.
+func (m *metrics) Add(r Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := "error"
+	if r.Error == nil {
+		status = strconv.Itoa(r.Status)
+	}
+	m.statuses[status]++
+	if failed(r) {
+		m.failures++
+	}
+	m.retries += r.Retries
+	m.bytes += r.Bytes
+
+	secs := r.Latency.Seconds()
+	for i, le := range latencyBuckets {
+		if secs <= le {
+			m.buckets[i]++
+		}
+	}
+	m.count++
+	m.sum += r.Latency
+}
+
+// ServeHTTP writes the counters in the Prometheus text exposition format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.writeTo(w)
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP blitz_requests_total Requests completed, by response status.")
+	fmt.Fprintln(w, "# TYPE blitz_requests_total counter")
+	for _, status := range slices.Sorted(maps.Keys(m.statuses)) {
+		fmt.Fprintf(w, "blitz_requests_total{status=%q} %d\n", status, m.statuses[status])
+	}
+
+	fmt.Fprintln(w, "# HELP blitz_request_failures_total Requests that counted as failed.")
+	fmt.Fprintln(w, "# TYPE blitz_request_failures_total counter")
+	fmt.Fprintf(w, "blitz_request_failures_total %d\n", m.failures)
+
+	fmt.Fprintln(w, "# HELP blitz_request_retries_total Resend attempts after transient failures.")
+	fmt.Fprintln(w, "# TYPE blitz_request_retries_total counter")
+	fmt.Fprintf(w, "blitz_request_retries_total %d\n", m.retries)
+
+	fmt.Fprintln(w, "# HELP blitz_received_bytes_total Response body bytes received.")
+	fmt.Fprintln(w, "# TYPE blitz_received_bytes_total counter")
+	fmt.Fprintf(w, "blitz_received_bytes_total %d\n", m.bytes)
+
+	fmt.Fprintln(w, "# HELP blitz_request_duration_seconds Request latency.")
+	fmt.Fprintln(w, "# TYPE blitz_request_duration_seconds histogram")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(w, "blitz_request_duration_seconds_bucket{le=\"%s\"} %d\n",
+			strconv.FormatFloat(le, 'g', -1, 64), m.buckets[i])
+	}
+	fmt.Fprintf(w, "blitz_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.count)
+	fmt.Fprintf(w, "blitz_request_duration_seconds_sum %g\n", m.sum.Seconds())
+	fmt.Fprintf(w, "blitz_request_duration_seconds_count %d\n", m.count)
+}
+
+// serveMetrics starts serving m on addr at /metrics. The listener is
+// opened before returning so a bad address fails the run up front.
+func serveMetrics(addr string, m *metrics) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics endpoint: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go srv.Serve(ln)
+	return srv, nil
+}
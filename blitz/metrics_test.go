@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsExposition(t *testing.T) {
+	m := newMetrics()
+	m.Add(Result{Status: 200, Latency: 20 * time.Millisecond, Bytes: 100})
+	m.Add(Result{Status: 500, Latency: 300 * time.Millisecond, Bytes: 50})
+	m.Add(Result{Error: errors.New("refused"), Latency: time.Millisecond, Retries: 2})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`blitz_requests_total{status="200"} 1`,
+		`blitz_requests_total{status="500"} 1`,
+		`blitz_requests_total{status="error"} 1`,
+		"blitz_request_failures_total 2",
+		"blitz_request_retries_total 2",
+		"blitz_received_bytes_total 150",
+		`blitz_request_duration_seconds_bucket{le="0.005"} 1`,
+		`blitz_request_duration_seconds_bucket{le="0.025"} 2`,
+		`blitz_request_duration_seconds_bucket{le="0.5"} 3`,
+		`blitz_request_duration_seconds_bucket{le="+Inf"} 3`,
+		"blitz_request_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("exposition missing %q\n%s", want, body)
+		}
+	}
+}
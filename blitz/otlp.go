@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLP export uses the OTLP/HTTP JSON encoding, which needs no
+// generated protobuf code. Request spans are batched and flushed in the
+// background; the run's metrics are pushed once when it ends.
+
+const (
+	otlpBatchSize     = 512
+	otlpFlushInterval = 5 * time.Second
+
+	spanKindClient  = 3
+	spanStatusError = 2
+	temporalityCum  = 2 // AGGREGATION_TEMPORALITY_CUMULATIVE
+)
+
+// spanContext identifies the client span of one request. It is sent to
+// the target in a W3C traceparent header so server-side traces can be
+// joined to the load test's.
+type spanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+func newSpanContext() spanContext {
+	var sc spanContext
+	rand.Read(sc.TraceID[:])
+	rand.Read(sc.SpanID[:])
+	return sc
+}
+
+// traceparent formats the W3C trace context header value, with the
+// sampled flag set.
+func (sc spanContext) traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-01"
+}
+
+// otlpExporter pushes spans and run metrics to an OTLP/HTTP collector.
+type otlpExporter struct {
+	endpoint string // collector base URL; /v1/traces and /v1/metrics are appended
+	client   *http.Client
+	start    time.Time
+
+	mu    sync.Mutex
+	spans []otlpSpan
+	errs  []error
+
+	kick chan struct{}
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	e := &otlpExporter{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		start:    time.Now(),
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// loop flushes buffered spans periodically, or early when a full batch
+// is waiting.
+func (e *otlpExporter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(otlpFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.kick:
+		case <-e.done:
+			return
+		}
+		e.flushSpans()
+	}
+}
+
+// AddSpan buffers the client span of r, if it has one.
+func (e *otlpExporter) AddSpan(r Result) {
+	if r.Span == nil {
+		return
+	}
+	span := otlpSpan{
+		TraceID: hex.EncodeToString(r.Span.TraceID[:]),
+		SpanID:  hex.EncodeToString(r.Span.SpanID[:]),
+		Name:    r.Step,
+		Kind:    spanKindClient,
+		Start:   unixNano(r.Timestamp.Add(-r.Latency)),
+		End:     unixNano(r.Timestamp),
+	}
+	if r.Error == nil {
+		span.Attributes = append(span.Attributes, intAttr("http.response.status_code", int64(r.Status)))
+	}
+	if r.Retries > 0 {
+		span.Attributes = append(span.Attributes, intAttr("blitz.retries", int64(r.Retries)))
+	}
+	if failed(r) {
+		cat := classify(r)
+		span.Attributes = append(span.Attributes, strAttr("error.type", cat))
+		span.Status = otlpStatus{Code: spanStatusError, Message: cat}
+	}
+
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	full := len(e.spans) >= otlpBatchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) flushSpans() {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+	if len(spans) == 0 {
+		return
+	}
+	e.post("/v1/traces", otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: resourceAttrs()},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "blitz"}, Spans: spans}},
+	}}})
+}
+
+// Close flushes any buffered spans, pushes the run metrics from s and
+// returns the export errors seen over the run.
+func (e *otlpExporter) Close(s Summary) error {
+	close(e.done)
+	e.wg.Wait()
+	e.flushSpans()
+	e.post("/v1/metrics", otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: resourceAttrs()},
+		ScopeMetrics: []otlpScopeMetrics{{Scope: otlpScope{Name: "blitz"}, Metrics: runMetrics(s, e.start, time.Now())}},
+	}}})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return errors.Join(e.errs...)
+}
+
+func (e *otlpExporter) post(path string, payload any) {
+	err := func() error {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		resp, err := e.client.Post(e.endpoint+path, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("%s: collector returned %s", path, resp.Status)
+		}
+		return nil
+	}()
+	if err != nil {
+		e.mu.Lock()
+		e.errs = append(e.errs, err)
+		e.mu.Unlock()
+	}
+}
+
+// runMetrics converts a summary into OTLP metrics. Counters are
+// cumulative over the run; latency is exported as a summary with the
+// reported percentiles.
+func runMetrics(s Summary, start, end time.Time) []otlpMetric {
+	from, now := unixNano(start), unixNano(end)
+	counter := func(n int, attrs ...otlpAttr) otlpPoint {
+		return otlpPoint{Attributes: attrs, Start: from, Time: now, AsInt: strconv.Itoa(n)}
+	}
+	sum := func(name, unit string, points ...otlpPoint) otlpMetric {
+		return otlpMetric{Name: name, Unit: unit, Sum: &otlpSum{
+			DataPoints: points, AggregationTemporality: temporalityCum, IsMonotonic: true,
+		}}
+	}
+	gauge := func(name, unit string, v float64) otlpMetric {
+		return otlpMetric{Name: name, Unit: unit, Gauge: &otlpGauge{
+			DataPoints: []otlpPoint{{Time: now, AsDouble: &v}},
+		}}
+	}
+
+	var codes []otlpPoint
+	for _, code := range slices.Sorted(maps.Keys(s.StatusCodes)) {
+		codes = append(codes, counter(s.StatusCodes[code], intAttr("http.response.status_code", int64(code))))
+	}
+	l := s.Latency
+	metrics := []otlpMetric{
+		sum("blitz.requests", "{request}", counter(s.Total)),
+		sum("blitz.requests.failed", "{request}", counter(s.Failed)),
+		sum("blitz.errors", "{request}", counter(s.Errors)),
+		sum("blitz.received", "By", counter(int(s.Bytes.Total))),
+		gauge("blitz.rps", "{request}/s", s.RPS),
+		{Name: "blitz.latency", Unit: "ms", Summary: &otlpSummary{DataPoints: []otlpSummaryPoint{{
+			Start: from, Time: now,
+			Count: strconv.Itoa(s.Total),
+			Sum:   ms(l.Mean) * float64(s.Total),
+			Quantiles: []otlpQuantile{
+				{Quantile: 0, Value: ms(l.Min)},
+				{Quantile: 0.5, Value: ms(l.P50)},
+				{Quantile: 0.95, Value: ms(l.P95)},
+				{Quantile: 0.99, Value: ms(l.P99)},
+				{Quantile: 1, Value: ms(l.Max)},
+			},
+		}}}},
+	}
+	if len(codes) > 0 {
+		metrics = append(metrics, sum("blitz.responses", "{response}", codes...))
+	}
+	return metrics
+}
+
+func resourceAttrs() []otlpAttr {
+	return []otlpAttr{strAttr("service.name", "blitz")}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func strAttr(key, v string) otlpAttr {
+	return otlpAttr{Key: key, Value: otlpValue{StringValue: v}}
+}
+
+func intAttr(key string, v int64) otlpAttr {
+	return otlpAttr{Key: key, Value: otlpValue{IntValue: strconv.FormatInt(v, 10)}}
+}
+
+// OTLP JSON payloads. 64-bit integers are encoded as strings and trace
+// and span IDs as hex, per the OTLP/JSON mapping.
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID    string     `json:"traceId"`
+	SpanID     string     `json:"spanId"`
+	Name       string     `json:"name"`
+	Kind       int        `json:"kind"`
+	Start      string     `json:"startTimeUnixNano"`
+	End        string     `json:"endTimeUnixNano"`
+	Attributes []otlpAttr `json:"attributes,omitempty"`
+	Status     otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name    string       `json:"name"`
+	Unit    string       `json:"unit,omitempty"`
+	Gauge   *otlpGauge   `json:"gauge,omitempty"`
+	Sum     *otlpSum     `json:"sum,omitempty"`
+	Summary *otlpSummary `json:"summary,omitempty"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality"`
+	IsMonotonic            bool        `json:"isMonotonic"`
+}
+
+type otlpPoint struct {
+	Attributes []otlpAttr `json:"attributes,omitempty"`
+	Start      string     `json:"startTimeUnixNano,omitempty"`
+	Time       string     `json:"timeUnixNano"`
+	AsInt      string     `json:"asInt,omitempty"`
+	AsDouble   *float64   `json:"asDouble,omitempty"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryPoint `json:"dataPoints"`
+}
+
+type otlpSummaryPoint struct {
+	Start     string         `json:"startTimeUnixNano"`
+	Time      string         `json:"timeUnixNano"`
+	Count     string         `json:"count"`
+	Sum       float64        `json:"sum"`
+	Quantiles []otlpQuantile `json:"quantileValues"`
+}
+
+type otlpQuantile struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTraceparent(t *testing.T) {
+	sc := newSpanContext()
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(sc.traceparent()) {
+		t.Errorf("traceparent() = %q", sc.traceparent())
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	bodies := map[string][]byte{}
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = b
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	e := newOTLPExporter(collector.URL + "/")
+	sc := newSpanContext()
+	now := time.Now()
+	e.AddSpan(Result{Step: "GET /", Status: 200, Latency: time.Millisecond, Timestamp: now, Span: &sc})
+	e.AddSpan(Result{Step: "GET /", Error: errors.New("boom"), Timestamp: now, Span: &sc})
+	e.AddSpan(Result{Step: "GET /", Status: 200, Timestamp: now}) // no span, skipped
+
+	s := Summary{Total: 2, Failed: 1, Errors: 1, StatusCodes: map[int]int{200: 1}}
+	if err := e.Close(s); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	var traces otlpTraces
+	if err := json.Unmarshal(bodies["/v1/traces"], &traces); err != nil {
+		t.Fatalf("decoding traces: %v", err)
+	}
+	spans := traces.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(spans))
+	}
+	if spans[1].Status.Code != spanStatusError || spans[0].Status.Code != 0 {
+		t.Errorf("span statuses = %d, %d; want 0, %d", spans[0].Status.Code, spans[1].Status.Code, spanStatusError)
+	}
+
+	var metrics otlpMetrics
+	if err := json.Unmarshal(bodies["/v1/metrics"], &metrics); err != nil {
+		t.Fatalf("decoding metrics: %v", err)
+	}
+	got := map[string]bool{}
+	for _, m := range metrics.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		got[m.Name] = true
+	}
+	for _, name := range []string{"blitz.requests", "blitz.requests.failed", "blitz.latency", "blitz.responses"} {
+		if !got[name] {
+			t.Errorf("metric %s not exported", name)
+		}
+	}
+}
+
+func TestOTLPExporterCollectorError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	e := newOTLPExporter(collector.URL)
+	if err := e.Close(Summary{}); err == nil {
+		t.Error("expected an error when the collector rejects the export")
+	}
+}
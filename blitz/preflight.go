@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+	"github.com/quic-go/quic-go"
+	"google.golang.org/grpc/status"
+)
+
+// preflightError is a preflight request that failed before getting a
+// response, with a diagnosis in place of the bare transport error.
+type preflightError struct {
+	Method string
+	URL    string
+	Err    error
+	Cause  string // what went wrong, in plain words
+	Hint   string // what to try next; "" when there is nothing specific
+}
+
+func (e *preflightError) Error() string {
+	return fmt.Sprintf("preflight %s %s: %s: %v", e.Method, e.URL, e.Cause, e.Err)
+}
+
+// render writes the diagnosis for the terminal, in color when enabled.
+func (e *preflightError) render(w io.Writer) {
+	fmt.Fprintln(w, cli.Error(cli.Colorize(cli.Bold, "Preflight check failed: ")+e.Method+" "+e.URL))
+	fmt.Fprintf(w, "  %s %s\n", cli.Colorize(cli.Bold, "Cause:"), e.Cause)
+	fmt.Fprintf(w, "  %s %s\n", cli.Colorize(cli.Bold, "Error:"), cli.Colorize(cli.Dim, e.Err.Error()))
+	if e.Hint != "" {
+		fmt.Fprintf(w, "  %s  %s\n", cli.Colorize(cli.Bold, "Hint:"), cli.Info(e.Hint))
+	}
+	fmt.Fprintln(w, cli.Warning("The run was not started; -no-preflight skips this check."))
+}
+
+// preflight sends one request to each host the run targets, so a
+// target that cannot be reached is diagnosed once rather than failing
+// every request of the run. Only failures without a response count: a
+// target answering 500 is still worth load testing. WebSocket targets
+// are skipped, since their sessions connect as the run starts.
+func (t *loadTest) preflight(ctx context.Context) error {
+	if t.req.ws {
+		return nil
+	}
+	var vars map[string]string
+	if t.req.data != nil {
+		vars = t.req.data.rows[0]
+	}
+	seen := make(map[string]bool)
+	for _, sc := range t.req.targets.scenarios {
+		tmpl := sc.Steps[0].Tmpl
+		u, err := url.Parse(tmpl.URL)
+		if err == nil && seen[u.Host] {
+			continue
+		}
+		if err == nil {
+			seen[u.Host] = true
+		}
+		res := t.req.send(ctx, tmpl, vars)
+		if res.Error == nil || ctx.Err() != nil {
+			continue
+		}
+		method := tmpl.Method
+		if t.req.grpc != nil || t.req.tcp != nil {
+			method = "connect"
+		}
+		e := &preflightError{Method: method, URL: tmpl.URL, Err: res.Error}
+		e.Cause, e.Hint = diagnose(res.Error, u)
+		return e
+	}
+	return nil
+}
+
+// diagnose explains a transport error from a request to u and suggests
+// what to check, going into more detail than the summary's categories.
+func diagnose(err error, u *url.URL) (cause, hint string) {
+	host := "the target"
+	if u != nil && u.Host != "" {
+		host = u.Host
+	}
+	if st, ok := status.FromError(err); ok {
+		return fmt.Sprintf("the gRPC call to %s failed with %s", host, st.Code()), ""
+	}
+
+	switch classifyError(err) {
+	case catDNS:
+		var dnsErr *net.DNSError
+		errors.As(err, &dnsErr)
+		if dnsErr != nil && dnsErr.IsNotFound {
+			return fmt.Sprintf("no DNS record for %s", dnsErr.Name),
+				"check the host name for typos, or pin it to an address with -resolve host:port:addr"
+		}
+		return fmt.Sprintf("DNS lookup for %s failed", host),
+			"check the network's resolver, or try another with -dns-server"
+	case catRefused:
+		return fmt.Sprintf("%s refused the connection", host),
+			"nothing is listening there: check that the service is running and the port is right"
+	case catTimeout:
+		var (
+			handshake *quic.HandshakeTimeoutError
+			idle      *quic.IdleTimeoutError
+		)
+		if errors.As(err, &handshake) || errors.As(err, &idle) {
+			return fmt.Sprintf("%s did not complete a QUIC handshake", host),
+				"it may not serve HTTP/3, or a firewall may drop UDP; drop -http3 to compare over TCP"
+		}
+		return fmt.Sprintf("%s did not respond in time", host),
+			"the host may be down or firewalled, or slower than -timeout allows"
+	case catReset, catClosed:
+		return fmt.Sprintf("%s closed the connection without a response", host),
+			"check the scheme and port; a TLS port reached over http:// often does this"
+	case catTLS:
+		return diagnoseTLS(err, host)
+	}
+	return fmt.Sprintf("the request to %s failed", host), ""
+}
+
+// diagnoseTLS explains a failed TLS handshake with host.
+func diagnoseTLS(err error, host string) (cause, hint string) {
+	var (
+		invalid  x509.CertificateInvalidError
+		unknown  x509.UnknownAuthorityError
+		hostname x509.HostnameError
+		record   tls.RecordHeaderError
+		alert    tls.AlertError
+	)
+	switch {
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired && invalid.Cert != nil:
+		cert := invalid.Cert
+		if time.Now().Before(cert.NotBefore) {
+			return fmt.Sprintf("the certificate for %s is not valid until %s", host, cert.NotBefore.Format(time.DateOnly)),
+				"check this machine's clock, or pass -insecure to skip verification"
+		}
+		return fmt.Sprintf("the certificate for %s expired on %s", host, cert.NotAfter.Format(time.DateOnly)),
+			"renew the certificate, or pass -insecure to skip verification while testing"
+	case errors.As(err, &unknown):
+		return fmt.Sprintf("the certificate for %s is signed by an unknown authority", host),
+			"trust its CA with -cacert, or pass -insecure to skip verification"
+	case errors.As(err, &hostname):
+		names := "no DNS names"
+		if hostname.Certificate != nil && len(hostname.Certificate.DNSNames) > 0 {
+			names = strings.Join(hostname.Certificate.DNSNames, ", ")
+		}
+		return fmt.Sprintf("the certificate is not valid for %s (it covers %s)", hostname.Host, names),
+			"use a host name the certificate covers, or pass -insecure to skip verification"
+	case errors.As(err, &record):
+		return fmt.Sprintf("%s did not answer with TLS", host),
+			"it may serve plain HTTP; try an http:// URL"
+	case errors.As(err, &alert):
+		return fmt.Sprintf("%s rejected the TLS handshake", host),
+			"it may require a client certificate (-cert and -key) or another TLS version (-tls-min-version)"
+	}
+	return fmt.Sprintf("the TLS handshake with %s failed", host), ""
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// preflightTest is a loadTest with just enough set up to preflight url.
+func preflightTest(client *http.Client, url string) *loadTest {
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{Name: "get", Method: http.MethodGet, URL: url}), 1)
+	return &loadTest{req: &requester{client: client, targets: targets}}
+}
+
+func TestPreflight(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	if err := preflightTest(srv.Client(), srv.URL).preflight(context.Background()); err != nil {
+		t.Errorf("preflight() error = %v; a target that answers should pass, whatever the status", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + ln.Addr().String()
+	ln.Close()
+	err = preflightTest(http.DefaultClient, closed).preflight(context.Background())
+	var pre *preflightError
+	if !errors.As(err, &pre) || !strings.Contains(pre.Cause, "refused") {
+		t.Errorf("preflight() to a closed port = %v, want a refused diagnosis", err)
+	}
+}
+
+func TestPreflightUntrustedCert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	err := preflightTest(&http.Client{}, srv.URL).preflight(context.Background())
+	var pre *preflightError
+	if !errors.As(err, &pre) || !strings.Contains(pre.Cause, "unknown authority") || !strings.Contains(pre.Hint, "-cacert") {
+		t.Fatalf("preflight() = %v, want an unknown authority diagnosis", err)
+	}
+
+	var out bytes.Buffer
+	pre.render(&out)
+	for _, want := range []string{"Preflight check failed: GET " + srv.URL, "Cause:", "Hint:", "-no-preflight"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("rendered diagnosis lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	u, _ := url.Parse("https://api.test/")
+	expired := &tls.CertificateVerificationError{Err: x509.CertificateInvalidError{
+		Cert:   &x509.Certificate{NotBefore: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), NotAfter: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		Reason: x509.Expired,
+	}}
+	wrongHost := &tls.CertificateVerificationError{Err: x509.HostnameError{
+		Certificate: &x509.Certificate{DNSNames: []string{"other.test"}},
+		Host:        "api.test",
+	}}
+	for _, tt := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"no such host", &net.DNSError{Name: "api.test", IsNotFound: true}, "no DNS record for api.test"},
+		{"dns", &net.DNSError{Name: "api.test", Err: "server misbehaving"}, "DNS lookup for api.test failed"},
+		{"expired", expired, "expired on 2020-01-02"},
+		{"hostname", wrongHost, "not valid for api.test (it covers other.test)"},
+		{"timeout", context.DeadlineExceeded, "did not respond in time"},
+		{"quic", &quic.IdleTimeoutError{}, "did not complete a QUIC handshake"},
+		{"other", errors.New("boom"), "request to api.test failed"},
+	} {
+		cause, _ := diagnose(tt.err, u)
+		if !strings.Contains(cause, tt.want) {
+			t.Errorf("%s: cause = %q, want it to contain %q", tt.name, cause, tt.want)
+		}
+	}
+}
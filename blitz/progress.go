@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// display shows run progress while results are collected.
+// Add is called for every result; Render is called every Interval.
+// Println prints a line that stays above the redrawn progress.
+type display interface {
+	Add(res Result)
+	Render()
+	Println(line string)
+	Finish()
+	Interval() time.Duration
+}
+
+// newDisplay returns the progress display cfg asks for. Progress goes
+// to stderr so stdout stays clean for structured output.
+func (c config) newDisplay(total int) display {
+	switch {
+	case c.Quiet:
+		return silentDisplay{w: os.Stderr}
+	case c.Live:
+		return newDashboard(os.Stderr, total)
+	}
+	return newProgressLine(os.Stderr, total)
+}
+
+// progressLine is the default single-line display redrawn with \r.
+type progressLine struct {
+	w     io.Writer
+	start time.Time
+	total int // 0 when the run is time-based
+	count int
+	errs  int
+
+	recent *liveWindow
+}
+
+// progressWindow is how far back the progress line's rate, failure
+// rate and p95 look.
+const progressWindow = 5 * time.Second
+
+func newProgressLine(w io.Writer, total int) *progressLine {
+	start := time.Now()
+	return &progressLine{w: w, start: start, total: total, recent: newLiveWindow(start)}
+}
+
+func (p *progressLine) Add(res Result) {
+	p.count++
+	if res.Error != nil {
+		p.errs++
+	}
+	p.recent.Record(res.Timestamp, failed(res), res.Latency)
+}
+
+func (p *progressLine) Render() {
+	now := time.Now()
+	elapsed := now.Sub(p.start)
+	st := p.recent.Stats(now, progressWindow)
+	recent := fmt.Sprintf("%.2f req/s, p95 %s, %.1f%% failed (last 5s)",
+		st.RPS, windowLatency(st.P95, st.Requests), st.Failed)
+	// The window can shrink the line, so clear what the last one left.
+	if p.total > 0 {
+		fmt.Fprintf(p.w, cli.ClearLine+"Running: %d/%d | %s | Errors: %d\r",
+			p.count, p.total, recent, p.errs)
+	} else {
+		fmt.Fprintf(p.w, cli.ClearLine+"Running: %d | %s | %s | Errors: %d\r",
+			p.count, elapsed.Round(time.Second), recent, p.errs)
+	}
+}
+
+func (p *progressLine) Println(line string) {
+	fmt.Fprintln(p.w, "\r"+cli.ClearLine+line)
+}
+
+func (p *progressLine) Finish() {
+	p.Render()
+	fmt.Fprintln(p.w) // Clear the progress line
+}
+
+func (p *progressLine) Interval() time.Duration {
+	return 100 * time.Millisecond
+}
+
+// windowLatency formats a latency percentile from a window of n
+// results, showing "-" when the window is empty.
+func windowLatency(d time.Duration, n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// dashboard is the -live view: totals so far, and throughput, failures
+// and latency percentiles over trailing windows, redrawn in place once
+// per second.
+type dashboard struct {
+	w     io.Writer
+	start time.Time
+	total int
+	count int
+	errs  int
+	lines int // lines drawn last frame, erased before redrawing
+
+	recent *liveWindow
+}
+
+// dashboardWindows are the trailing windows the dashboard shows.
+var dashboardWindows = []time.Duration{time.Second, 5 * time.Second, 10 * time.Second}
+
+func newDashboard(w io.Writer, total int) *dashboard {
+	start := time.Now()
+	return &dashboard{w: w, start: start, total: total, recent: newLiveWindow(start)}
+}
+
+func (d *dashboard) Add(res Result) {
+	d.count++
+	if res.Error != nil {
+		d.errs++
+	}
+	d.recent.Record(res.Timestamp, failed(res), res.Latency)
+}
+
+func (d *dashboard) Render() {
+	now := time.Now()
+	elapsed := now.Sub(d.start)
+
+	progress := fmt.Sprintf("%d", d.count)
+	if d.total > 0 {
+		progress = fmt.Sprintf("%d/%d (%.0f%%)", d.count, d.total,
+			float64(d.count)/float64(d.total)*100)
+	}
+
+	var errRate float64
+	if d.count > 0 {
+		errRate = float64(d.errs) / float64(d.count) * 100
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, cli.Bold+"=== LIVE ==="+cli.Reset)
+	table := cli.NewTable("Metric", "Value")
+	table.Writer = &buf
+	table.AddRow("Elapsed", elapsed.Round(time.Second).String())
+	table.AddRow("Requests", progress)
+	table.AddRow("Requests/sec", fmt.Sprintf("%.2f", float64(d.count)/elapsed.Seconds()))
+	table.AddRow("Errors", fmt.Sprintf("%d (%.2f%%)", d.errs, errRate))
+	table.Render()
+
+	fmt.Fprintln(&buf)
+	recent := cli.NewTable("Window", "Req/s", "Failed", "P50", "P95", "P99")
+	recent.Writer = &buf
+	for _, span := range dashboardWindows {
+		st := d.recent.Stats(now, span)
+		recent.AddRow(fmt.Sprintf("last %s", span), fmt.Sprintf("%.2f", st.RPS),
+			fmt.Sprintf("%.2f%%", st.Failed), windowLatency(st.P50, st.Requests),
+			windowLatency(st.P95, st.Requests), windowLatency(st.P99, st.Requests))
+	}
+	recent.Render()
+
+	fmt.Fprint(d.w, cli.CursorUp(d.lines)+cli.ClearBelow+buf.String())
+	d.lines = strings.Count(buf.String(), "\n")
+}
+
+func (d *dashboard) Println(line string) {
+	// Replace the last frame; the next Render draws below the line.
+	fmt.Fprintln(d.w, cli.CursorUp(d.lines)+cli.ClearBelow+line)
+	d.lines = 0
+}
+
+func (d *dashboard) Finish() {
+	d.Render()
+}
+
+func (d *dashboard) Interval() time.Duration {
+	return time.Second
+}
+
+// silentDisplay shows no progress, for -quiet runs and agents running
+// on behalf of a coordinator. Println lines (the -report-interval
+// snapshots) still go to w; with a nil w they are dropped.
+type silentDisplay struct {
+	w io.Writer
+}
+
+func (silentDisplay) Add(Result) {}
+
+func (silentDisplay) Render() {}
+
+func (d silentDisplay) Println(line string) {
+	if d.w != nil {
+		fmt.Fprintln(d.w, line)
+	}
+}
+
+func (silentDisplay) Finish() {}
+
+func (silentDisplay) Interval() time.Duration {
+	return time.Second
+}
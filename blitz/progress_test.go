@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewDisplay(t *testing.T) {
+	if _, ok := (config{Quiet: true}).newDisplay(10).(silentDisplay); !ok {
+		t.Error("-quiet should show no progress")
+	}
+	if _, ok := (config{Live: true}).newDisplay(10).(*dashboard); !ok {
+		t.Error("-live should show the dashboard")
+	}
+	if _, ok := (config{}).newDisplay(10).(*progressLine); !ok {
+		t.Error("default should be the progress line")
+	}
+}
+
+func TestSilentDisplayPrintln(t *testing.T) {
+	var buf bytes.Buffer
+	d := silentDisplay{w: &buf}
+	d.Add(Result{Status: 200})
+	d.Render()
+	d.Println("[1m0s] 100 requests")
+	d.Finish()
+	if buf.String() != "[1m0s] 100 requests\n" {
+		t.Errorf("quiet display wrote %q, want only the printed line", buf.String())
+	}
+}
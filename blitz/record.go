@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// recorder streams individual results to a file as they arrive,
+// so per-request data is available for offline analysis without
+// holding every result in memory.
+type recorder interface {
+	Record(r Result) error
+	Close() error
+}
+
+// recordLine is the serialized form of a single Result.
+type recordLine struct {
+	Timestamp string  `json:"timestamp"`
+	Step      string  `json:"step"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	QueuedMs  float64 `json:"queued_ms,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	RequestID string  `json:"request_id,omitempty"`
+}
+
+func newRecordLine(r Result) recordLine {
+	line := recordLine{
+		Timestamp: r.Timestamp.Format(time.RFC3339Nano),
+		Step:      r.Step,
+		Status:    r.Status,
+		LatencyMs: ms(r.Latency),
+		QueuedMs:  ms(r.Queued),
+		RequestID: r.RequestID,
+	}
+	if r.Error != nil {
+		line.Error = r.Error.Error()
+	}
+	return line
+}
+
+// newRecorder creates a recorder writing to path. Files ending in .csv
+// are written as CSV; anything else is written as newline-delimited JSON.
+func newRecorder(path string) (recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(f)
+
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		w := csv.NewWriter(buf)
+		if err := w.Write([]string{"timestamp", "step", "status", "latency_ms", "queued_ms", "error", "request_id"}); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &csvRecorder{file: f, buf: buf, w: w}, nil
+	}
+
+	return &ndjsonRecorder{file: f, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+type ndjsonRecorder struct {
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+func (n *ndjsonRecorder) Record(r Result) error {
+	return n.enc.Encode(newRecordLine(r))
+}
+
+func (n *ndjsonRecorder) Close() error {
+	if err := n.buf.Flush(); err != nil {
+		n.file.Close()
+		return err
+	}
+	return n.file.Close()
+}
+
+type csvRecorder struct {
+	file *os.File
+	buf  *bufio.Writer
+	w    *csv.Writer
+}
+
+func (c *csvRecorder) Record(r Result) error {
+	line := newRecordLine(r)
+	return c.w.Write([]string{
+		line.Timestamp,
+		line.Step,
+		strconv.Itoa(line.Status),
+		strconv.FormatFloat(line.LatencyMs, 'f', 3, 64),
+		strconv.FormatFloat(line.QueuedMs, 'f', 3, 64),
+		line.Error,
+		line.RequestID,
+	})
+}
+
+func (c *csvRecorder) Close() error {
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		c.file.Close()
+		return err
+	}
+	if err := c.buf.Flush(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func recordedResults() []Result {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 600_000_000, time.UTC)
+	return []Result{
+		{Timestamp: at, Step: "GET /a", Status: 200, Latency: 12500 * time.Microsecond, RequestID: "id-1"},
+		{Timestamp: at.Add(time.Second), Step: "POST /b", Status: 503, Latency: 40 * time.Millisecond, Queued: 3 * time.Millisecond},
+		{Timestamp: at.Add(2 * time.Second), Step: "GET /a", Error: errors.New("connection refused"), Latency: time.Millisecond},
+	}
+}
+
+func TestRecordNDJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.ndjson")
+	rec, err := newRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := recordedResults()
+	for _, r := range results {
+		if err := rec.Record(r); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []recordLine
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line recordLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d: %v", len(got)+1, err)
+		}
+		got = append(got, line)
+	}
+	if len(got) != len(results) {
+		t.Fatalf("read %d lines, want %d", len(got), len(results))
+	}
+	for i, r := range results {
+		if want := newRecordLine(r); !reflect.DeepEqual(got[i], want) {
+			t.Errorf("line %d = %+v, want %+v", i+1, got[i], want)
+		}
+	}
+}
+
+func TestRecordCSVRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.CSV")
+	rec, err := newRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := recordedResults()
+	for _, r := range results {
+		if err := rec.Record(r); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(rows) != len(results)+1 || rows[0][0] != "timestamp" {
+		t.Fatalf("read %d rows starting %q, want a header and %d results", len(rows), rows[0], len(results))
+	}
+	for i, r := range results {
+		row := rows[i+1]
+		status, _ := strconv.Atoi(row[2])
+		latency, _ := strconv.ParseFloat(row[3], 64)
+		queued, _ := strconv.ParseFloat(row[4], 64)
+		got := recordLine{Timestamp: row[0], Step: row[1], Status: status, LatencyMs: latency, QueuedMs: queued, Error: row[5], RequestID: row[6]}
+		if want := newRecordLine(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d = %+v, want %+v", i+1, got, want)
+		}
+	}
+}
+
+func TestRecordStreamsRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "run.ndjson")
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	if err := fs.Parse([]string{"-url", srv.URL, "-requests", "25", "-workers", "3", "-record", path}); err != nil {
+		t.Fatal(err)
+	}
+	lt, err := newLoadTest(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lt.Close()
+	out := lt.execute(context.Background(), silentDisplay{})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines int
+	for _, b := range data {
+		if b == '\n' {
+			lines++
+		}
+	}
+	if lines != 25 || out.stats.total != 25 {
+		t.Errorf("recorded %d lines for %d results, want 25", lines, out.stats.total)
+	}
+}
@@ -0,0 +1,826 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// renderText prints the human-readable summary and latency tables.
+func renderText(w io.Writer, s Summary) {
+	if s.MaxSearch != nil {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== FIND MAX ==="+cli.Reset)
+		renderMaxSearch(w, s.MaxSearch)
+	}
+
+	// Summary Section
+	if s.Partial {
+		if s.Aborted != "" {
+			fmt.Fprintln(w, "\n"+cli.Warning("Run aborted: "+s.Aborted))
+		} else {
+			fmt.Fprintln(w, "\n"+cli.Warning("Run interrupted: results cover completed requests only"))
+		}
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== SUMMARY (PARTIAL) ==="+cli.Reset)
+	} else {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== SUMMARY ==="+cli.Reset)
+	}
+	summaryTable := cli.NewTable("Metric", "Value")
+	summaryTable.Writer = w
+	summaryTable.AddRow("Total Requests", fmt.Sprintf("%d", s.Total))
+	summaryTable.AddRow("Successful", cli.Success(fmt.Sprintf("%d", s.Successful)))
+	summaryTable.AddRow("Failed", cli.Error(fmt.Sprintf("%d", s.Failed)))
+	summaryTable.AddRow("Duration", s.Duration.Round(time.Millisecond).String())
+	summaryTable.AddRow("Requests/sec", fmt.Sprintf("%.2f", s.RPS))
+	if s.Apdex != nil {
+		summaryTable.AddRow("Apdex", apdexRow(*s.Apdex))
+	}
+	if s.Redirected > 0 {
+		summaryTable.AddRow("Redirected", fmt.Sprintf("%d", s.Redirected))
+	}
+	if s.Retried > 0 {
+		summaryTable.AddRow("Retried", fmt.Sprintf("%d (%d retries)", s.Retried, s.Retries))
+	}
+	if s.Hedged > 0 {
+		summaryTable.AddRow("Hedged", fmt.Sprintf("%d (%s), duplicate won %d", s.Hedged, percentOf(s.Hedged, s.Total), s.HedgeWins))
+	}
+	if s.Throttled > 0 || s.BackedOff > 0 {
+		summaryTable.AddRow("Throttled", throttledRow(s))
+	}
+	if s.Timeouts > 0 {
+		summaryTable.AddRow("Timeouts", cli.Error(fmt.Sprintf("%d", s.Timeouts)))
+	}
+	summaryTable.AddRow("Data Received", formatBytes(s.Bytes.Total))
+	if wire, ok := wireRow(s.Bytes); ok {
+		summaryTable.AddRow("Data on Wire", wire)
+		summaryTable.AddRow("Compressed", fmt.Sprintf("%d (%s)", s.Bytes.Encoded, percentOf(s.Bytes.Encoded, s.Total-s.Errors)))
+	}
+	summaryTable.AddRow("Throughput", formatBytes(int64(s.Bytes.Throughput))+"/s")
+	summaryTable.AddRow("Body Size (min/avg/max)", fmt.Sprintf("%s / %s / %s",
+		formatBytes(s.Bytes.Min), formatBytes(s.Bytes.Mean), formatBytes(s.Bytes.Max)))
+	if len(s.Protocols) > 0 {
+		summaryTable.AddRow("Protocol", formatCounts(s.Protocols))
+	}
+	if len(s.Families) > 0 {
+		summaryTable.AddRow("Address Family", formatCounts(s.Families))
+	}
+	summaryTable.Render()
+
+	// Status Code Section, absent when nothing carried a status (bare
+	// TCP connects)
+	if len(s.StatusCodes) > 0 || s.Errors > 0 {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== STATUS CODES ==="+cli.Reset)
+		codesTable := cli.NewTable("Status", "Count", "Percent")
+		codesTable.Writer = w
+		for _, code := range slices.Sorted(maps.Keys(s.StatusCodes)) {
+			count := s.StatusCodes[code]
+			codesTable.AddRow(fmt.Sprintf("%d %s", code, http.StatusText(code)),
+				fmt.Sprintf("%d", count), percentOf(count, s.Total))
+		}
+		if s.Errors > 0 {
+			codesTable.AddRow("Errors", fmt.Sprintf("%d", s.Errors), percentOf(s.Errors, s.Total))
+		}
+		codesTable.Render()
+	}
+
+	// Error Section
+	if len(s.ErrorKinds) > 0 {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== ERRORS ==="+cli.Reset)
+		errTable := cli.NewTable("Category", "Count", "Percent", "Example")
+		errTable.Writer = w
+		for _, cat := range s.ErrorKinds {
+			errTable.AddRow(cat.Name, fmt.Sprintf("%d", cat.Count),
+				percentOf(cat.Count, s.Total), truncate(cat.Example, 80))
+		}
+		errTable.Render()
+	}
+
+	// Latency Section
+	if s.Total > 0 {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== LATENCY ==="+cli.Reset)
+		latencyTable := cli.NewTable("Percentile", "Duration")
+		if s.Corrected != nil {
+			latencyTable = cli.NewTable("Percentile", "Duration", "Corrected")
+		}
+		latencyTable.Writer = w
+		addLatencyRow := func(name string, get func(LatencyStats) time.Duration) {
+			row := []string{name, get(s.Latency).Round(time.Millisecond).String()}
+			if s.Corrected != nil {
+				row = append(row, get(*s.Corrected).Round(time.Millisecond).String())
+			}
+			latencyTable.AddRow(row...)
+		}
+		addLatencyRow("Min", func(l LatencyStats) time.Duration { return l.Min })
+		addLatencyRow("Average", func(l LatencyStats) time.Duration { return l.Mean })
+		for _, p := range latencyPercentiles(s.Latency) {
+			name := p.Name
+			if name == "P50" {
+				name += " (Median)"
+			}
+			addLatencyRow(name, p.Get)
+		}
+		addLatencyRow("Max", func(l LatencyStats) time.Duration { return l.Max })
+		latencyTable.Render()
+		if s.Corrected != nil {
+			fmt.Fprintln(w, cli.Colorize(cli.Dim, "Corrected latency is measured from each request's scheduled send time (coordinated omission)."))
+		}
+		if line, caption, ok := p95Sparkline(s.Timeline); ok {
+			fmt.Fprintf(w, "\nP95 over time  %s  %s\n", cli.Colorize(cli.Cyan, line), cli.Colorize(cli.Dim, caption))
+		}
+
+		if len(s.Distribution) > 0 {
+			fmt.Fprintln(w, "\n"+cli.Bold+"=== LATENCY DISTRIBUTION ==="+cli.Reset)
+			renderDistribution(w, s.Distribution, s.Total)
+		}
+
+		if len(s.ByStatus) > 1 {
+			fmt.Fprintln(w, "\n"+cli.Bold+"=== LATENCY BY STATUS ==="+cli.Reset)
+			statusTable := cli.NewTable("Status", "Count", "Mean", "P50", "P95", "P99", "Max")
+			statusTable.Writer = w
+			for _, sl := range s.ByStatus {
+				statusTable.AddRow(statusLatencyRow(sl)...)
+			}
+			statusTable.Render()
+		}
+
+		if s.AB != nil {
+			fmt.Fprintln(w, "\n"+cli.Bold+"=== A/B COMPARISON ==="+cli.Reset)
+			fmt.Fprintln(w, cli.Colorize(cli.Dim, "A: "+s.AB.URLA))
+			fmt.Fprintln(w, cli.Colorize(cli.Dim, "B: "+s.AB.URLB))
+			abTable := cli.NewTable(abHeaders()...)
+			abTable.Writer = w
+			for _, row := range abRows(s.AB) {
+				abTable.AddRow(row...)
+			}
+			abTable.Render()
+		}
+
+		if len(s.Endpoints) > 1 {
+			fmt.Fprintln(w, "\n"+cli.Bold+"=== PER ENDPOINT ==="+cli.Reset)
+			endpointTable := cli.NewTable("Endpoint", "Requests", "Failed", "Fail %", "Mean", "P50", "P95", "P99", "Max")
+			endpointTable.Writer = w
+			for _, e := range s.Endpoints {
+				endpointTable.AddRow(endpointRow(e)...)
+			}
+			endpointTable.Render()
+		}
+
+		if rows := bodyHashRows(s.Endpoints); len(rows) > 0 {
+			fmt.Fprintln(w, "\n"+cli.Bold+"=== RESPONSE BODIES ==="+cli.Reset)
+			hashTable := cli.NewTable("Endpoint", "Body SHA-256", "Status", "Size", "Count", "Percent")
+			hashTable.Writer = w
+			for _, row := range rows {
+				hashTable.AddRow(row...)
+			}
+			hashTable.Render()
+		}
+
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== TIMING BREAKDOWN ==="+cli.Reset)
+		timingTable := cli.NewTable("Phase", "Average", "P95", "Max")
+		timingTable.Writer = w
+		addPhaseRow(timingTable, "DNS Lookup", s.Timing.DNS)
+		addPhaseRow(timingTable, "TCP Connect", s.Timing.Connect)
+		addPhaseRow(timingTable, "TLS Handshake", s.Timing.TLS)
+		addPhaseRow(timingTable, "Time to First Byte", s.Timing.TTFB)
+		addPhaseRow(timingTable, "Body Download", s.Timing.Download)
+		timingTable.Render()
+		fmt.Fprintf(w, "Reused connections: %d\n", s.Timing.Reused)
+	} else {
+		fmt.Fprintln(w, "\n"+cli.Error("No successful requests"))
+	}
+
+	// Load Generator Section
+	if s.Self != nil {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== LOAD GENERATOR ==="+cli.Reset)
+		selfTable := cli.NewTable("Metric", "Value")
+		selfTable.Writer = w
+		for _, row := range selfStatsRows(*s.Self) {
+			selfTable.AddRow(row...)
+		}
+		selfTable.Render()
+		if s.Self.Saturated() {
+			fmt.Fprintln(w, cli.Warning(selfStatsWarning))
+		}
+	}
+
+	// Baseline Section
+	if len(s.Comparison) > 0 {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== BASELINE COMPARISON ==="+cli.Reset)
+		renderComparison(w, s.Comparison)
+	}
+
+	// Assertions Section
+	if len(s.Assertions) > 0 {
+		fmt.Fprintln(w, "\n"+cli.Bold+"=== ASSERTIONS ==="+cli.Reset)
+		assertTable := cli.NewTable("Assertion", "Result", "Detail")
+		assertTable.Writer = w
+		for _, a := range s.Assertions {
+			result := "PASS"
+			if !a.Passed {
+				result = "FAIL"
+			}
+			assertTable.AddRow(a.Name, result, a.Detail)
+		}
+		assertTable.Render()
+	}
+
+	fmt.Fprintln(w) // Final blank line for spacing
+}
+
+// distributionBarWidth is the length of the longest histogram bar.
+const distributionBarWidth = 40
+
+// renderDistribution draws the latency buckets as horizontal bars
+// scaled to the fullest bucket, so the shape of the distribution (a
+// second mode, a long tail) is visible at a glance.
+func renderDistribution(w io.Writer, buckets []LatencyBucket, total int) {
+	labels := make([]string, len(buckets))
+	labelWidth, peak := 0, 0
+	for i, b := range buckets {
+		labels[i] = bucketLabel(b)
+		labelWidth = max(labelWidth, len(labels[i]))
+		peak = max(peak, b.Count)
+	}
+	for i, b := range buckets {
+		n := b.Count * distributionBarWidth / peak
+		if b.Count > 0 && n == 0 {
+			n = 1 // keep sparse buckets visible
+		}
+		bar := strings.Repeat("#", n) + strings.Repeat(" ", distributionBarWidth-n)
+		fmt.Fprintf(w, "%-*s  %s  %d (%s)\n", labelWidth, labels[i],
+			cli.Colorize(cli.Cyan, bar), b.Count, percentOf(b.Count, total))
+	}
+}
+
+// bucketLabel names a latency bucket's range, e.g. "10ms-25ms" or "10s+".
+func bucketLabel(b LatencyBucket) string {
+	from := b.From.String()
+	if b.From == 0 {
+		from = "0"
+	}
+	if b.To == 0 {
+		return from + "+"
+	}
+	return from + "-" + b.To.String()
+}
+
+// formatCounts lists the keys of counts, such as negotiated protocols,
+// with counts only when more than one was seen.
+func formatCounts(counts map[string]int) string {
+	names := slices.Sorted(maps.Keys(counts))
+	if len(names) == 1 {
+		return names[0]
+	}
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s (%d)", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// truncate shortens s to at most n runes, marking the cut with "...".
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// percentOf formats n as a percentage of total.
+func percentOf(n, total int) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(n)/float64(total)*100)
+}
+
+// latencyPercentile is one percentile row of the latency tables.
+type latencyPercentile struct {
+	Name string
+	Get  func(LatencyStats) time.Duration
+}
+
+// latencyPercentiles returns the percentile rows to show for l: the
+// -percentiles list if one was given, otherwise p50, p95 and p99.
+func latencyPercentiles(l LatencyStats) []latencyPercentile {
+	if len(l.Percentiles) == 0 {
+		return []latencyPercentile{
+			{"P50", func(l LatencyStats) time.Duration { return l.P50 }},
+			{"P95", func(l LatencyStats) time.Duration { return l.P95 }},
+			{"P99", func(l LatencyStats) time.Duration { return l.P99 }},
+		}
+	}
+	var rows []latencyPercentile
+	for i, q := range l.Percentiles {
+		rows = append(rows, latencyPercentile{
+			Name: "P" + strconv.FormatFloat(q.P, 'f', -1, 64),
+			Get:  func(l LatencyStats) time.Duration { return l.Percentiles[i].Value },
+		})
+	}
+	return rows
+}
+
+// endpointRow formats one endpoint's counts and latencies as table
+// cells, in the order of the PER ENDPOINT columns.
+func endpointRow(e EndpointStats) []string {
+	return []string{
+		e.Name,
+		fmt.Sprintf("%d", e.Total),
+		fmt.Sprintf("%d", e.Failed),
+		percentOf(e.Failed, e.Total),
+		e.Latency.Mean.Round(time.Millisecond).String(),
+		e.Latency.P50.Round(time.Millisecond).String(),
+		e.Latency.P95.Round(time.Millisecond).String(),
+		e.Latency.P99.Round(time.Millisecond).String(),
+		e.Latency.Max.Round(time.Millisecond).String(),
+	}
+}
+
+// throttledRow describes the responses that asked for a Retry-After
+// wait and the time spent honoring them, summed over workers.
+func throttledRow(s Summary) string {
+	return fmt.Sprintf("%d (%s), backed off %s", s.Throttled, percentOf(s.Throttled, s.Total), s.BackedOff.Round(time.Millisecond))
+}
+
+// wireRow describes the bytes sent on the wire next to the decoded
+// total, for runs with -compression. ok is false without it.
+func wireRow(b ByteStats) (text string, ok bool) {
+	if b.Wire == 0 && b.Encoded == 0 {
+		return "", false
+	}
+	text = formatBytes(b.Wire)
+	if b.Total > 0 && b.Wire != b.Total {
+		text += fmt.Sprintf(" (%.1f%% of decoded)", float64(b.Wire)/float64(b.Total)*100)
+	}
+	return text, true
+}
+
+// bodyHashRowLimit caps the distinct bodies listed per endpoint; the
+// rest are folded into one row.
+const bodyHashRowLimit = 10
+
+// bodyHashRows formats the -hash-bodies distribution as table cells, in
+// the order of the RESPONSE BODIES columns. The endpoint is named on its
+// first row only.
+func bodyHashRows(endpoints []EndpointStats) [][]string {
+	var rows [][]string
+	for _, e := range endpoints {
+		hashed := 0
+		for _, h := range e.BodyHashes {
+			hashed += h.Count
+		}
+		name := e.Name
+		for i, h := range e.BodyHashes {
+			if i == bodyHashRowLimit {
+				rest := 0
+				for _, h := range e.BodyHashes[i:] {
+					rest += h.Count
+				}
+				rows = append(rows, []string{name, fmt.Sprintf("(%d more)", len(e.BodyHashes)-i), "",
+					"", fmt.Sprintf("%d", rest), percentOf(rest, hashed)})
+				break
+			}
+			rows = append(rows, []string{name, h.Hash, fmt.Sprintf("%d", h.Status), formatBytes(h.Bytes),
+				fmt.Sprintf("%d", h.Count), percentOf(h.Count, hashed)})
+			name = ""
+		}
+	}
+	return rows
+}
+
+// statusLabel names a StatusLatency group.
+func statusLabel(status int) string {
+	switch status {
+	case statusErrored:
+		return "Errors"
+	case 0:
+		return "No status"
+	}
+	return fmt.Sprintf("%d %s", status, http.StatusText(status))
+}
+
+// statusLatencyRow formats one status code's latencies as table cells,
+// in the order of the LATENCY BY STATUS columns.
+func statusLatencyRow(sl StatusLatency) []string {
+	return []string{
+		statusLabel(sl.Status),
+		fmt.Sprintf("%d", sl.Count),
+		sl.Latency.Mean.Round(time.Millisecond).String(),
+		sl.Latency.P50.Round(time.Millisecond).String(),
+		sl.Latency.P95.Round(time.Millisecond).String(),
+		sl.Latency.P99.Round(time.Millisecond).String(),
+		sl.Latency.Max.Round(time.Millisecond).String(),
+	}
+}
+
+// addPhaseRow appends one timing phase to the breakdown table.
+// Sub-millisecond phases are common, so they are rounded to microseconds.
+func addPhaseRow(t *cli.Table, name string, p PhaseStats) {
+	t.AddRow(name,
+		p.Mean.Round(time.Microsecond).String(),
+		p.P95.Round(time.Microsecond).String(),
+		p.Max.Round(time.Microsecond).String())
+}
+
+// jsonReport is the machine-readable form of a Summary.
+// Durations are expressed in milliseconds so consumers don't need to
+// parse Go duration strings.
+type jsonReport struct {
+	TotalRequests int            `json:"total_requests"`
+	Successful    int            `json:"successful"`
+	Failed        int            `json:"failed"`
+	Errors        int            `json:"errors"`
+	Mismatches    int            `json:"mismatches"`
+	Redirected    int            `json:"redirected"`
+	Retried       int            `json:"retried"`
+	Timeouts      int            `json:"timeouts"`
+	Retries       int            `json:"retries"`
+	Hedged        int            `json:"hedged"`
+	HedgeWins     int            `json:"hedge_wins"`
+	Throttled     int            `json:"throttled,omitempty"`
+	BackedOffMs   float64        `json:"backed_off_ms,omitempty"`
+	DurationMs    float64        `json:"duration_ms"`
+	RPS           float64        `json:"rps"`
+	Bytes         jsonBytes      `json:"bytes"`
+	Latency       jsonLatency    `json:"latency_ms"`
+	Corrected     *jsonLatency   `json:"corrected_latency_ms,omitempty"`
+	Apdex         *jsonApdex     `json:"apdex,omitempty"`
+	Distribution  []jsonBucket   `json:"latency_distribution"`
+	Timing        jsonTiming     `json:"timing_ms"`
+	StatusCodes   map[string]int `json:"status_codes"`
+	ErrorKinds    map[string]int `json:"error_categories"`
+	Endpoints     []jsonEndpoint `json:"endpoints,omitempty"`
+	ByStatus      []jsonStatus   `json:"latency_by_status,omitempty"`
+	Protocols     map[string]int `json:"protocols"`
+	Families      map[string]int `json:"address_families"`
+	Partial       bool           `json:"partial"`
+	Aborted       string         `json:"aborted,omitempty"`
+	Assertions    []jsonAssert   `json:"assertions,omitempty"`
+	Comparison    []jsonDelta    `json:"comparison,omitempty"`
+	FindMax       *jsonMaxSearch `json:"find_max,omitempty"`
+	AB            *jsonAB        `json:"ab_comparison,omitempty"`
+	Self          *jsonSelf      `json:"selfstats,omitempty"`
+}
+
+// jsonSelf is blitz's own resource use, with -selfstats.
+type jsonSelf struct {
+	CPUMs          float64 `json:"cpu_ms"`
+	CPUCores       float64 `json:"cpu_cores"`
+	MaxProcs       int     `json:"max_procs"`
+	PeakHeap       uint64  `json:"peak_heap_bytes"`
+	PeakMemory     uint64  `json:"peak_memory_bytes"`
+	PeakGoroutines int     `json:"peak_goroutines"`
+	GCCycles       uint32  `json:"gc_cycles"`
+	GCPauseMs      float64 `json:"gc_pause_ms"`
+	Saturated      bool    `json:"saturated"`
+}
+
+// jsonApdex is the -apdex-t score.
+type jsonApdex struct {
+	TMs        float64 `json:"t_ms"`
+	Score      float64 `json:"score"`
+	Rating     string  `json:"rating"`
+	Satisfied  int     `json:"satisfied"`
+	Tolerating int     `json:"tolerating"`
+	Frustrated int     `json:"frustrated"`
+}
+
+// jsonEndpoint is the breakdown for one target or scenario step.
+type jsonEndpoint struct {
+	Name      string      `json:"name"`
+	Requests  int         `json:"requests"`
+	Failed    int         `json:"failed"`
+	Errors    int         `json:"errors"`
+	ErrorRate float64     `json:"error_rate"`
+	Latency   jsonLatency `json:"latency_ms"`
+	Bodies    []jsonBody  `json:"body_hashes,omitempty"`
+}
+
+// jsonAB is the -url-a/-url-b comparison.
+type jsonAB struct {
+	URLA string       `json:"a_url"`
+	URLB string       `json:"b_url"`
+	A    jsonEndpoint `json:"a"`
+	B    jsonEndpoint `json:"b"`
+}
+
+func newJSONEndpoint(e EndpointStats) jsonEndpoint {
+	je := jsonEndpoint{
+		Name:     e.Name,
+		Requests: e.Total,
+		Failed:   e.Failed,
+		Errors:   e.Errors,
+		Latency:  newJSONLatency(e.Latency),
+	}
+	if e.Total > 0 {
+		je.ErrorRate = float64(e.Failed) / float64(e.Total)
+	}
+	for _, h := range e.BodyHashes {
+		je.Bodies = append(je.Bodies, jsonBody{Hash: h.Hash, Count: h.Count, Status: h.Status, Bytes: h.Bytes})
+	}
+	return je
+}
+
+// jsonBody is one distinct response body seen with -hash-bodies.
+type jsonBody struct {
+	Hash   string `json:"sha256_prefix"`
+	Count  int    `json:"count"`
+	Status int    `json:"status"`
+	Bytes  int64  `json:"bytes"`
+}
+
+// jsonStatus is the latency of the responses with one status code.
+// Status is "error" for requests that failed without a response.
+type jsonStatus struct {
+	Status  string      `json:"status"`
+	Count   int         `json:"count"`
+	Latency jsonLatency `json:"latency_ms"`
+}
+
+// jsonMaxSearch is the result of a -find-max search.
+type jsonMaxSearch struct {
+	MaxRate int         `json:"max_rate"`
+	Probes  []jsonProbe `json:"probes"`
+}
+
+type jsonProbe struct {
+	Rate      int     `json:"rate"`
+	Workers   int     `json:"workers"`
+	RPS       float64 `json:"rps"`
+	P99       float64 `json:"p99_ms"`
+	ErrorRate float64 `json:"error_rate"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// jsonDelta is one baseline comparison; ChangePct is omitted when the
+// baseline value was zero.
+type jsonDelta struct {
+	Metric    string   `json:"metric"`
+	Unit      string   `json:"unit"`
+	Baseline  float64  `json:"baseline"`
+	Current   float64  `json:"current"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+}
+
+type jsonAssert struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"`
+}
+
+type jsonLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+
+	Percentiles map[string]float64 `json:"percentiles,omitempty"` // -percentiles, keyed e.g. "p99.9"
+}
+
+// jsonBucket is one latency distribution bucket; ToMs is omitted for
+// the open-ended last bucket.
+type jsonBucket struct {
+	FromMs float64  `json:"from_ms"`
+	ToMs   *float64 `json:"to_ms,omitempty"`
+	Count  int      `json:"count"`
+}
+
+type jsonBytes struct {
+	Total          int64   `json:"total"`
+	BodyMin        int64   `json:"body_min"`
+	BodyMean       int64   `json:"body_mean"`
+	BodyMax        int64   `json:"body_max"`
+	ThroughputPerS float64 `json:"throughput_per_sec"`
+	Wire           int64   `json:"wire,omitempty"`
+	Encoded        int     `json:"compressed_responses,omitempty"`
+}
+
+// jsonTiming holds the mean duration of each connection phase.
+type jsonTiming struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	TTFB     float64 `json:"ttfb"`
+	Download float64 `json:"download"`
+	Reused   int     `json:"reused_connections"`
+}
+
+// ms converts a duration to fractional milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newJSONLatency(l LatencyStats) jsonLatency {
+	j := jsonLatency{
+		Min:  ms(l.Min),
+		Mean: ms(l.Mean),
+		P50:  ms(l.P50),
+		P95:  ms(l.P95),
+		P99:  ms(l.P99),
+		Max:  ms(l.Max),
+	}
+	if len(l.Percentiles) > 0 {
+		j.Percentiles = make(map[string]float64, len(l.Percentiles))
+		for _, q := range l.Percentiles {
+			j.Percentiles["p"+strconv.FormatFloat(q.P, 'f', -1, 64)] = ms(q.Value)
+		}
+	}
+	return j
+}
+
+func newJSONReport(s Summary) jsonReport {
+	codes := make(map[string]int, len(s.StatusCodes))
+	for code, count := range s.StatusCodes {
+		codes[strconv.Itoa(code)] = count
+	}
+	kinds := make(map[string]int, len(s.ErrorKinds))
+	for _, cat := range s.ErrorKinds {
+		kinds[cat.Name] = cat.Count
+	}
+	var endpoints []jsonEndpoint
+	for _, e := range s.Endpoints {
+		endpoints = append(endpoints, newJSONEndpoint(e))
+	}
+	var ab *jsonAB
+	if s.AB != nil {
+		ab = &jsonAB{URLA: s.AB.URLA, URLB: s.AB.URLB, A: newJSONEndpoint(s.AB.A), B: newJSONEndpoint(s.AB.B)}
+	}
+	var byStatus []jsonStatus
+	for _, sl := range s.ByStatus {
+		js := jsonStatus{Status: strconv.Itoa(sl.Status), Count: sl.Count, Latency: newJSONLatency(sl.Latency)}
+		if sl.Status == statusErrored {
+			js.Status = "error"
+		}
+		byStatus = append(byStatus, js)
+	}
+	var asserts []jsonAssert
+	for _, a := range s.Assertions {
+		asserts = append(asserts, jsonAssert{Name: a.Name, Passed: a.Passed, Detail: a.Detail})
+	}
+	var deltas []jsonDelta
+	for _, d := range s.Comparison {
+		jd := jsonDelta{Metric: d.Name, Unit: d.Unit, Baseline: d.Baseline, Current: d.Current}
+		if pct := d.Change(); !math.IsNaN(pct) {
+			jd.ChangePct = &pct
+		}
+		deltas = append(deltas, jd)
+	}
+	buckets := make([]jsonBucket, len(s.Distribution))
+	for i, b := range s.Distribution {
+		buckets[i] = jsonBucket{FromMs: ms(b.From), Count: b.Count}
+		if b.To > 0 {
+			to := ms(b.To)
+			buckets[i].ToMs = &to
+		}
+	}
+	var search *jsonMaxSearch
+	if s.MaxSearch != nil {
+		search = &jsonMaxSearch{MaxRate: s.MaxSearch.MaxRate}
+		for _, p := range s.MaxSearch.Probes {
+			search.Probes = append(search.Probes, jsonProbe{
+				Rate:      p.Rate,
+				Workers:   p.Workers,
+				RPS:       p.RPS,
+				P99:       ms(p.P99),
+				ErrorRate: p.ErrorRate,
+				Reason:    p.Reason,
+			})
+		}
+	}
+	var corrected *jsonLatency
+	if s.Corrected != nil {
+		c := newJSONLatency(*s.Corrected)
+		corrected = &c
+	}
+	var apdex *jsonApdex
+	if a := s.Apdex; a != nil {
+		apdex = &jsonApdex{TMs: ms(a.T), Score: a.Score, Rating: a.Rating(), Satisfied: a.Satisfied, Tolerating: a.Tolerating, Frustrated: a.Frustrated}
+	}
+	var self *jsonSelf
+	if st := s.Self; st != nil {
+		self = &jsonSelf{
+			CPUMs:          ms(st.CPU),
+			CPUCores:       st.CPUCores,
+			MaxProcs:       st.MaxProcs,
+			PeakHeap:       st.PeakHeap,
+			PeakMemory:     st.PeakMemory,
+			PeakGoroutines: st.PeakGoroutines,
+			GCCycles:       st.GCCycles,
+			GCPauseMs:      ms(st.GCPause),
+			Saturated:      st.Saturated(),
+		}
+	}
+	return jsonReport{
+		TotalRequests: s.Total,
+		Successful:    s.Successful,
+		Failed:        s.Failed,
+		Errors:        s.Errors,
+		Mismatches:    s.Mismatches,
+		Redirected:    s.Redirected,
+		Retried:       s.Retried,
+		Timeouts:      s.Timeouts,
+		Retries:       s.Retries,
+		Hedged:        s.Hedged,
+		HedgeWins:     s.HedgeWins,
+		Throttled:     s.Throttled,
+		BackedOffMs:   ms(s.BackedOff),
+		DurationMs:    ms(s.Duration),
+		RPS:           s.RPS,
+		Bytes: jsonBytes{
+			Total:          s.Bytes.Total,
+			BodyMin:        s.Bytes.Min,
+			BodyMean:       s.Bytes.Mean,
+			BodyMax:        s.Bytes.Max,
+			ThroughputPerS: s.Bytes.Throughput,
+			Wire:           s.Bytes.Wire,
+			Encoded:        s.Bytes.Encoded,
+		},
+		Latency:      newJSONLatency(s.Latency),
+		Corrected:    corrected,
+		Apdex:        apdex,
+		Distribution: buckets,
+		Timing: jsonTiming{
+			DNS:      ms(s.Timing.DNS.Mean),
+			Connect:  ms(s.Timing.Connect.Mean),
+			TLS:      ms(s.Timing.TLS.Mean),
+			TTFB:     ms(s.Timing.TTFB.Mean),
+			Download: ms(s.Timing.Download.Mean),
+			Reused:   s.Timing.Reused,
+		},
+		StatusCodes: codes,
+		ErrorKinds:  kinds,
+		Endpoints:   endpoints,
+		ByStatus:    byStatus,
+		Protocols:   s.Protocols,
+		Families:    s.Families,
+		Partial:     s.Partial,
+		Aborted:     s.Aborted,
+		Assertions:  asserts,
+		Comparison:  deltas,
+		FindMax:     search,
+		AB:          ab,
+		Self:        self,
+	}
+}
+
+// renderJSON writes the summary as indented JSON.
+func renderJSON(w io.Writer, s Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(newJSONReport(s))
+}
+
+// writeReport renders the summary in the requested format to path,
+// or to stdout when path is empty.
+func writeReport(format, path string, s Summary) error {
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+		// Escape codes are only meaningful on a terminal.
+		cli.SetColorsEnabled(false)
+	}
+
+	switch format {
+	case "text":
+		renderText(w, s)
+		return nil
+	case "json":
+		return renderJSON(w, s)
+	case "markdown":
+		renderMarkdown(w, s)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
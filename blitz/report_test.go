@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpointBreakdown(t *testing.T) {
+	results := []Result{
+		{Step: "login", Status: 200, Latency: 10 * time.Millisecond},
+		{Step: "login", Status: 200, Latency: 20 * time.Millisecond},
+		{Step: "search", Status: 200, Latency: 300 * time.Millisecond},
+		{Step: "search", Status: 500, Latency: 500 * time.Millisecond},
+	}
+	c := newCollector()
+	for _, r := range results {
+		c.Add(r)
+	}
+	s := c.Summary(time.Second)
+
+	j := newJSONReport(s)
+	if len(j.Endpoints) != 2 {
+		t.Fatalf("endpoints = %d, want 2", len(j.Endpoints))
+	}
+	search := j.Endpoints[1]
+	if search.Name != "search" || search.Requests != 2 || search.Failed != 1 || search.ErrorRate != 0.5 {
+		t.Errorf("search endpoint = %+v", search)
+	}
+	if search.Latency.Max < 499 {
+		t.Errorf("search max latency = %vms, want ~500ms", search.Latency.Max)
+	}
+
+	var buf bytes.Buffer
+	renderText(&buf, s)
+	out := buf.String()
+	if !strings.Contains(out, "PER ENDPOINT") || !strings.Contains(out, "50.00%") {
+		t.Errorf("text report missing per-endpoint breakdown:\n%s", out)
+	}
+}
+
+func TestStatusCodeTable(t *testing.T) {
+	c := newCollector()
+	for i := range 10 {
+		res := Result{Step: "get", Status: 200, Latency: time.Millisecond}
+		switch {
+		case i < 2:
+			res.Status = 404
+		case i == 2:
+			res.Status = 503
+		case i == 3:
+			res.Status, res.Error = 0, errors.New("connection refused")
+		}
+		c.Add(res)
+	}
+	var buf bytes.Buffer
+	renderText(&buf, c.Summary(time.Second))
+	out := buf.String()
+
+	_, section, ok := strings.Cut(out, "=== STATUS CODES ===")
+	if !ok {
+		t.Fatalf("text report has no status codes table:\n%s", out)
+	}
+	section, _, _ = strings.Cut(section, "===")
+	// Codes in ascending order, then transport errors, each as a share of all requests.
+	rows := []string{`200 OK\s+6\s+60\.00%`, `404 Not Found\s+2\s+20\.00%`, `503 Service Unavailable\s+1\s+10\.00%`, `Errors\s+1\s+10\.00%`}
+	last := -1
+	for _, row := range rows {
+		loc := regexp.MustCompile(row).FindStringIndex(section)
+		if loc == nil {
+			t.Errorf("status codes table has no row matching %q:\n%s", row, section)
+			continue
+		}
+		if loc[0] < last {
+			t.Errorf("row %q is out of order:\n%s", row, section)
+		}
+		last = loc[0]
+	}
+}
+
+func TestProtocolSummary(t *testing.T) {
+	c := newCollector()
+	for _, proto := range []string{"HTTP/2.0", "HTTP/1.1", "HTTP/2.0", "HTTP/2.0"} {
+		c.Add(Result{Step: "get", Status: 200, Proto: proto, Latency: time.Millisecond})
+	}
+	// A request with no response negotiated no protocol.
+	c.Add(Result{Step: "get", Error: errors.New("connection refused"), Latency: time.Millisecond})
+	s := c.Summary(time.Second)
+
+	want := map[string]int{"HTTP/1.1": 1, "HTTP/2.0": 3}
+	if !reflect.DeepEqual(s.Protocols, want) {
+		t.Errorf("Protocols = %v, want %v", s.Protocols, want)
+	}
+
+	var buf bytes.Buffer
+	renderText(&buf, s)
+	if row := `Protocol\s+HTTP/1\.1 \(1\), HTTP/2\.0 \(3\)`; !regexp.MustCompile(row).MatchString(buf.String()) {
+		t.Errorf("text report has no row matching %q:\n%s", row, buf.String())
+	}
+
+	buf.Reset()
+	if err := renderJSON(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	var j struct {
+		Protocols map[string]int `json:"protocols"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(j.Protocols, want) {
+		t.Errorf("JSON protocols = %v, want %v", j.Protocols, want)
+	}
+}
+
+func TestByteSummary(t *testing.T) {
+	c := newCollector()
+	for _, n := range []int64{1024, 3072, 2048} {
+		c.Add(Result{Step: "get", Status: 200, Bytes: n, Latency: time.Millisecond})
+	}
+	// Failed requests have no body to count.
+	c.Add(Result{Step: "get", Error: errors.New("connection reset"), Bytes: 512, Latency: time.Millisecond})
+	s := c.Summary(2 * time.Second)
+
+	want := ByteStats{Total: 6144, Min: 1024, Mean: 2048, Max: 3072, Throughput: 3072}
+	if s.Bytes != want {
+		t.Errorf("Bytes = %+v, want %+v", s.Bytes, want)
+	}
+
+	var buf bytes.Buffer
+	renderText(&buf, s)
+	for _, row := range []string{
+		`Data Received\s+6\.00 KiB`,
+		`Throughput\s+3\.00 KiB/s`,
+		`Body Size \(min/avg/max\)\s+1\.00 KiB / 2\.00 KiB / 3\.00 KiB`,
+	} {
+		if !regexp.MustCompile(row).MatchString(buf.String()) {
+			t.Errorf("text report has no row matching %q:\n%s", row, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := renderJSON(&buf, s); err != nil {
+		t.Fatal(err)
+	}
+	var j struct {
+		Bytes jsonBytes `json:"bytes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &j); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := jsonBytes{Total: 6144, BodyMin: 1024, BodyMean: 2048, BodyMax: 3072, ThroughputPerS: 3072}
+	if j.Bytes != wantJSON {
+		t.Errorf("JSON bytes = %+v, want %+v", j.Bytes, wantJSON)
+	}
+}
+
+func TestBodyHashes(t *testing.T) {
+	var results []Result
+	for i := range 20 {
+		results = append(results, Result{Step: "get", Status: 200, Bytes: 100, Latency: time.Millisecond, BodyHash: "full"})
+		if i < 5 {
+			results = append(results, Result{Step: "get", Status: 200, Bytes: 12, Latency: time.Millisecond, BodyHash: "short"})
+		}
+	}
+	c := newCollector()
+	for _, r := range results {
+		c.Add(r)
+	}
+	s := c.Summary(time.Second)
+
+	hashes := s.Endpoints[0].BodyHashes
+	want := []BodyHash{{Hash: "full", Count: 20, Status: 200, Bytes: 100}, {Hash: "short", Count: 5, Status: 200, Bytes: 12}}
+	if !reflect.DeepEqual(hashes, want) {
+		t.Fatalf("BodyHashes = %+v, want %+v", hashes, want)
+	}
+	rows := bodyHashRows(s.Endpoints)
+	if len(rows) != 2 || rows[0][0] != "get" || rows[1][0] != "" || rows[1][5] != "20.00%" {
+		t.Errorf("bodyHashRows() = %q", rows)
+	}
+
+	var buf bytes.Buffer
+	renderText(&buf, s)
+	if !strings.Contains(buf.String(), "RESPONSE BODIES") {
+		t.Errorf("text report missing body hashes:\n%s", buf.String())
+	}
+}
+
+func TestBodyHashRowLimit(t *testing.T) {
+	e := EndpointStats{Name: "get"}
+	for i := range bodyHashRowLimit + 3 {
+		e.BodyHashes = append(e.BodyHashes, BodyHash{Hash: fmt.Sprintf("h%02d", i), Count: 1, Status: 200})
+	}
+	rows := bodyHashRows([]EndpointStats{e})
+	if len(rows) != bodyHashRowLimit+1 {
+		t.Fatalf("got %d rows, want %d", len(rows), bodyHashRowLimit+1)
+	}
+	if last := rows[len(rows)-1]; last[1] != "(3 more)" || last[4] != "3" {
+		t.Errorf("folded row = %q", last)
+	}
+}
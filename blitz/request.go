@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// requestTemplate describes the request every worker sends.
+// The body is held in memory so it can be replayed for each request
+// without re-reading the file it came from.
+type requestTemplate struct {
+	Name   string // label used in per-step reporting
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+
+	// Capture lists the values a scenario step takes from its response
+	// for the steps after it.
+	Capture []*capture
+
+	// dynamic holds parsed text/templates for the parts containing
+	// {{ }} placeholders; nil until compile finds any.
+	dynamic *dynamicParts
+}
+
+// dynamicParts are the templated pieces of a request. A nil field
+// means that part is static and used verbatim.
+type dynamicParts struct {
+	url    *template.Template
+	body   *template.Template
+	header map[string][]*template.Template
+}
+
+// compile parses {{ }} placeholders in the URL, header values and body
+// so they can be filled per request, with templateFuncs available.
+// Referencing a column the data row lacks is an error rather than a
+// silent "<no value>".
+func (t *requestTemplate) compile() error {
+	parse := func(name, text string) (*template.Template, error) {
+		if !strings.Contains(text, "{{") {
+			return nil, nil
+		}
+		return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	}
+
+	d := &dynamicParts{header: make(map[string][]*template.Template)}
+	found := false
+
+	var err error
+	if d.url, err = parse("url", t.URL); err != nil {
+		return fmt.Errorf("url template: %w", err)
+	}
+	found = found || d.url != nil
+
+	if d.body, err = parse("body", string(t.Body)); err != nil {
+		return fmt.Errorf("body template: %w", err)
+	}
+	found = found || d.body != nil
+
+	for key, values := range t.Header {
+		tmpls := make([]*template.Template, len(values))
+		for i, v := range values {
+			if tmpls[i], err = parse(key, v); err != nil {
+				return fmt.Errorf("header %s template: %w", key, err)
+			}
+			found = found || tmpls[i] != nil
+		}
+		d.header[key] = tmpls
+	}
+
+	if found {
+		t.dynamic = d
+	}
+	return nil
+}
+
+// newRequest builds a fresh *http.Request from the template, filling
+// any placeholders from vars. A new reader is created over the body
+// bytes each time, so concurrent workers never share read state.
+func (t *requestTemplate) newRequest(ctx context.Context, vars map[string]string) (*http.Request, error) {
+	target, header, payload, err := t.expand(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if len(payload) > 0 {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, t.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	return req, nil
+}
+
+// expand fills the template's placeholders from vars, returning the
+// URL, a header map owned by the caller, and the body.
+func (t *requestTemplate) expand(vars map[string]string) (target string, header http.Header, payload []byte, err error) {
+	target, payload = t.URL, t.Body
+	if t.dynamic != nil {
+		if target, err = render(t.dynamic.url, t.URL, vars); err != nil {
+			return "", nil, nil, err
+		}
+		if t.dynamic.body != nil {
+			rendered, err := render(t.dynamic.body, "", vars)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			payload = []byte(rendered)
+		}
+	}
+
+	header = make(http.Header, len(t.Header))
+	for key, values := range t.Header {
+		for i, v := range values {
+			if t.dynamic != nil {
+				if v, err = render(t.dynamic.header[key][i], v, vars); err != nil {
+					return "", nil, nil, err
+				}
+			}
+			header.Add(key, v)
+		}
+	}
+	return target, header, payload, nil
+}
+
+// render executes tmpl with vars, or returns static when tmpl is nil.
+func render(tmpl *template.Template, static string, vars map[string]string) (string, error) {
+	if tmpl == nil {
+		return static, nil
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// loadBody returns the request payload from either an inline string
+// or a file path. Setting both is an error.
+func loadBody(inline, path string) ([]byte, error) {
+	if inline != "" && path != "" {
+		return nil, fmt.Errorf("-body and -body-file are mutually exclusive")
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return []byte(inline), nil
+}
+
+// headerList collects repeated -header flags.
+type headerList []string
+
+func (h *headerList) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerList) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+// newRequestID returns a random version 4 UUID to identify one request
+// in the target's logs.
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// parseHeaders converts "Key: Value" strings into an http.Header.
+func parseHeaders(raw []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, h := range raw {
+		key, value, ok := strings.Cut(h, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || !httpguts.ValidHeaderFieldName(key) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid header %q, expected 'Key: Value'", h)
+		}
+		header.Add(key, value)
+	}
+	return header, nil
+}
+
+// applyAuth sets the Authorization header from -basic-auth ("user:pass")
+// or -bearer. The two are mutually exclusive; either one replaces an
+// Authorization header given with -header.
+func applyAuth(header http.Header, basic, bearer string) error {
+	switch {
+	case basic != "" && bearer != "":
+		return fmt.Errorf("-basic-auth and -bearer are mutually exclusive")
+	case basic != "":
+		user, pass, ok := strings.Cut(basic, ":")
+		if !ok {
+			return fmt.Errorf("-basic-auth must be in the form user:pass")
+		}
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+pass)))
+	case bearer != "":
+		header.Set("Authorization", "Bearer "+bearer)
+	}
+	return nil
+}
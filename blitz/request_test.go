@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequestTemplateRender(t *testing.T) {
+	tmpl := &requestTemplate{
+		Method: http.MethodPost,
+		URL:    "http://h/users/{{.id}}",
+		Header: http.Header{"X-User": {"{{.name}}"}, "Accept": {"application/json"}},
+		Body:   []byte(`{"name":"{{.name}}"}`),
+	}
+	if err := tmpl.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	req, err := tmpl.newRequest(context.Background(), map[string]string{"id": "7", "name": "alice"})
+	if err != nil {
+		t.Fatalf("newRequest() error = %v", err)
+	}
+
+	if got := req.URL.String(); got != "http://h/users/7" {
+		t.Errorf("URL = %q, want http://h/users/7", got)
+	}
+	if got := req.Header.Get("X-User"); got != "alice" {
+		t.Errorf("X-User = %q, want alice", got)
+	}
+	if got := req.Header.Get("Accept"); got != "application/json" {
+		t.Errorf("Accept = %q, want application/json", got)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"name":"alice"}` {
+		t.Errorf("body = %s, want {\"name\":\"alice\"}", body)
+	}
+}
+
+func TestRequestTemplateMissingColumn(t *testing.T) {
+	tmpl := &requestTemplate{Method: http.MethodGet, URL: "http://h/{{.nope}}"}
+	if err := tmpl.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	if _, err := tmpl.newRequest(context.Background(), map[string]string{"id": "1"}); err == nil {
+		t.Error("expected error for missing column")
+	}
+}
+
+func TestLoadDataRoundRobin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte("id,name\n1,alice\n2,bob\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := loadData(path, false)
+	if err != nil {
+		t.Fatalf("loadData() error = %v", err)
+	}
+
+	want := []string{"alice", "bob", "alice"}
+	for i, name := range want {
+		if got := ds.next()["name"]; got != name {
+			t.Errorf("row %d name = %q, want %q", i, got, name)
+		}
+	}
+
+	var nilSource *dataSource
+	if nilSource.next() != nil {
+		t.Error("nil dataSource should yield nil rows")
+	}
+}
+
+func TestLoadBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(path, []byte(`{"from":"file"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		inline, file string
+		want         string
+		wantErr      bool
+	}{
+		{"none", "", "", "", false},
+		{"inline", `{"from":"flag"}`, "", `{"from":"flag"}`, false},
+		{"file", "", path, `{"from":"file"}`, false},
+		{"both", `{"from":"flag"}`, path, "", true},
+		{"missing file", "", filepath.Join(t.TempDir(), "missing.json"), "", true},
+	}
+	for _, tt := range tests {
+		body, err := loadBody(tt.inline, tt.file)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: loadBody() = %q, want an error", tt.name, body)
+			}
+			continue
+		}
+		if err != nil || string(body) != tt.want {
+			t.Errorf("%s: loadBody() = %q, %v; want %q", tt.name, body, err, tt.want)
+		}
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := parseHeaders([]string{"Content-Type: application/json", "X-Trace:abc"})
+	if err != nil {
+		t.Fatalf("parseHeaders() error = %v", err)
+	}
+	if h.Get("Content-Type") != "application/json" || h.Get("X-Trace") != "abc" {
+		t.Errorf("parseHeaders() = %v", h)
+	}
+	for _, raw := range []string{"no-colon", ": empty name", "Bad(Name): x", "X Trace: abc", "X-Trace: a\nb"} {
+		if _, err := parseHeaders([]string{raw}); err == nil {
+			t.Errorf("parseHeaders(%q) accepted an invalid header", raw)
+		}
+	}
+}
+
+func TestApplyAuth(t *testing.T) {
+	h := http.Header{"Authorization": {"Token old"}}
+	if err := applyAuth(h, "alice:s3cret", ""); err != nil {
+		t.Fatalf("applyAuth() error = %v", err)
+	}
+	if got := h.Get("Authorization"); got != "Basic YWxpY2U6czNjcmV0" {
+		t.Errorf("basic Authorization = %q", got)
+	}
+
+	h = http.Header{}
+	if err := applyAuth(h, "", "tok123"); err != nil {
+		t.Fatalf("applyAuth() error = %v", err)
+	}
+	if got := h.Get("Authorization"); got != "Bearer tok123" {
+		t.Errorf("bearer Authorization = %q", got)
+	}
+
+	if err := applyAuth(http.Header{}, "a:b", "tok"); err == nil {
+		t.Error("expected error when both are set")
+	}
+	if err := applyAuth(http.Header{}, "nocolon", ""); err == nil {
+		t.Error("expected error for malformed basic auth")
+	}
+}
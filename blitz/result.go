@@ -2,49 +2,485 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
+	"slices"
 	"time"
+
+	"github.com/NickDiPreta/gokit/pool"
 )
 
+// bodyHashLen is how many bytes of a body's SHA-256 -hash-bodies keeps,
+// plenty to tell a handful of distinct bodies apart.
+const bodyHashLen = 8
+
 type Result struct {
-	Status    int
-	Latency   time.Duration
-	Error     error
-	Timestamp time.Time
+	Step       string // name of the scenario step that produced this result
+	Status     int
+	Proto      string // negotiated protocol, e.g. "HTTP/2.0"
+	Bytes      int64  // response body bytes read
+	Latency    time.Duration
+	Queued     time.Duration // wait between the scheduled and actual send time
+	Error      error
+	Mismatch   error           // response arrived but failed a body expectation
+	Redirected bool            // response followed a redirect, or is one that wasn't followed
+	Check      statusCheck     // how Status was judged against -success-codes
+	Retries    int             // times the request was resent after a transient failure
+	Hedged     bool            // a duplicate was sent after -hedge elapsed
+	HedgeWon   bool            // the duplicate completed first and is what's reported
+	Span       *spanContext    // client span sent in the traceparent header, with -otlp-traces
+	RequestID  string          // ID sent in the -request-id-header
+	BodyHash   string          // hex prefix of the body's SHA-256, with -hash-bodies
+	WireBytes  int64           // body bytes as sent, before decoding; with -compression only
+	Encoded    bool            // the body arrived with a Content-Encoding; with -compression only
+	Sample     *responseSample // the failed exchange, captured for -save-errors
+	Timestamp  time.Time
+	Timing     Timing
+
+	// Captured holds the values taken for later scenario steps; nil
+	// unless every capture of the step found one.
+	Captured map[string]string
+
+	// With -retry-after: the wait a 429 or 503 asked for, and how long
+	// this request was held back by earlier ones.
+	RetryAfter time.Duration
+	BackedOff  time.Duration
+}
+
+// requester bundles everything a worker needs to send one request
+// and evaluate the response.
+type requester struct {
+	client   *http.Client
+	targets  *mix
+	data     *dataSource // optional per-iteration template variables
+	expect   *expectations
+	cookies  bool          // give each worker its own cookie jar
+	retries  int           // resend attempts for transient failures
+	backoff  time.Duration // delay before the first retry, doubled for each one after
+	timeout  time.Duration // per-attempt deadline set on the request context; 0 for none
+	hedge    time.Duration // send a duplicate of an attempt still running after this; 0 for none
+	spans    bool          // send a traceparent header and record a span per request
+	idHeader string        // header carrying a fresh ID on every request; "" for none
+	grpc     *grpcClient   // set in -grpc mode, replacing HTTP requests with unary calls
+	ws       bool          // target is a WebSocket URL; each worker holds one connection
+	tcp      dialFunc      // set for tcp:// targets, replacing HTTP requests with bare connects
+	tcpHold  time.Duration // keep each tcp:// connection open this long before closing it
+	inflight chan struct{} // -max-inflight slots shared by all workers; nil for no cap
+	hashBody bool          // hash each response body into Result.BodyHash
+	encoding string        // -compression; "" leaves Accept-Encoding to the transport
+	think    thinkTime     // pause between a worker's iterations
+	success  []int         // -success-codes; nil means any 2xx is a success
+	saver    *errorSaver   // -save-errors; captures failed responses while it wants more
+
+	skipBody  bool      // -discard-body: close responses without reading their bodies
+	bodyLimit int64     // -max-body-read: read at most this many body bytes; 0 for all
+	throttle  *throttle // -retry-after; nil to ignore Retry-After headers
+}
+
+// statusCheck records whether an HTTP status was one of the
+// -success-codes, which replace the default rule that 2xx succeeds.
+type statusCheck int8
+
+const (
+	statusDefault  statusCheck = iota // no -success-codes
+	statusListed                      // Status is one of -success-codes
+	statusUnlisted                    // Status is not one of -success-codes
+)
+
+// checkStatus judges code against the configured success codes.
+func (r *requester) checkStatus(code int) statusCheck {
+	switch {
+	case len(r.success) == 0:
+		return statusDefault
+	case slices.Contains(r.success, code):
+		return statusListed
+	}
+	return statusUnlisted
+}
+
+// forWorker returns the requester a single worker should use. With
+// cookies enabled each worker gets a private jar, so a session started
+// by one virtual user is never seen by another, and with -retry-after
+// worker each backs off on its own.
+func (r *requester) forWorker() *requester {
+	throttle := r.throttle.forWorker()
+	if !r.cookies && throttle == r.throttle {
+		return r
+	}
+	w := *r
+	w.throttle = throttle
+	if r.cookies {
+		jar, _ := cookiejar.New(nil) // New never returns an error with nil options
+		client := *r.client
+		client.Jar = jar
+		w.client = &client
+	}
+	return &w
+}
+
+// redirectPolicy returns a CheckRedirect func that follows up to max
+// redirects, or none at all when follow is false, in which case the
+// redirect response is returned to the caller as-is.
+func redirectPolicy(max int, follow bool) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= max {
+			return fmt.Errorf("stopped after %d redirects", max)
+		}
+		return nil
+	}
+}
+
+// isRedirect reports whether code is one the client would follow.
+func isRedirect(code int) bool {
+	switch code {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// virtualUser is one of the -c simulated users. Iterations run on
+// whichever pool worker is free, so the per-user state that used to
+// live in each worker goroutine (its cookie jar, and whether it has
+// run yet so needs a -think pause) travels with the user instead.
+type virtualUser struct {
+	*requester
+	started bool
 }
 
-func worker(ctx context.Context, client *http.Client, url string, jobs <-chan struct{}, results chan<- Result) {
-	for range jobs {
-		results <- makeRequest(ctx, client, url)
+// runWorkers runs jobs on a gokit/pool of n workers until jobs is
+// closed and every iteration has finished. Each pool job is one
+// iteration, run by a virtual user checked out for its length; with n
+// users and n workers a checkout never waits. WebSocket users hold a
+// connection for the whole run, so with -ws each user is instead one
+// long pool job that reads jobs itself.
+func runWorkers(ctx context.Context, r *requester, n int, jobs <-chan job, results chan<- Result) {
+	users := make(chan *virtualUser, n)
+	for range n {
+		users <- &virtualUser{requester: r.forWorker()}
 	}
 
+	// Unbuffered, so Submit hands jobs over only as workers free up and
+	// queueing stays visible in Result.Queued. The pool runs until
+	// Shutdown rather than until ctx ends: workers leaving on
+	// cancellation could strand a Submit, and iterations see ctx anyway.
+	p := pool.New(n, 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range p.Start(context.Background()) {
+			// Iterations report through results; the pool's own
+			// results carry nothing.
+		}
+	}()
+
+	if r.ws {
+		for i := range n {
+			p.Submit(pool.Job{ID: i, Func: func([]byte) ([]byte, error) {
+				u := <-users
+				u.wsSession(ctx, jobs, results)
+				return nil, nil
+			}})
+		}
+	} else {
+		id := 0
+		for j := range jobs {
+			p.Submit(pool.Job{ID: id, Func: func([]byte) ([]byte, error) {
+				u := <-users
+				defer func() { users <- u }()
+				if u.started && !u.think.pause(ctx) {
+					return nil, nil
+				}
+				u.started = true
+				u.runIteration(ctx, j, results)
+				return nil, nil
+			}})
+			id++
+		}
+	}
+	p.Shutdown()
+	<-done
+}
+
+// acquire waits for an in-flight slot, reporting false if ctx ends
+// first. Without -max-inflight it returns immediately.
+func (r *requester) acquire(ctx context.Context) bool {
+	if r.inflight == nil {
+		return true
+	}
+	select {
+	case r.inflight <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees the slot taken by acquire.
+func (r *requester) release() {
+	if r.inflight != nil {
+		<-r.inflight
+	}
+}
+
+// runIteration executes every scenario step in order, pausing for each
+// step's think time and passing values captured from responses on to
+// the steps after. A transport error or a missing capture ends the
+// iteration early since later steps usually depend on earlier ones.
+func (r *requester) runIteration(ctx context.Context, j job, results chan<- Result) {
+	// One data row per iteration, so every step acts as the same user.
+	vars := r.data.next()
+	for i, st := range r.targets.pick().Steps {
+		// Waiting out a Retry-After or for an in-flight slot counts as
+		// queueing, so it shows up in corrected latency.
+		backedOff, ok := r.throttle.wait(ctx)
+		if !ok || !r.acquire(ctx) {
+			return
+		}
+		var queued time.Duration
+		if i == 0 && !j.Scheduled.IsZero() {
+			queued = max(time.Since(j.Scheduled), 0)
+		}
+		res := r.send(ctx, st.Tmpl, vars)
+		r.release()
+		res.Queued = queued
+		res.BackedOff = backedOff
+		r.throttle.observe(res)
+		results <- res
+		if res.Error != nil {
+			return
+		}
+		if len(st.Tmpl.Capture) > 0 {
+			if res.Captured == nil {
+				return
+			}
+			vars = withCaptures(vars, res.Captured)
+		}
+		if st.Think > 0 {
+			select {
+			case <-time.After(st.Think):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// send makes a request, retrying transient connection failures up to
+// r.retries times with exponential backoff. Only the final attempt is
+// reported, with Retries recording how many attempts preceded it.
+func (r *requester) send(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
+	call := r.makeRequest
+	switch {
+	case r.grpc != nil:
+		call = r.grpc.call
+	case r.tcp != nil:
+		call = r.tcpConnect
+	}
+	once := func(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
+		if r.timeout <= 0 {
+			return call(ctx, tmpl, vars)
+		}
+		ctx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+		return call(ctx, tmpl, vars)
+	}
+	do := once
+	if r.hedge > 0 {
+		do = func(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
+			return r.hedged(ctx, func(ctx context.Context) Result { return once(ctx, tmpl, vars) })
+		}
+	}
+	res := do(ctx, tmpl, vars)
+	for attempt := 0; attempt < r.retries && res.Error != nil && isTransient(res.Error); attempt++ {
+		select {
+		case <-time.After(r.backoff << attempt):
+		case <-ctx.Done():
+			return res
+		}
+		res = do(ctx, tmpl, vars)
+		res.Retries = attempt + 1
+	}
+	return res
+}
+
+// hedged runs attempt and, if it hasn't completed within r.hedge, a
+// duplicate of it, returning whichever completes first and cancelling
+// the other. Latency is measured from the first attempt's start, since
+// that is what a hedging client would observe.
+func (r *requester) hedged(ctx context.Context, attempt func(context.Context) Result) Result {
+	type outcome struct {
+		res Result
+		dup bool
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan outcome, 2) // buffered so the loser never blocks
+
+	start := time.Now()
+	go func() { done <- outcome{res: attempt(ctx)} }()
+	timer := time.NewTimer(r.hedge)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.res
+	case <-timer.C:
+	}
+
+	go func() { done <- outcome{res: attempt(ctx), dup: true} }()
+	o := <-done
+	o.res.Hedged = true
+	if o.dup {
+		o.res.HedgeWon = true
+		o.res.Latency = o.res.Timestamp.Sub(start)
+	}
+	return o.res
 }
 
 // helper function used to make the http request so we can close the body cleanly
 // don't want to risk leaving open in range loop
-func makeRequest(ctx context.Context, client *http.Client, url string) Result {
+func (r *requester) makeRequest(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
 	start := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	var tr tracer
+	req, err := tmpl.newRequest(tr.withTrace(ctx), vars)
 	if err != nil {
 		return Result{
+			Step:      tmpl.Name,
 			Error:     err,
 			Timestamp: time.Now(),
 		}
 	}
-	resp, err := client.Do(req)
+	var span *spanContext
+	if r.spans {
+		sc := newSpanContext()
+		span = &sc
+		req.Header.Set("traceparent", sc.traceparent())
+	}
+	var requestID string
+	if r.idHeader != "" {
+		requestID = newRequestID()
+		req.Header.Set(r.idHeader, requestID)
+	}
+	if r.encoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncodings[r.encoding])
+	}
+	resp, err := r.client.Do(req)
 	if err != nil {
-		return Result{
+		res := Result{
+			Step:      tmpl.Name,
 			Error:     err,
+			Latency:   time.Since(start),
 			Timestamp: time.Now(),
+			Span:      span,
+			RequestID: requestID,
 		}
+		if r.saver.wants() {
+			res.Sample = &responseSample{Method: req.Method, URL: req.URL.String()}
+		}
+		return res
 	}
 	defer resp.Body.Close()
-	io.Copy(io.Discard, resp.Body)
 
-	return Result{
+	// With -compression the body is counted as it comes off the wire
+	// and decoded here rather than by the transport.
+	var reader io.Reader = resp.Body
+	var wire *countingReader
+	var encoded bool
+	if r.encoding != "" {
+		reader, wire, encoded, err = wireBody(resp)
+	}
+	// With -hash-bodies the body is hashed as it streams past, whichever
+	// way it is read below.
+	var hasher hash.Hash
+	if r.hashBody {
+		hasher = sha256.New()
+		reader = io.TeeReader(reader, hasher)
+	}
+	if r.bodyLimit > 0 {
+		// Closing the rest unread gives up the connection on HTTP/1.1,
+		// as a client abandoning a download would.
+		reader = io.LimitReader(reader, r.bodyLimit)
+	}
+	var body []byte
+	var n int64
+	switch {
+	case err != nil:
+	case r.expect.needsBody() || tmpl.capturesBody():
+		// Steps capturing from the body read it even with -discard-body.
+		body, err = io.ReadAll(reader)
+		n = int64(len(body))
+	case r.skipBody:
+	case r.saver.wants():
+		// Keep the start of the body in case the response fails.
+		body, err = io.ReadAll(io.LimitReader(reader, sampleBodyLimit))
+		n = int64(len(body))
+		if err == nil {
+			var rest int64
+			rest, err = io.Copy(io.Discard, reader)
+			n += rest
+		}
+	default:
+		n, err = io.Copy(io.Discard, reader)
+	}
+	end := time.Now()
+
+	res := Result{
+		Step:      tmpl.Name,
 		Status:    resp.StatusCode,
-		Latency:   time.Since(start),
-		Timestamp: time.Now(),
+		Check:     r.checkStatus(resp.StatusCode),
+		Proto:     resp.Proto,
+		Bytes:     n,
+		Latency:   end.Sub(start),
+		Timestamp: end,
+		Timing:    tr.timing(end),
+		Span:      span,
+		RequestID: requestID,
+		// resp.Request.Response is set when this request was issued
+		// because of a redirect.
+		Redirected: resp.Request.Response != nil ||
+			(isRedirect(resp.StatusCode) && resp.Header.Get("Location") != ""),
+	}
+	if wire != nil {
+		res.WireBytes = wire.n
+		res.Encoded = encoded
+	}
+	if r.throttle != nil {
+		res.RetryAfter = retryAfter(resp, end)
+	}
+	if err != nil {
+		res.Error = err
+	} else {
+		res.Mismatch = r.expect.checkBody(body)
+		if hasher != nil {
+			res.BodyHash = hex.EncodeToString(hasher.Sum(nil)[:bodyHashLen])
+		}
+		if len(tmpl.Capture) > 0 {
+			var capErr error
+			res.Captured, capErr = tmpl.captureAll(resp.Header, body)
+			if res.Mismatch == nil {
+				res.Mismatch = capErr
+			}
+		}
+	}
+	if r.saver.wants() && failed(res) {
+		res.Sample = &responseSample{
+			Method:    req.Method,
+			URL:       req.URL.String(),
+			Status:    resp.Status,
+			Proto:     resp.Proto,
+			Header:    resp.Header,
+			Body:      body[:min(len(body), sampleBodyLimit)],
+			Truncated: n > sampleBodyLimit,
+		}
 	}
+	return res
 }
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForWorkerCookieJar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			return
+		}
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	base := &requester{client: srv.Client(), cookies: true}
+	login := &requestTemplate{Name: "login", Method: http.MethodGet, URL: srv.URL + "/login"}
+	me := &requestTemplate{Name: "me", Method: http.MethodGet, URL: srv.URL + "/me"}
+
+	a, b := base.forWorker(), base.forWorker()
+	if a.client.Jar == nil || a.client.Jar == b.client.Jar {
+		t.Fatal("each worker should get its own cookie jar")
+	}
+	if base.client.Jar != nil {
+		t.Fatal("forWorker must not modify the shared client")
+	}
+
+	ctx := context.Background()
+	a.makeRequest(ctx, login, nil)
+	if res := a.makeRequest(ctx, me, nil); res.Status != http.StatusOK {
+		t.Errorf("worker with session: status = %d, want 200", res.Status)
+	}
+	if res := b.makeRequest(ctx, me, nil); res.Status != http.StatusUnauthorized {
+		t.Errorf("worker without session: status = %d, want 401", res.Status)
+	}
+}
+
+func TestRunWorkersKeepsUserCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}), 1)
+	r := &requester{client: srv.Client(), targets: targets, cookies: true}
+
+	const users, n = 3, 12
+	jobs := make(chan job, n)
+	for range n {
+		jobs <- job{}
+	}
+	close(jobs)
+	results := make(chan Result, n)
+	runWorkers(context.Background(), r, users, jobs, results)
+	close(results)
+
+	unauthorized := 0
+	for res := range results {
+		if res.Status == http.StatusUnauthorized {
+			unauthorized++
+		}
+	}
+	// Only each user's first iteration arrives without the session,
+	// however the pool spreads iterations over its workers.
+	if unauthorized > users {
+		t.Errorf("%d iterations had no session, want at most %d", unauthorized, users)
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "old", Method: http.MethodGet, URL: srv.URL + "/old"}
+	tests := []struct {
+		name       string
+		max        int
+		follow     bool
+		wantStatus int
+		wantErr    bool
+	}{
+		{"follow", 10, true, http.StatusOK, false},
+		{"no follow", 10, false, http.StatusFound, false},
+		{"limit exceeded", 0, true, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := srv.Client()
+			client.CheckRedirect = redirectPolicy(tt.max, tt.follow)
+			r := &requester{client: client}
+			res := r.makeRequest(context.Background(), tmpl, nil)
+			if (res.Error != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", res.Error, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res.Status != tt.wantStatus || !res.Redirected {
+				t.Errorf("status = %d, redirected = %v; want %d, true", res.Status, res.Redirected, tt.wantStatus)
+			}
+			if failed(res) {
+				t.Error("redirect should count as a success")
+			}
+		})
+	}
+}
+
+func TestSendRetriesTransientErrors(t *testing.T) {
+	calls, failures := 0, 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			// Drop the connection without a response.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}
+	}))
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "flaky", Method: http.MethodGet, URL: srv.URL}
+	r := &requester{client: srv.Client(), retries: 3, backoff: time.Millisecond}
+	res := r.send(context.Background(), tmpl, nil)
+	if res.Error != nil || res.Status != http.StatusOK {
+		t.Fatalf("send() = %d, %v; want 200 after retries", res.Status, res.Error)
+	}
+	if res.Retries != 2 {
+		t.Errorf("Retries = %d, want 2", res.Retries)
+	}
+
+	calls, failures = 0, 10
+	r.retries = 1
+	if res := r.send(context.Background(), tmpl, nil); res.Error == nil || res.Retries != 1 {
+		t.Errorf("send() = %v after %d retries; want error after 1", res.Error, res.Retries)
+	}
+}
+
+func TestSendTimeouts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	tmpl := &requestTemplate{Name: "slow", Method: http.MethodGet, URL: srv.URL}
+
+	client := *srv.Client()
+	client.Timeout = 20 * time.Millisecond
+	perClient := &requester{client: &client}
+	perRequest := &requester{client: srv.Client(), timeout: 20 * time.Millisecond}
+
+	c := newCollector()
+	for name, r := range map[string]*requester{"-timeout": perClient, "-request-timeout": perRequest} {
+		res := r.send(context.Background(), tmpl, nil)
+		if got := classify(res); got != catTimeout {
+			t.Errorf("%s: category %q (%v), want %q", name, got, res.Error, catTimeout)
+		}
+		c.Add(res)
+	}
+	if s := c.Summary(time.Second); s.Timeouts != 2 {
+		t.Errorf("Timeouts = %d, want 2", s.Timeouts)
+	}
+}
+
+func TestSuccessCodes(t *testing.T) {
+	r := &requester{success: []int{200, 404}}
+	tests := []struct {
+		status int
+		failed bool
+	}{
+		{200, false},
+		{404, false},
+		{201, true},
+		{500, true},
+	}
+	for _, tt := range tests {
+		res := Result{Status: tt.status, Check: r.checkStatus(tt.status)}
+		if got := failed(res); got != tt.failed {
+			t.Errorf("failed(%d) = %v, want %v", tt.status, got, tt.failed)
+		}
+	}
+
+	if got := (&requester{}).checkStatus(404); got != statusDefault {
+		t.Errorf("checkStatus without -success-codes = %v, want statusDefault", got)
+	}
+	if !failed(Result{Status: 404}) {
+		t.Error("404 should fail without -success-codes")
+	}
+}
+
+func TestSendHedges(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		// Only the first copy is slow.
+		if n == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}
+	}))
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "slow", Method: http.MethodGet, URL: srv.URL}
+	r := &requester{client: srv.Client(), hedge: 20 * time.Millisecond}
+	res := r.send(context.Background(), tmpl, nil)
+	if res.Error != nil || !res.Hedged || !res.HedgeWon {
+		t.Fatalf("send() = %+v, want the hedged duplicate to win", res)
+	}
+	if res.Latency < 20*time.Millisecond || res.Latency > 500*time.Millisecond {
+		t.Errorf("Latency = %v, want the hedge delay plus the fast reply", res.Latency)
+	}
+
+	// A fast first attempt is never hedged.
+	res = r.send(context.Background(), tmpl, nil)
+	if res.Hedged {
+		t.Error("fast request should not be hedged")
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	var seen []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = append(seen, r.Header.Get("X-Request-ID"))
+	}))
+	defer srv.Close()
+
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}
+	r := &requester{client: srv.Client(), idHeader: "X-Request-ID"}
+	a := r.makeRequest(context.Background(), tmpl, nil)
+	b := r.makeRequest(context.Background(), tmpl, nil)
+
+	if len(seen) != 2 || seen[0] != a.RequestID || seen[1] != b.RequestID {
+		t.Fatalf("server saw %q, results carry %q and %q", seen, a.RequestID, b.RequestID)
+	}
+	if a.RequestID == b.RequestID {
+		t.Error("request IDs should be unique")
+	}
+	if len(a.RequestID) != 36 || a.RequestID[14] != '4' {
+		t.Errorf("RequestID = %q, want a version 4 UUID", a.RequestID)
+	}
+	if line := newRecordLine(a); line.RequestID != a.RequestID {
+		t.Errorf("record line request_id = %q, want %q", line.RequestID, a.RequestID)
+	}
+}
+
+func TestRequestIDHeaderValidation(t *testing.T) {
+	for _, name := range []string{"X Request ID", "X-Request-ID:", "X(Request)", "X-Request-ID\n"} {
+		cfg := config{URLs: urlList{{URL: "http://localhost"}}, Workers: 1, RequestID: name}
+		var usage usageError
+		if _, err := newLoadTest(cfg); !errors.As(err, &usage) {
+			t.Errorf("newLoadTest with -request-id-header %q: err = %v, want a usage error", name, err)
+		}
+	}
+}
+
+func TestMaxInflight(t *testing.T) {
+	var mu sync.Mutex
+	active, peak := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}), 1)
+	r := &requester{client: srv.Client(), targets: targets, inflight: make(chan struct{}, 2)}
+
+	const n = 16
+	jobs := make(chan job, n)
+	for range n {
+		jobs <- job{Scheduled: time.Now()}
+	}
+	close(jobs)
+	results := make(chan Result, n)
+	runWorkers(context.Background(), r, 8, jobs, results)
+	close(results)
+
+	var maxQueued time.Duration
+	count := 0
+	for res := range results {
+		count++
+		maxQueued = max(maxQueued, res.Queued)
+	}
+	if count != n {
+		t.Fatalf("got %d results, want %d", count, n)
+	}
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+	// 16 requests two at a time take eight rounds; the last ones wait
+	// for most of them.
+	if maxQueued < 50*time.Millisecond {
+		t.Errorf("max Queued = %v, want the wait for a slot included", maxQueued)
+	}
+}
+
+func TestHashBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+	defer srv.Close()
+
+	r := &requester{client: srv.Client(), hashBody: true}
+	get := func(body string) Result {
+		tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL + "?body=" + body}
+		return r.makeRequest(context.Background(), tmpl, nil)
+	}
+	a, b, c := get("same"), get("same"), get("other")
+	if a.BodyHash == "" || a.BodyHash != b.BodyHash {
+		t.Errorf("identical bodies hashed to %q and %q", a.BodyHash, b.BodyHash)
+	}
+	if c.BodyHash == a.BodyHash {
+		t.Error("different bodies hashed alike")
+	}
+	if len(a.BodyHash) != 2*bodyHashLen {
+		t.Errorf("BodyHash = %q, want %d hex digits", a.BodyHash, 2*bodyHashLen)
+	}
+
+	r.hashBody = false
+	if res := get("same"); res.BodyHash != "" {
+		t.Errorf("BodyHash = %q without -hash-bodies", res.BodyHash)
+	}
+}
+
+func TestBodyReadLimits(t *testing.T) {
+	const size = 1 << 20
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, size))
+	}))
+	defer srv.Close()
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}
+
+	for _, tt := range []struct {
+		name string
+		r    *requester
+		want int64
+	}{
+		{"whole body", &requester{client: srv.Client()}, size},
+		{"discard", &requester{client: srv.Client(), skipBody: true}, 0},
+		{"limit", &requester{client: srv.Client(), bodyLimit: 1000}, 1000},
+		{"limit above size", &requester{client: srv.Client(), bodyLimit: 2 * size}, size},
+	} {
+		res := tt.r.makeRequest(context.Background(), tmpl, nil)
+		if res.Error != nil || res.Status != http.StatusOK {
+			t.Errorf("%s: status %d, error %v", tt.name, res.Status, res.Error)
+		}
+		if res.Bytes != tt.want {
+			t.Errorf("%s: read %d bytes, want %d", tt.name, res.Bytes, tt.want)
+		}
+	}
+}
+
+func TestCoordinatedOmission(t *testing.T) {
+	// The first request stalls the only worker while the schedule keeps
+	// falling due; the requests it could not send meanwhile go out late.
+	const stall = 300 * time.Millisecond
+	var once sync.Once
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { time.Sleep(stall) })
+	}))
+	defer srv.Close()
+
+	fs := flag.NewFlagSet("blitz", flag.ContinueOnError)
+	var cfg config
+	cfg.registerFlags(fs)
+	if err := fs.Parse([]string{"-url", srv.URL, "-rate", "100", "-requests", "60", "-workers", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.requestsSet = true
+	lt, err := newLoadTest(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lt.Close()
+	s := lt.execute(context.Background(), silentDisplay{}).stats.Summary(0)
+
+	if s.Total != 60 {
+		t.Fatalf("total = %d, want 60", s.Total)
+	}
+	if s.Corrected == nil {
+		t.Fatal("a paced run should report corrected latency")
+	}
+	// Only one request was slow to answer, so it sits above the raw p95.
+	if s.Latency.P95 >= stall/2 {
+		t.Errorf("raw p95 = %v, want it well under the %v stall", s.Latency.P95, stall)
+	}
+	// About 30 requests fell due during the stall and waited up to its
+	// length to be sent, which corrected latency counts.
+	if s.Corrected.P50 <= s.Latency.P50 || s.Corrected.P95 < stall/2 {
+		t.Errorf("corrected p50 %v, p95 %v; want the queued requests' wait included", s.Corrected.P50, s.Corrected.P95)
+	}
+	if s.Corrected.Max < stall {
+		t.Errorf("corrected max = %v, want at least the %v stall", s.Corrected.Max, stall)
+	}
+}
+
+func TestTransportErrorLatency(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	const timeout = 50 * time.Millisecond
+	client := srv.Client()
+	client.Timeout = timeout
+	r := &requester{client: client}
+	res := r.makeRequest(context.Background(), &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}, nil)
+	if res.Error == nil {
+		t.Fatal("request to a stalled server should time out")
+	}
+	// A timeout is as slow as a request gets, not an instant one.
+	if res.Latency < timeout {
+		t.Errorf("timed-out request latency = %v, want at least %v", res.Latency, timeout)
+	}
+
+	c := newCollector()
+	c.Add(res)
+	if got := c.Summary(time.Second).Latency.Max; got < timeout {
+		t.Errorf("max latency = %v, want the timed-out request's %v counted", got, res.Latency)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// sampleBodyLimit caps how much of a failed response's body -save-errors
+// keeps.
+const sampleBodyLimit = 64 << 10
+
+// responseSample is what -save-errors keeps of a failed exchange. Header
+// and Body are empty when no response arrived.
+type responseSample struct {
+	Method    string
+	URL       string
+	Status    string // status line text, e.g. "404 Not Found"
+	Proto     string
+	Header    http.Header
+	Body      []byte
+	Truncated bool // Body holds only the first sampleBodyLimit bytes
+}
+
+// errorSaver writes the first failed results of a run to a directory,
+// one file each, so a failure count can be traced back to the actual
+// responses. Save is called from the collecting goroutine only; workers
+// consult wants to skip buffering bodies once the limit is reached.
+type errorSaver struct {
+	dir   string
+	limit int
+	saved int
+	full  atomic.Bool
+}
+
+func newErrorSaver(dir string, limit int) (*errorSaver, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &errorSaver{dir: dir, limit: limit}, nil
+}
+
+// wants reports whether failed responses should still be captured.
+func (s *errorSaver) wants() bool {
+	return s != nil && !s.full.Load()
+}
+
+// Save writes r to the next numbered file if it failed and fewer than
+// limit results have been saved so far.
+func (s *errorSaver) Save(r Result) error {
+	if !failed(r) || s.saved >= s.limit {
+		return nil
+	}
+	s.saved++
+	if s.saved == s.limit {
+		s.full.Store(true)
+	}
+	name := filepath.Join(s.dir, fmt.Sprintf("error-%04d.txt", s.saved))
+	if err := os.WriteFile(name, formatSample(r), 0o644); err != nil {
+		s.full.Store(true)
+		return err
+	}
+	return nil
+}
+
+// formatSample renders a failed result as a comment header describing
+// the failure followed by the response in HTTP wire format.
+func formatSample(r Result) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# time: %s\n", r.Timestamp.Format(time.RFC3339Nano))
+	if r.Step != "" {
+		fmt.Fprintf(&b, "# step: %s\n", r.Step)
+	}
+	fmt.Fprintf(&b, "# latency: %s\n", r.Latency)
+	if r.RequestID != "" {
+		fmt.Fprintf(&b, "# request id: %s\n", r.RequestID)
+	}
+	if cat := classify(r); cat != "" {
+		fmt.Fprintf(&b, "# failure: %s\n", cat)
+	}
+	switch {
+	case r.Error != nil:
+		fmt.Fprintf(&b, "# error: %v\n", r.Error)
+	case r.Mismatch != nil:
+		fmt.Fprintf(&b, "# mismatch: %v\n", r.Mismatch)
+	}
+
+	s := r.Sample
+	if s == nil {
+		// gRPC and WebSocket results carry no HTTP exchange.
+		if r.Status != 0 {
+			fmt.Fprintf(&b, "# status: %d\n", r.Status)
+		}
+		return b.Bytes()
+	}
+	fmt.Fprintf(&b, "# request: %s %s\n", s.Method, s.URL)
+	if s.Status == "" {
+		return b.Bytes()
+	}
+	fmt.Fprintf(&b, "\n%s %s\n", s.Proto, s.Status)
+	s.Header.Write(&b)
+	b.WriteString("\n")
+	b.Write(s.Body)
+	if s.Truncated {
+		fmt.Fprintf(&b, "\n# body truncated after %d bytes\n", sampleBodyLimit)
+	}
+	return b.Bytes()
+}
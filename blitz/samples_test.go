@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorSaver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok" {
+			return
+		}
+		w.Header().Set("X-Reason", "quota")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(strings.Repeat("x", sampleBodyLimit+10)))
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(t.TempDir(), "errors")
+	saver, err := newErrorSaver(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &requester{client: srv.Client(), saver: saver}
+	ok := &requestTemplate{Name: "ok", Method: http.MethodGet, URL: srv.URL + "/ok"}
+	limited := &requestTemplate{Name: "limited", Method: http.MethodGet, URL: srv.URL + "/limited"}
+
+	ctx := context.Background()
+	if res := r.makeRequest(ctx, ok, nil); res.Sample != nil {
+		t.Error("successful response should not be sampled")
+	}
+	for range 3 {
+		res := r.makeRequest(ctx, limited, nil)
+		if res.Bytes != sampleBodyLimit+10 {
+			t.Errorf("Bytes = %d, want the full body length", res.Bytes)
+		}
+		if err := saver.Save(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 2 {
+		t.Fatalf("saved %d files, want the limit of 2", len(files))
+	}
+	if saver.wants() {
+		t.Error("saver should stop capturing once full")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "error-0001.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"# step: limited",
+		"# failure: HTTP 4xx",
+		"# request: GET " + srv.URL + "/limited",
+		"HTTP/1.1 429 Too Many Requests",
+		"X-Reason: quota",
+		"# body truncated after 65536 bytes",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("sample missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scenario is the ordered list of steps a virtual user executes for
+// each job. Plain single-URL runs are a scenario with one step.
+type scenario struct {
+	Steps []step
+}
+
+// step is one request in a scenario, followed by an optional pause.
+type step struct {
+	Tmpl  *requestTemplate
+	Think time.Duration
+}
+
+// singleStep wraps a template as a one-step scenario.
+func singleStep(tmpl *requestTemplate) *scenario {
+	return &scenario{Steps: []step{{Tmpl: tmpl}}}
+}
+
+// scenarioFile is the on-disk YAML/JSON scenario format.
+type scenarioFile struct {
+	BaseURL string         `yaml:"base_url"`
+	Steps   []scenarioStep `yaml:"steps"`
+}
+
+type scenarioStep struct {
+	Name     string            `yaml:"name"`
+	Method   string            `yaml:"method"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	Body     string            `yaml:"body"`
+	BodyFile string            `yaml:"body_file"`
+	Think    string            `yaml:"think"`
+	// Capture maps a variable name to where its value comes from in
+	// the response: "header:NAME" or a JSON path like $.token.
+	Capture map[string]string `yaml:"capture"`
+}
+
+// loadScenario reads a scenario from path. Relative step URLs are
+// resolved against the file's base_url, falling back to baseURL.
+// Headers in common are added to every step unless the step sets them.
+func loadScenario(path, baseURL string, common http.Header) (*scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats.
+	var file scenarioFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing scenario %s: %w", path, err)
+	}
+	if len(file.Steps) == 0 {
+		return nil, fmt.Errorf("scenario %s has no steps", path)
+	}
+	if file.BaseURL != "" {
+		baseURL = file.BaseURL
+	}
+
+	sc := &scenario{}
+	for i, s := range file.Steps {
+		st, err := s.build(baseURL, common)
+		if err != nil {
+			return nil, fmt.Errorf("scenario step %d: %w", i+1, err)
+		}
+		sc.Steps = append(sc.Steps, st)
+	}
+	return sc, nil
+}
+
+// build converts a file step into a runnable step.
+func (s scenarioStep) build(baseURL string, common http.Header) (step, error) {
+	target, err := resolveURL(baseURL, s.URL)
+	if err != nil {
+		return step{}, err
+	}
+
+	body, err := loadBody(s.Body, s.BodyFile)
+	if err != nil {
+		return step{}, err
+	}
+
+	header := common.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	for k, v := range s.Headers {
+		header.Set(k, v)
+	}
+
+	method := strings.ToUpper(s.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	name := s.Name
+	if name == "" {
+		name = method + " " + s.URL
+	}
+
+	var think time.Duration
+	if s.Think != "" {
+		think, err = time.ParseDuration(s.Think)
+		if err != nil {
+			return step{}, fmt.Errorf("invalid think time %q: %w", s.Think, err)
+		}
+	}
+
+	var captures []*capture
+	for _, key := range slices.Sorted(maps.Keys(s.Capture)) {
+		c, err := parseCapture(key, s.Capture[key])
+		if err != nil {
+			return step{}, err
+		}
+		captures = append(captures, c)
+	}
+
+	return step{
+		Tmpl: &requestTemplate{
+			Name:    name,
+			Method:  method,
+			URL:     target,
+			Header:  header,
+			Body:    body,
+			Capture: captures,
+		},
+		Think: think,
+	}, nil
+}
+
+// resolveURL resolves ref against base. Absolute refs are returned as-is.
+func resolveURL(base, ref string) (string, error) {
+	if ref == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if u.IsAbs() {
+		return ref, nil
+	}
+	if base == "" {
+		return "", fmt.Errorf("relative url %q needs base_url or -url", ref)
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(u).String(), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadScenario(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flow.yaml")
+	data := `
+base_url: http://api.test
+steps:
+  - name: login
+    method: post
+    url: /login
+    headers:
+      Content-Type: application/json
+    body: '{"user":"a"}'
+    think: 250ms
+  - url: https://other.test/items
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	common := http.Header{"X-Common": {"1"}}
+	sc, err := loadScenario(path, "", common)
+	if err != nil {
+		t.Fatalf("loadScenario() error = %v", err)
+	}
+	if len(sc.Steps) != 2 {
+		t.Fatalf("got %d steps, want 2", len(sc.Steps))
+	}
+
+	login := sc.Steps[0]
+	if login.Tmpl.Name != "login" || login.Tmpl.Method != "POST" {
+		t.Errorf("login step = %s %s, want login POST", login.Tmpl.Name, login.Tmpl.Method)
+	}
+	if login.Tmpl.URL != "http://api.test/login" {
+		t.Errorf("login URL = %q, want http://api.test/login", login.Tmpl.URL)
+	}
+	if login.Tmpl.Header.Get("Content-Type") != "application/json" || login.Tmpl.Header.Get("X-Common") != "1" {
+		t.Errorf("login headers = %v, want step and common headers", login.Tmpl.Header)
+	}
+	if login.Think != 250*time.Millisecond {
+		t.Errorf("login think = %v, want 250ms", login.Think)
+	}
+
+	items := sc.Steps[1]
+	if items.Tmpl.Name != "GET https://other.test/items" {
+		t.Errorf("default step name = %q", items.Tmpl.Name)
+	}
+}
+
+func TestLoadScenarioJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.json")
+	data := `{"steps": [{"url": "/health"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sc, err := loadScenario(path, "http://base.test", nil)
+	if err != nil {
+		t.Fatalf("loadScenario() error = %v", err)
+	}
+	if got := sc.Steps[0].Tmpl.URL; got != "http://base.test/health" {
+		t.Errorf("URL = %q, want http://base.test/health", got)
+	}
+}
+
+func TestLoadScenarioRelativeWithoutBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flow.yaml")
+	if err := os.WriteFile(path, []byte("steps:\n  - url: /x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadScenario(path, "", nil); err == nil {
+		t.Error("expected error for relative URL without a base")
+	}
+}
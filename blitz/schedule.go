@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/loadtest"
+)
+
+// The load profile and the jobs it paces come from gokit/loadtest.
+type (
+	schedule = loadtest.Schedule
+	stage    = loadtest.Stage
+	burst    = loadtest.Burst
+	job      = loadtest.Job
+)
+
+// rateFlag is the -rate flag, in requests per second. It takes
+// fractions such as 0.5 and, for slow drip tests of quota-limited APIs,
+// a count per second, minute or hour such as 5/s, 120/m or 30/h.
+type rateFlag float64
+
+func (r *rateFlag) String() string {
+	return strconv.FormatFloat(float64(*r), 'g', -1, 64)
+}
+
+func (r *rateFlag) Set(s string) error {
+	count, unit, perUnit := strings.Cut(strings.TrimSpace(s), "/")
+	rate, err := strconv.ParseFloat(strings.TrimSpace(count), 64)
+	if err != nil || rate < 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+		return fmt.Errorf("invalid rate %q, expected requests per second or a count per unit such as 120/m", s)
+	}
+	if perUnit {
+		switch strings.TrimSpace(unit) {
+		case "s":
+		case "m":
+			rate /= 60
+		case "h":
+			rate /= 3600
+		default:
+			return fmt.Errorf("invalid rate %q: unit must be s, m or h", s)
+		}
+	}
+	*r = rateFlag(rate)
+	return nil
+}
+
+// parseBurst parses a -burst value such as "500@10s".
+func parseBurst(spec string, length time.Duration) (burst, error) {
+	countStr, everyStr, ok := strings.Cut(spec, "@")
+	if !ok {
+		return burst{}, fmt.Errorf("invalid -burst %q, expected 'count@interval' such as 500@10s", spec)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil || count <= 0 {
+		return burst{}, fmt.Errorf("invalid -burst count in %q", spec)
+	}
+	every, err := time.ParseDuration(strings.TrimSpace(everyStr))
+	if err != nil || every <= 0 {
+		return burst{}, fmt.Errorf("invalid -burst interval in %q", spec)
+	}
+	if length <= 0 || length > every {
+		return burst{}, fmt.Errorf("-burst-length must be positive and at most the -burst interval (%s)", every)
+	}
+	return burst{Count: count, Every: every, Length: length}, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBurst(t *testing.T) {
+	b, err := parseBurst("500@10s", time.Second)
+	if err != nil {
+		t.Fatalf("parseBurst() error = %v", err)
+	}
+	if want := (burst{Count: 500, Every: 10 * time.Second, Length: time.Second}); b != want {
+		t.Errorf("parseBurst() = %+v, want %+v", b, want)
+	}
+	for _, spec := range []string{"500", "0@10s", "x@10s", "500@", "500@-1s"} {
+		if _, err := parseBurst(spec, time.Second); err == nil {
+			t.Errorf("parseBurst(%q) expected error, got nil", spec)
+		}
+	}
+	if _, err := parseBurst("500@10s", 20*time.Second); err == nil {
+		t.Error("parseBurst accepted a length longer than the interval")
+	}
+}
+
+func TestRateFlag(t *testing.T) {
+	for in, want := range map[string]float64{
+		"10":    10,
+		"0.5":   0.5,
+		"5/s":   5,
+		"120/m": 2,
+		"30 /h": 30.0 / 3600,
+		"0":     0,
+	} {
+		var r rateFlag
+		if err := r.Set(in); err != nil || float64(r) != want {
+			t.Errorf("Set(%q) = %v, %v; want %v", in, float64(r), err, want)
+		}
+	}
+	for _, in := range []string{"", "-1", "fast", "10/d", "1/", "NaN", "Inf"} {
+		var r rateFlag
+		if err := r.Set(in); err == nil {
+			t.Errorf("Set(%q) succeeded, want an error", in)
+		}
+	}
+
+	// Agents are sent the flag's String form, which must parse back to
+	// the same rate.
+	r := rateFlag(1.0 / 3600)
+	var back rateFlag
+	if err := back.Set(r.String()); err != nil || back != r {
+		t.Errorf("String() = %q parses back as %v, %v", r.String(), float64(back), err)
+	}
+}
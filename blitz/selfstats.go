@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	rtmetrics "runtime/metrics"
+	"sync"
+	"time"
+)
+
+// SelfStats is blitz's own resource use over a run, reported with
+// -selfstats so a saturated load generator can be told apart from a
+// slow target.
+type SelfStats struct {
+	CPU            time.Duration // user and system CPU time; 0 where the platform cannot report it
+	CPUCores       float64       // average cores kept busy, CPU over the wall time
+	MaxProcs       int           // GOMAXPROCS: the cores blitz could use
+	PeakHeap       uint64        // most bytes of live heap objects seen
+	PeakMemory     uint64        // most memory mapped from the OS by the Go runtime
+	PeakGoroutines int
+	GCCycles       uint32
+	GCPause        time.Duration // total stop-the-world pause time
+}
+
+// Busy is the fraction of the available cores blitz used.
+func (s SelfStats) Busy() float64 {
+	if s.MaxProcs == 0 {
+		return 0
+	}
+	return s.CPUCores / float64(s.MaxProcs)
+}
+
+// Saturated reports whether blitz used most of the CPU it had, in
+// which case it may have been unable to send the load it intended and
+// the latency it measured includes its own scheduling delays.
+func (s SelfStats) Saturated() bool {
+	return s.Busy() >= 0.8
+}
+
+// selfStatsRows are the summary rows for s.
+func selfStatsRows(s SelfStats) [][]string {
+	cpu := "unavailable on this platform"
+	if s.CPU > 0 {
+		cpu = fmt.Sprintf("%s (%.2f cores of %d, %.0f%%)", s.CPU.Round(time.Millisecond), s.CPUCores, s.MaxProcs, s.Busy()*100)
+	}
+	return [][]string{
+		{"CPU Time", cpu},
+		{"Peak Heap", formatBytes(int64(s.PeakHeap))},
+		{"Peak Memory", formatBytes(int64(s.PeakMemory))},
+		{"Peak Goroutines", fmt.Sprintf("%d", s.PeakGoroutines)},
+		{"GC", fmt.Sprintf("%d cycles, %s paused", s.GCCycles, s.GCPause.Round(time.Microsecond))},
+	}
+}
+
+// selfStatsWarning is the note shown when blitz itself was the
+// bottleneck.
+const selfStatsWarning = "blitz used most of its CPU; the load generator, not the target, may have limited these results"
+
+// selfSampleInterval is how often the peaks are sampled.
+const selfSampleInterval = 100 * time.Millisecond
+
+// selfSampler tracks blitz's resource use from start until Stop.
+type selfSampler struct {
+	start    time.Time
+	cpu      time.Duration
+	gcCycles uint32
+	gcPause  uint64
+
+	mu      sync.Mutex
+	stats   SelfStats
+	samples []rtmetrics.Sample
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// startSelfStats begins sampling; Stop ends it and returns the stats.
+func startSelfStats() *selfSampler {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	s := &selfSampler{
+		start:    time.Now(),
+		cpu:      processCPU(),
+		gcCycles: mem.NumGC,
+		gcPause:  mem.PauseTotalNs,
+		samples: []rtmetrics.Sample{
+			{Name: "/memory/classes/heap/objects:bytes"},
+			{Name: "/memory/classes/total:bytes"},
+			{Name: "/sched/goroutines:goroutines"},
+		},
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	s.sample()
+	go s.loop()
+	return s
+}
+
+func (s *selfSampler) loop() {
+	defer close(s.done)
+	ticker := time.NewTicker(selfSampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sample records new peaks.
+func (s *selfSampler) sample() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rtmetrics.Read(s.samples)
+	s.stats.PeakHeap = max(s.stats.PeakHeap, s.samples[0].Value.Uint64())
+	s.stats.PeakMemory = max(s.stats.PeakMemory, s.samples[1].Value.Uint64())
+	s.stats.PeakGoroutines = max(s.stats.PeakGoroutines, int(s.samples[2].Value.Uint64()))
+}
+
+// Stop ends sampling and returns the usage since startSelfStats.
+func (s *selfSampler) Stop() SelfStats {
+	close(s.stop)
+	<-s.done
+	s.sample()
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	wall := time.Since(s.start)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.stats
+	st.MaxProcs = runtime.GOMAXPROCS(0)
+	if cpu := processCPU(); cpu > 0 {
+		st.CPU = cpu - s.cpu
+		if wall > 0 {
+			st.CPUCores = st.CPU.Seconds() / wall.Seconds()
+		}
+	}
+	st.GCCycles = mem.NumGC - s.gcCycles
+	st.GCPause = time.Duration(mem.PauseTotalNs - s.gcPause)
+	return st
+}
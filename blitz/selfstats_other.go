@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPU reports 0: the process's CPU time is read with getrusage,
+// which this platform lacks.
+func processCPU() time.Duration {
+	return 0
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSelfSampler(t *testing.T) {
+	s := startSelfStats()
+
+	const n = 50
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+		}()
+	}
+	time.Sleep(2 * selfSampleInterval)
+	close(release)
+	wg.Wait()
+	var sink [][]byte
+	for end := time.Now().Add(50 * time.Millisecond); time.Now().Before(end); {
+		sink = append(sink, make([]byte, 1024))
+	}
+	runtime.GC()
+	_ = sink
+
+	st := s.Stop()
+	if st.PeakGoroutines < n {
+		t.Errorf("PeakGoroutines = %d, want at least %d", st.PeakGoroutines, n)
+	}
+	if st.PeakHeap == 0 || st.PeakMemory < st.PeakHeap {
+		t.Errorf("PeakHeap = %d, PeakMemory = %d; want a heap within the memory", st.PeakHeap, st.PeakMemory)
+	}
+	if st.GCCycles == 0 {
+		t.Error("GCCycles = 0 after a forced collection")
+	}
+	if st.MaxProcs != runtime.GOMAXPROCS(0) {
+		t.Errorf("MaxProcs = %d", st.MaxProcs)
+	}
+	if processCPU() > 0 && st.CPU <= 0 {
+		t.Errorf("CPU = %v after a busy loop", st.CPU)
+	}
+}
+
+func TestSelfStatsReport(t *testing.T) {
+	busy := &SelfStats{CPU: 3 * time.Second, CPUCores: 3.6, MaxProcs: 4, PeakGoroutines: 12}
+	if !busy.Saturated() {
+		t.Errorf("Busy() = %.2f should count as saturated", busy.Busy())
+	}
+
+	var text, md bytes.Buffer
+	renderText(&text, Summary{Total: 1, Self: busy})
+	renderMarkdown(&md, Summary{Total: 1, Self: busy})
+	for name, out := range map[string]string{"text": text.String(), "markdown": md.String()} {
+		if !strings.Contains(out, "3.60 cores of 4, 90%") || !strings.Contains(out, selfStatsWarning) {
+			t.Errorf("%s report lacks the CPU row or saturation warning:\n%s", name, out)
+		}
+	}
+
+	idle := &SelfStats{CPU: time.Second, CPUCores: 0.5, MaxProcs: 4}
+	text.Reset()
+	renderText(&text, Summary{Self: idle})
+	if strings.Contains(text.String(), selfStatsWarning) {
+		t.Error("an idle load generator should not be flagged")
+	}
+	if got := newJSONReport(Summary{Self: busy}).Self; got == nil || !got.Saturated || got.CPUMs != 3000 {
+		t.Errorf("JSON selfstats = %+v", got)
+	}
+}
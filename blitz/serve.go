@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// serveOptions shape the responses of the "blitz serve" test target.
+// Each can be overridden per request with a query parameter of the same
+// name, e.g. "/?latency=50ms&error-rate=0.1".
+type serveOptions struct {
+	Latency   time.Duration // base response delay
+	Jitter    time.Duration // delay varies uniformly by up to this much either way
+	ErrorRate float64       // fraction of requests answered with ErrorCode
+	ErrorCode int
+	Size      int // response body bytes
+}
+
+// runServe implements "blitz serve": a local target to practice on and
+// to test blitz itself against.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("blitz serve", flag.ExitOnError)
+	listen := fs.String("listen", ":8080", "Address to serve on")
+	var opts serveOptions
+	fs.DurationVar(&opts.Latency, "latency", 0, "Delay every response by this long")
+	fs.DurationVar(&opts.Jitter, "jitter", 0, "Vary the delay uniformly by up to this much either way")
+	fs.Float64Var(&opts.ErrorRate, "error-rate", 0, "Fraction of requests to fail (e.g. 0.05)")
+	fs.IntVar(&opts.ErrorCode, "error-code", http.StatusInternalServerError, "Status code for failed requests")
+	fs.IntVar(&opts.Size, "size", 64, "Response body size in bytes")
+	fs.Parse(args)
+
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+		fs.Usage()
+		return exitError
+	}
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "Serving test target on http://%s\n", ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Handler: newTargetHandler(opts)}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func (o serveOptions) validate() error {
+	switch {
+	case o.Latency < 0 || o.Jitter < 0:
+		return errors.New("-latency and -jitter must not be negative")
+	case o.ErrorRate < 0 || o.ErrorRate > 1:
+		return errors.New("-error-rate must be a fraction between 0 and 1")
+	case o.ErrorCode < 100 || o.ErrorCode > 999:
+		return fmt.Errorf("invalid -error-code %d", o.ErrorCode)
+	case o.Size < 0:
+		return errors.New("-size must not be negative")
+	}
+	return nil
+}
+
+// withQuery returns o with any overrides from the request's query.
+func (o serveOptions) withQuery(q url.Values) (serveOptions, error) {
+	var err error
+	duration := func(name string, d *time.Duration) {
+		if v := q.Get(name); v != "" && err == nil {
+			*d, err = time.ParseDuration(v)
+		}
+	}
+	integer := func(name string, n *int) {
+		if v := q.Get(name); v != "" && err == nil {
+			*n, err = strconv.Atoi(v)
+		}
+	}
+	duration("latency", &o.Latency)
+	duration("jitter", &o.Jitter)
+	integer("error-code", &o.ErrorCode)
+	integer("size", &o.Size)
+	if v := q.Get("error-rate"); v != "" && err == nil {
+		o.ErrorRate, err = strconv.ParseFloat(v, 64)
+	}
+	if err != nil {
+		return o, err
+	}
+	return o, o.validate()
+}
+
+// delay picks this response's latency.
+func (o serveOptions) delay() time.Duration {
+	d := o.Latency
+	if o.Jitter > 0 {
+		d += time.Duration(rand.Int64N(int64(2*o.Jitter)+1)) - o.Jitter
+	}
+	return max(d, 0)
+}
+
+// newTargetHandler answers every request according to opts.
+func newTargetHandler(opts serveOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o, err := opts.withQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		timer := time.NewTimer(o.delay())
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return
+		}
+
+		if o.ErrorRate > 0 && rand.Float64() < o.ErrorRate {
+			http.Error(w, http.StatusText(o.ErrorCode), o.ErrorCode)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Length", strconv.Itoa(o.Size))
+		w.Write(bytes.Repeat([]byte("x"), o.Size))
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTargetHandler(t *testing.T) {
+	srv := httptest.NewServer(newTargetHandler(serveOptions{
+		Latency:   20 * time.Millisecond,
+		ErrorCode: http.StatusServiceUnavailable,
+		Size:      100,
+	}))
+	defer srv.Close()
+
+	get := func(query string) (*http.Response, []byte, time.Duration) {
+		t.Helper()
+		start := time.Now()
+		resp, err := http.Get(srv.URL + "/" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body, time.Since(start)
+	}
+
+	resp, body, took := get("")
+	if resp.StatusCode != http.StatusOK || len(body) != 100 {
+		t.Errorf("default: status %d, %d bytes; want 200, 100 bytes", resp.StatusCode, len(body))
+	}
+	if took < 20*time.Millisecond {
+		t.Errorf("default: took %v, want at least 20ms", took)
+	}
+
+	resp, body, _ = get("?size=5&latency=0s")
+	if len(body) != 5 {
+		t.Errorf("size override: %d bytes, want 5", len(body))
+	}
+
+	resp, _, _ = get("?error-rate=1&latency=0s")
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("error-rate=1: status %d, want 503", resp.StatusCode)
+	}
+
+	resp, _, _ = get("?error-rate=2")
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("invalid override: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServeDelayJitter(t *testing.T) {
+	o := serveOptions{Latency: 10 * time.Millisecond, Jitter: 5 * time.Millisecond}
+	for range 100 {
+		if d := o.delay(); d < 5*time.Millisecond || d > 15*time.Millisecond {
+			t.Fatalf("delay %v outside [5ms, 15ms]", d)
+		}
+	}
+	o = serveOptions{Latency: time.Millisecond, Jitter: 10 * time.Millisecond}
+	for range 100 {
+		if d := o.delay(); d < 0 {
+			t.Fatalf("delay %v is negative", d)
+		}
+	}
+}
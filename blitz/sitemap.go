@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sitemapLimit caps the URLs a -sitemap run spreads its load across,
+// matching the most one sitemap file may list.
+const sitemapLimit = 50000
+
+// sitemapMaxBytes is the protocol's size limit for one uncompressed
+// sitemap, and so how much of each file is read.
+const sitemapMaxBytes = 50 << 20
+
+// sitemapDoc is either a <urlset> of pages or a <sitemapindex> of
+// further sitemaps; only the <loc> of each entry is used.
+type sitemapDoc struct {
+	Pages    []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// loadSitemap replaces c.URLs with the pages listed by the -sitemap.
+// It is fetched with the run's TLS and proxy options, so it works
+// against the same targets the run does.
+func (c *config) loadSitemap(ctx context.Context) error {
+	switch {
+	case c.URLFile != "":
+		return usageError{errors.New("-sitemap and -url-file are mutually exclusive")}
+	case len(c.URLs) > 0 || c.Scenario != "" || c.HAR != "" || c.GRPC.Method != "":
+		return usageError{errors.New("-sitemap replaces -url, -scenario, -har and -grpc")}
+	}
+	transport, err := newTransport(c.Transport)
+	if err != nil {
+		return err
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{Transport: transport, Timeout: c.Timeout}
+
+	pages, err := fetchSitemap(ctx, client, c.Sitemap)
+	if err != nil {
+		return fmt.Errorf("reading sitemap: %w", err)
+	}
+	c.URLs = make(urlList, len(pages))
+	for i, page := range pages {
+		c.URLs[i] = weightedURL{URL: page, Weight: 1}
+	}
+	c.listedFrom = "sitemap"
+	return nil
+}
+
+// fetchSitemap returns the pages listed by the sitemap at loc. A
+// sitemap index is followed one level down, as the protocol allows,
+// stopping once sitemapLimit pages are known.
+func fetchSitemap(ctx context.Context, client *http.Client, loc string) ([]string, error) {
+	doc, err := getSitemap(ctx, client, loc)
+	if err != nil {
+		return nil, err
+	}
+	pages := doc.Pages
+	for _, child := range doc.Sitemaps {
+		if len(pages) >= sitemapLimit {
+			break
+		}
+		sub, err := getSitemap(ctx, client, child)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, sub.Pages...)
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%s lists no URLs", loc)
+	}
+	return pages[:min(len(pages), sitemapLimit)], nil
+}
+
+// getSitemap fetches and parses one sitemap file. Files are often
+// published gzipped (sitemap.xml.gz) without a Content-Encoding, so a
+// gzip body is recognized by its magic number.
+func getSitemap(ctx context.Context, client *http.Client, loc string) (*sitemapDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", loc, resp.Status)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", loc, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(r, sitemapMaxBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", loc, err)
+	}
+	trim := func(locs []string) {
+		for i, l := range locs {
+			locs[i] = strings.TrimSpace(l)
+		}
+	}
+	trim(doc.Pages)
+	trim(doc.Sitemaps)
+	return &doc, nil
+}
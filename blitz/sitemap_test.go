@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchSitemap(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/posts.xml.gz</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/</loc><priority>1.0</priority></url>
+  <url><loc>
+    %[1]s/about
+  </loc></url>
+</urlset>`, srv.URL)
+		case "/posts.xml.gz":
+			var b bytes.Buffer
+			gz := gzip.NewWriter(&b)
+			fmt.Fprintf(gz, `<urlset><url><loc>%s/posts/1</loc></url></urlset>`, srv.URL)
+			gz.Close()
+			w.Write(b.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	pages, err := fetchSitemap(context.Background(), srv.Client(), srv.URL+"/sitemap.xml")
+	if err != nil {
+		t.Fatalf("fetchSitemap() error = %v", err)
+	}
+	want := []string{srv.URL + "/", srv.URL + "/about", srv.URL + "/posts/1"}
+	if fmt.Sprint(pages) != fmt.Sprint(want) {
+		t.Errorf("fetchSitemap() = %v, want %v", pages, want)
+	}
+
+	if _, err := fetchSitemap(context.Background(), srv.Client(), srv.URL+"/missing.xml"); err == nil {
+		t.Error("a missing sitemap should be an error")
+	}
+}
+
+func TestLoadSitemapConflicts(t *testing.T) {
+	for name, cfg := range map[string]config{
+		"url-file": {Sitemap: "http://h/sitemap.xml", URLFile: "-"},
+		"url":      {Sitemap: "http://h/sitemap.xml", URLs: urlList{{URL: "http://h/"}}},
+	} {
+		if err := cfg.loadSitemap(context.Background()); err == nil {
+			t.Errorf("%s: loadSitemap() succeeded, want an error", name)
+		}
+	}
+}
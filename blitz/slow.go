@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/cli"
+)
+
+// slowLine is the -slow-file form of a request over -slow-threshold:
+// its record line plus where the time went.
+type slowLine struct {
+	recordLine
+	Retries    int     `json:"retries,omitempty"`
+	DNSMs      float64 `json:"dns_ms"`
+	ConnectMs  float64 `json:"connect_ms"`
+	TLSMs      float64 `json:"tls_ms"`
+	TTFBMs     float64 `json:"ttfb_ms"`
+	DownloadMs float64 `json:"download_ms"`
+	Reused     bool    `json:"reused"`
+	Family     string  `json:"family,omitempty"`
+}
+
+// slowLogger writes the timing breakdown of the first requests slower
+// than a threshold, so the tail of the latency distribution can be
+// traced to DNS, connecting, the server or the transfer. Entries go to
+// a file as NDJSON, or to stderr as one line each. Log is called from
+// the collecting goroutine only.
+type slowLogger struct {
+	threshold time.Duration
+	limit     int
+	logged    int
+	w         io.Writer
+	file      *os.File // nil when logging to stderr
+}
+
+func newSlowLogger(threshold time.Duration, limit int, path string) (*slowLogger, error) {
+	l := &slowLogger{threshold: threshold, limit: limit, w: os.Stderr}
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		l.w, l.file = f, f
+	}
+	return l, nil
+}
+
+// Log writes r if it took longer than the threshold and fewer than
+// limit requests have been logged so far.
+func (l *slowLogger) Log(r Result) error {
+	if r.Latency <= l.threshold || l.logged >= l.limit {
+		return nil
+	}
+	l.logged++
+	if l.file == nil {
+		fmt.Fprintln(l.w, cli.Warning("Slow request: ")+formatSlow(r))
+		if l.logged == l.limit {
+			fmt.Fprintln(l.w, cli.Colorize(cli.Dim, fmt.Sprintf("(-slow-max reached; later requests over %s are not logged)", l.threshold)))
+		}
+		return nil
+	}
+	return json.NewEncoder(l.w).Encode(newSlowLine(r))
+}
+
+// Close closes the -slow-file, if any.
+func (l *slowLogger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func newSlowLine(r Result) slowLine {
+	t := r.Timing
+	return slowLine{
+		recordLine: newRecordLine(r),
+		Retries:    r.Retries,
+		DNSMs:      ms(t.DNS),
+		ConnectMs:  ms(t.Connect),
+		TLSMs:      ms(t.TLS),
+		TTFBMs:     ms(t.TTFB),
+		DownloadMs: ms(t.Download),
+		Reused:     t.Reused,
+		Family:     t.Family,
+	}
+}
+
+// formatSlow renders a slow result as a single line of key=value pairs.
+func formatSlow(r Result) string {
+	round := func(d time.Duration) string { return d.Round(time.Microsecond).String() }
+	t := r.Timing
+	fields := []string{"latency=" + round(r.Latency)}
+	if r.Step != "" {
+		fields = append(fields, fmt.Sprintf("step=%q", r.Step))
+	}
+	if r.Status != 0 {
+		fields = append(fields, fmt.Sprintf("status=%d", r.Status))
+	}
+	fields = append(fields,
+		"dns="+round(t.DNS),
+		"connect="+round(t.Connect),
+		"tls="+round(t.TLS),
+		"ttfb="+round(t.TTFB),
+		"download="+round(t.Download),
+		fmt.Sprintf("reused=%t", t.Reused),
+	)
+	if t.Family != "" {
+		fields = append(fields, "family="+t.Family)
+	}
+	if r.Queued > 0 {
+		fields = append(fields, "queued="+round(r.Queued))
+	}
+	if r.Retries > 0 {
+		fields = append(fields, fmt.Sprintf("retries=%d", r.Retries))
+	}
+	if r.RequestID != "" {
+		fields = append(fields, "request_id="+r.RequestID)
+	}
+	if r.Error != nil {
+		fields = append(fields, fmt.Sprintf("error=%q", r.Error.Error()))
+	}
+	fields = append(fields, "time="+r.Timestamp.Format(time.RFC3339Nano))
+	return strings.Join(fields, " ")
+}
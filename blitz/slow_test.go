@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slow.ndjson")
+	l, err := newSlowLogger(100*time.Millisecond, 2, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast := Result{Step: "get", Status: 200, Latency: 50 * time.Millisecond}
+	slow := Result{Step: "get", Status: 200, Latency: 300 * time.Millisecond, Timing: Timing{
+		DNS: 10 * time.Millisecond, Connect: 20 * time.Millisecond, TTFB: 250 * time.Millisecond, Download: 20 * time.Millisecond, Family: "IPv4",
+	}}
+	for _, r := range []Result{fast, slow, fast, slow, slow} {
+		if err := l.Log(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var lines []slowLine
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var line slowLine
+		if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("logged %d requests, want the first 2 slow ones", len(lines))
+	}
+	if got := lines[0]; got.LatencyMs != 300 || got.TTFBMs != 250 || got.DNSMs != 10 || got.Family != "IPv4" {
+		t.Errorf("slow line = %+v, want the full timing breakdown", got)
+	}
+}
+
+func TestFormatSlow(t *testing.T) {
+	r := Result{
+		Step: "GET /search", Status: 504, Latency: 1500 * time.Millisecond, Retries: 1, RequestID: "abc",
+		Timing: Timing{TLS: 40 * time.Millisecond, TTFB: 1400 * time.Millisecond, Reused: true},
+	}
+	got := formatSlow(r)
+	for _, want := range []string{"latency=1.5s", `step="GET /search"`, "status=504", "tls=40ms", "ttfb=1.4s", "reused=true", "retries=1", "request_id=abc"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatSlow() = %q, missing %q", got, want)
+		}
+	}
+}
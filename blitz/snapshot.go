@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// snapshot is one -report-interval entry: the results of a single
+// interval, not the run so far, so a series of them shows the trend.
+type snapshot struct {
+	Time      string      `json:"time"`
+	ElapsedMs float64     `json:"elapsed_ms"`
+	Requests  int         `json:"requests"`
+	RPS       float64     `json:"rps"`
+	Failed    int         `json:"failed"`
+	ErrorRate float64     `json:"error_rate"`
+	Latency   jsonLatency `json:"latency_ms"`
+}
+
+// intervalReporter collects results between ticks of -report-interval
+// and emits a snapshot of each interval, either as NDJSON lines
+// appended to a file or as text lines on the progress display.
+type intervalReporter struct {
+	start time.Time
+	last  time.Time
+	stats *collector
+	enc   *json.Encoder // nil to print to the display instead
+}
+
+func newIntervalReporter(start time.Time, file io.Writer) *intervalReporter {
+	r := &intervalReporter{start: start, last: start, stats: newCollector()}
+	if file != nil {
+		r.enc = json.NewEncoder(file)
+	}
+	return r
+}
+
+func (r *intervalReporter) Add(res Result) {
+	r.stats.Add(res)
+}
+
+// Flush emits the snapshot for the interval ending now and starts the
+// next one. An interval without results is reported too, since a stall
+// is part of the trend.
+func (r *intervalReporter) Flush(now time.Time, prog display) error {
+	s := r.stats.Summary(now.Sub(r.last))
+	snap := snapshot{
+		Time:      now.Format(time.RFC3339),
+		ElapsedMs: ms(now.Sub(r.start)),
+		Requests:  s.Total,
+		RPS:       s.RPS,
+		Failed:    s.Failed,
+		Latency:   newJSONLatency(s.Latency),
+	}
+	if s.Total > 0 {
+		snap.ErrorRate = float64(s.Failed) / float64(s.Total)
+	}
+	r.stats = newCollector()
+	r.last = now
+
+	if r.enc != nil {
+		return r.enc.Encode(snap)
+	}
+	prog.Println(fmt.Sprintf("[%s] %d requests (%.2f req/s) | failed %d (%s) | p50 %s p95 %s p99 %s max %s",
+		now.Sub(r.start).Round(time.Second), s.Total, s.RPS, s.Failed, percentOf(s.Failed, s.Total),
+		s.Latency.P50.Round(time.Millisecond), s.Latency.P95.Round(time.Millisecond),
+		s.Latency.P99.Round(time.Millisecond), s.Latency.Max.Round(time.Millisecond)))
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lineDisplay records Println output.
+type lineDisplay struct {
+	silentDisplay
+	lines []string
+}
+
+func (d *lineDisplay) Println(line string) { d.lines = append(d.lines, line) }
+
+func TestIntervalReporter(t *testing.T) {
+	start := time.Now()
+	var buf bytes.Buffer
+	r := newIntervalReporter(start, &buf)
+	r.Add(Result{Status: 200, Latency: 10 * time.Millisecond})
+	r.Add(Result{Status: 500, Latency: 30 * time.Millisecond})
+	if err := r.Flush(start.Add(2*time.Second), silentDisplay{}); err != nil {
+		t.Fatal(err)
+	}
+	// The next interval starts empty.
+	r.Add(Result{Status: 200, Latency: 10 * time.Millisecond})
+	if err := r.Flush(start.Add(4*time.Second), silentDisplay{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var snaps []snapshot
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var s snapshot
+		if err := dec.Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		snaps = append(snaps, s)
+	}
+	if len(snaps) != 2 {
+		t.Fatalf("got %d snapshots, want 2", len(snaps))
+	}
+	if s := snaps[0]; s.Requests != 2 || s.Failed != 1 || s.RPS != 1 || s.ErrorRate != 0.5 {
+		t.Errorf("first snapshot = %+v", s)
+	}
+	if s := snaps[1]; s.Requests != 1 || s.Failed != 0 || s.ElapsedMs != 4000 {
+		t.Errorf("second snapshot = %+v", s)
+	}
+
+	d := &lineDisplay{}
+	r = newIntervalReporter(start, nil)
+	r.Add(Result{Status: 200, Latency: 10 * time.Millisecond})
+	r.Flush(start.Add(time.Minute), d)
+	if len(d.lines) != 1 || !strings.HasPrefix(d.lines[0], "[1m0s] 1 requests") {
+		t.Errorf("text snapshot = %q", d.lines)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// sparklineWidth caps the columns of the p95 sparkline; longer runs
+// fold several seconds into each column.
+const sparklineWidth = 60
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// p95Sparkline draws the per-second p95 of the timeline as a row of
+// block characters scaled between the lowest and highest values, so a
+// run that degrades shows a rising line. Each column takes the worst
+// p95 of the seconds it covers, so short spikes are not averaged away,
+// and seconds without results are left blank. The caption gives the
+// range and the seconds per column. ok is false for runs under three
+// seconds, too short to show a trend.
+func p95Sparkline(points []TimelinePoint) (line, caption string, ok bool) {
+	if len(points) < 3 {
+		return "", "", false
+	}
+	per := (len(points) + sparklineWidth - 1) / sparklineWidth
+	var cols []time.Duration // -1 for columns without results
+	for i := 0; i < len(points); i += per {
+		col := time.Duration(-1)
+		for _, p := range points[i:min(i+per, len(points))] {
+			if p.Requests > 0 {
+				col = max(col, p.P95)
+			}
+		}
+		cols = append(cols, col)
+	}
+
+	lo, hi := time.Duration(-1), time.Duration(0)
+	for _, c := range cols {
+		if c >= 0 && (lo < 0 || c < lo) {
+			lo = c
+		}
+		hi = max(hi, c)
+	}
+	if lo < 0 {
+		return "", "", false
+	}
+
+	var b strings.Builder
+	for _, c := range cols {
+		switch {
+		case c < 0:
+			b.WriteRune(' ')
+		case hi == lo:
+			b.WriteRune(sparkBlocks[0])
+		default:
+			b.WriteRune(sparkBlocks[int(c-lo)*(len(sparkBlocks)-1)/int(hi-lo)])
+		}
+	}
+	caption = fmt.Sprintf("%s to %s, %s per column",
+		lo.Round(time.Millisecond), hi.Round(time.Millisecond), time.Duration(per)*time.Second)
+	return b.String(), caption, true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestP95Sparkline(t *testing.T) {
+	var points []TimelinePoint
+	for i, p95 := range []int{10, 10, 0, 40, 80} {
+		p := TimelinePoint{Offset: time.Duration(i) * time.Second, P95: time.Duration(p95) * time.Millisecond}
+		if p95 > 0 {
+			p.Requests = 1
+		}
+		points = append(points, p)
+	}
+	line, caption, ok := p95Sparkline(points)
+	if !ok {
+		t.Fatal("p95Sparkline() = not ok, want a line")
+	}
+	if want := "▁▁ ▄█"; line != want {
+		t.Errorf("line = %q, want %q", line, want)
+	}
+	if want := "10ms to 80ms, 1s per column"; caption != want {
+		t.Errorf("caption = %q, want %q", caption, want)
+	}
+
+	if _, _, ok := p95Sparkline(points[:2]); ok {
+		t.Error("p95Sparkline() of a two-second run = ok, want no line")
+	}
+}
+
+func TestP95SparklineFoldsLongRuns(t *testing.T) {
+	points := make([]TimelinePoint, 3*sparklineWidth)
+	for i := range points {
+		points[i] = TimelinePoint{Requests: 1, P95: 10 * time.Millisecond}
+	}
+	points[100].P95 = time.Second // a one-second spike must survive folding
+
+	line, caption, _ := p95Sparkline(points)
+	if n := len([]rune(line)); n != sparklineWidth {
+		t.Errorf("line has %d columns, want %d", n, sparklineWidth)
+	}
+	if want := "█"; string([]rune(line)[100/3]) != want {
+		t.Errorf("column %d = %q, want the spike", 100/3, string([]rune(line)[100/3]))
+	}
+	if want := "10ms to 1s, 3s per column"; caption != want {
+		t.Errorf("caption = %q, want %q", caption, want)
+	}
+}
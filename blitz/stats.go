@@ -0,0 +1,610 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NickDiPreta/gokit/loadtest"
+)
+
+// Summary holds the aggregated outcome of a load test run.
+type Summary struct {
+	Total        int
+	Successful   int
+	Failed       int
+	Errors       int // transport-level errors (no HTTP response)
+	Mismatches   int // responses that failed a body expectation
+	Redirected   int // responses that involved a redirect
+	Retried      int // requests resent at least once after a transient failure
+	Retries      int // total resend attempts
+	Hedged       int // requests that had a -hedge duplicate sent
+	HedgeWins    int // hedged requests where the duplicate completed first
+	Throttled    int // responses asking for a Retry-After wait, with -retry-after; BackedOff is the total waited
+	BackedOff    time.Duration
+	Timeouts     int // requests that failed on -timeout, -request-timeout or a network timeout
+	Duration     time.Duration
+	RPS          float64
+	StatusCodes  map[int]int
+	Protocols    map[string]int // responses per negotiated protocol
+	Families     map[string]int // responses per connection address family
+	ErrorKinds   []ErrorCategory
+	Bytes        ByteStats
+	Latency      LatencyStats
+	Distribution []LatencyBucket // latency histogram, trimmed to the occupied range
+	Timeline     []TimelinePoint // per-second throughput and latency
+	Corrected    *LatencyStats   // latency measured from the scheduled send time; nil for unpaced runs
+	Apdex        *Apdex          // score against -apdex-t; nil without it
+	Timing       TimingStats
+	Endpoints    []EndpointStats // per-target breakdown, in first-seen order
+	ByStatus     []StatusLatency // latency per status code, then transport errors
+	Partial      bool            // run was interrupted before all requests were sent
+	Aborted      string          // why -abort-on-error-rate stopped the run, if it did
+	Assertions   []AssertionResult
+	Comparison   []Delta       // deltas against the -compare baseline
+	MaxSearch    *MaxSearch    // -find-max probes; the rest of the summary is the best probe
+	AB           *ABComparison // -url-a against -url-b
+	Self         *SelfStats    // blitz's own resource use; nil without -selfstats
+}
+
+// Latency is recorded in gokit/loadtest's histogram. LatencyStats.Percentiles
+// holds the -percentiles list, if given; P50-P99 are always set.
+type (
+	histogram    = loadtest.Histogram
+	LatencyStats = loadtest.LatencyStats
+	Quantile     = loadtest.Quantile
+)
+
+// parsePercentiles parses a comma-separated -percentiles list such as
+// "50,90,99.9".
+func parsePercentiles(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ps []float64
+	for _, part := range strings.Split(s, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, expected a number above 0 and at most 100", part)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// LatencyBucket counts requests whose latency fell in [From, To).
+// To is zero for the open-ended last bucket.
+type LatencyBucket struct {
+	From  time.Duration
+	To    time.Duration
+	Count int
+}
+
+// distributionBounds are the edges of the reported latency buckets.
+var distributionBounds = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ErrorCategory counts failures of one kind, with a sample message.
+type ErrorCategory struct {
+	Name    string
+	Count   int
+	Example string
+}
+
+// ByteStats describes response body sizes and data throughput.
+type ByteStats struct {
+	Total      int64   // body bytes received across all responses
+	Min        int64   // smallest body
+	Mean       int64   // average body
+	Max        int64   // largest body
+	Throughput float64 // bytes per second over the run
+	Wire       int64   // body bytes as sent, before decoding; counted with -compression only
+	Encoded    int     // responses that arrived compressed, with -compression
+}
+
+// TimingStats summarizes the per-phase connection timings of a run.
+type TimingStats struct {
+	DNS      PhaseStats
+	Connect  PhaseStats
+	TLS      PhaseStats
+	TTFB     PhaseStats
+	Download PhaseStats
+	Reused   int // requests served on a pooled connection
+}
+
+// PhaseStats describes the distribution of a single timing phase.
+type PhaseStats struct {
+	Mean time.Duration
+	P95  time.Duration
+	Max  time.Duration
+}
+
+// EndpointStats summarizes the results for one target or scenario step.
+type EndpointStats struct {
+	Name       string
+	Total      int
+	Failed     int
+	Errors     int
+	Latency    LatencyStats
+	BodyHashes []BodyHash // distinct bodies, most common first, with -hash-bodies
+}
+
+// BodyHash counts the responses whose bodies hashed to Hash. Status and
+// Bytes are taken from the first such response, since a differing size
+// is often what gives a truncated body away.
+type BodyHash struct {
+	Hash   string
+	Count  int
+	Status int
+	Bytes  int64
+}
+
+// StatusLatency is the latency of the responses with one status code,
+// so fast rejections can be told apart from slow timeouts. Status is
+// statusErrored for requests that failed without a usable response,
+// and 0 for results that carry no status, like WebSocket messages.
+type StatusLatency struct {
+	Status  int
+	Count   int
+	Latency LatencyStats
+}
+
+// failed reports whether r counts against the run's success total.
+func failed(r Result) bool {
+	if r.Error != nil || r.Mismatch != nil {
+		return true
+	}
+	switch r.Check {
+	case statusListed:
+		return false
+	case statusUnlisted:
+		return true
+	}
+	// An unfollowed redirect (-no-follow) is the expected response.
+	if r.Redirected && r.Status >= 300 && r.Status < 400 {
+		return false
+	}
+	// A WebSocket upgrade answers 101; its messages carry no status, and
+	// neither do bare TCP connects.
+	if r.Status == http.StatusSwitchingProtocols || (r.Status == 0 && (r.Proto == "WebSocket" || r.Proto == "TCP")) {
+		return false
+	}
+	return r.Status < 200 || r.Status >= 300
+}
+
+// collector aggregates results incrementally into histograms and
+// counters, so memory use is independent of the number of requests.
+type collector struct {
+	total       int
+	successful  int
+	failed      int
+	errors      int
+	mismatches  int
+	redirected  int
+	retried     int
+	timeouts    int
+	retries     int
+	hedged      int
+	hedgeWins   int
+	throttled   int
+	backedOff   time.Duration
+	reused      int
+	bytes       int64
+	minBytes    int64
+	maxBytes    int64
+	wireBytes   int64
+	encoded     int
+	paced       bool
+	statusCodes map[int]int
+	protocols   map[string]int
+	families    map[string]int
+	categories  map[string]*ErrorCategory
+
+	latency   histogram
+	succeeded histogram // latency of successful requests, for Apdex
+	corrected histogram
+	dns       histogram
+	connect   histogram
+	tls       histogram
+	ttfb      histogram
+	download  histogram
+	timeline  timeline
+
+	endpoints     map[string]*endpointCollector
+	endpointOrder []string
+	byStatus      map[int]*histogram // keyed by status, or statusErrored
+}
+
+// statusErrored keys the latency of requests that ended in an error in
+// collector.byStatus.
+const statusErrored = -1
+
+// endpointCollector aggregates the results of one target or step.
+type endpointCollector struct {
+	total   int
+	failed  int
+	errors  int
+	latency histogram
+	hashes  map[string]*BodyHash // nil until a hashed body arrives
+}
+
+// addHash counts one occurrence of h.
+func (e *endpointCollector) addHash(h BodyHash) {
+	if e.hashes == nil {
+		e.hashes = make(map[string]*BodyHash)
+	}
+	if seen, ok := e.hashes[h.Hash]; ok {
+		seen.Count += h.Count
+		return
+	}
+	e.hashes[h.Hash] = &h
+}
+
+// bodyHashes returns the distinct bodies, most common first.
+func (e *endpointCollector) bodyHashes() []BodyHash {
+	var out []BodyHash
+	for _, h := range e.hashes {
+		out = append(out, *h)
+	}
+	slices.SortFunc(out, func(a, b BodyHash) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Hash, b.Hash))
+	})
+	return out
+}
+
+func newCollector() *collector {
+	return &collector{
+		statusCodes: make(map[int]int),
+		protocols:   make(map[string]int),
+		families:    make(map[string]int),
+		categories:  make(map[string]*ErrorCategory),
+		endpoints:   make(map[string]*endpointCollector),
+		byStatus:    make(map[int]*histogram),
+	}
+}
+
+// Add folds one result into the aggregate.
+// A request counts as successful when it returned a 2xx status without
+// error and its body met any expectations.
+func (c *collector) Add(r Result) {
+	c.total++
+	if r.Error != nil {
+		c.errors++
+	} else {
+		if r.Status != 0 {
+			c.statusCodes[r.Status]++
+		}
+		c.protocols[r.Proto]++
+		if r.Timing.Family != "" {
+			c.families[r.Timing.Family]++
+		}
+	}
+	if r.Mismatch != nil {
+		c.mismatches++
+	}
+	if r.Redirected {
+		c.redirected++
+	}
+	if r.Retries > 0 {
+		c.retried++
+		c.retries += r.Retries
+	}
+	if r.Hedged {
+		c.hedged++
+	}
+	if r.HedgeWon {
+		c.hedgeWins++
+	}
+	if r.RetryAfter > 0 {
+		c.throttled++
+	}
+	c.backedOff += r.BackedOff
+	if failed(r) {
+		c.failed++
+		c.addCategory(r)
+		if r.Error != nil && classifyError(r.Error) == catTimeout {
+			c.timeouts++
+		}
+	} else {
+		c.successful++
+		c.succeeded.Record(r.Latency)
+	}
+
+	c.latency.Record(r.Latency)
+	if r.Error != nil {
+		c.statusHistogram(statusErrored).Record(r.Latency)
+	} else {
+		c.statusHistogram(r.Status).Record(r.Latency)
+	}
+	c.timeline.Record(r.Timestamp, failed(r), r.Latency)
+	c.corrected.Record(r.Latency + r.Queued)
+	c.paced = c.paced || r.Queued > 0
+
+	if r.Error == nil {
+		responses := c.total - c.errors
+		if responses == 1 || r.Bytes < c.minBytes {
+			c.minBytes = r.Bytes
+		}
+		c.maxBytes = max(c.maxBytes, r.Bytes)
+		c.bytes += r.Bytes
+		c.wireBytes += r.WireBytes
+		if r.Encoded {
+			c.encoded++
+		}
+
+		c.dns.Record(r.Timing.DNS)
+		c.connect.Record(r.Timing.Connect)
+		c.tls.Record(r.Timing.TLS)
+		c.ttfb.Record(r.Timing.TTFB)
+		c.download.Record(r.Timing.Download)
+		if r.Timing.Reused {
+			c.reused++
+		}
+	}
+
+	e, ok := c.endpoints[r.Step]
+	if !ok {
+		e = &endpointCollector{}
+		c.endpoints[r.Step] = e
+		c.endpointOrder = append(c.endpointOrder, r.Step)
+	}
+	e.total++
+	if r.Error != nil {
+		e.errors++
+	}
+	if failed(r) {
+		e.failed++
+	}
+	e.latency.Record(r.Latency)
+	if r.BodyHash != "" {
+		e.addHash(BodyHash{Hash: r.BodyHash, Count: 1, Status: r.Status, Bytes: r.Bytes})
+	}
+}
+
+// statusHistogram returns the latency histogram for status, creating
+// it on first use.
+func (c *collector) statusHistogram(status int) *histogram {
+	h, ok := c.byStatus[status]
+	if !ok {
+		h = &histogram{}
+		c.byStatus[status] = h
+	}
+	return h
+}
+
+// addCategory counts a failed result under its error category,
+// keeping the first message seen as an example.
+func (c *collector) addCategory(r Result) {
+	name := classify(r)
+	cat, ok := c.categories[name]
+	if !ok {
+		cat = &ErrorCategory{Name: name}
+		switch {
+		case r.Error != nil:
+			cat.Example = r.Error.Error()
+		case r.Mismatch != nil:
+			cat.Example = r.Mismatch.Error()
+		default:
+			cat.Example = fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status))
+		}
+		c.categories[name] = cat
+	}
+	cat.Count++
+}
+
+// Merge folds everything aggregated by o into c, as if o's results had
+// been added to c directly.
+func (c *collector) Merge(o *collector) {
+	if o.total > o.errors && (c.total == c.errors || o.minBytes < c.minBytes) {
+		c.minBytes = o.minBytes
+	}
+	c.maxBytes = max(c.maxBytes, o.maxBytes)
+	c.bytes += o.bytes
+	c.wireBytes += o.wireBytes
+	c.encoded += o.encoded
+
+	c.total += o.total
+	c.successful += o.successful
+	c.failed += o.failed
+	c.errors += o.errors
+	c.mismatches += o.mismatches
+	c.redirected += o.redirected
+	c.retried += o.retried
+	c.timeouts += o.timeouts
+	c.retries += o.retries
+	c.hedged += o.hedged
+	c.hedgeWins += o.hedgeWins
+	c.throttled += o.throttled
+	c.backedOff += o.backedOff
+	c.reused += o.reused
+	c.paced = c.paced || o.paced
+
+	for code, n := range o.statusCodes {
+		c.statusCodes[code] += n
+	}
+	for proto, n := range o.protocols {
+		c.protocols[proto] += n
+	}
+	for family, n := range o.families {
+		c.families[family] += n
+	}
+	for name, cat := range o.categories {
+		if mine, ok := c.categories[name]; ok {
+			mine.Count += cat.Count
+		} else {
+			merged := *cat
+			c.categories[name] = &merged
+		}
+	}
+
+	c.latency.Merge(&o.latency)
+	c.succeeded.Merge(&o.succeeded)
+	c.corrected.Merge(&o.corrected)
+	c.dns.Merge(&o.dns)
+	c.connect.Merge(&o.connect)
+	c.tls.Merge(&o.tls)
+	c.ttfb.Merge(&o.ttfb)
+	c.download.Merge(&o.download)
+	c.timeline.Merge(&o.timeline)
+
+	for _, name := range o.endpointOrder {
+		oe := o.endpoints[name]
+		e, ok := c.endpoints[name]
+		if !ok {
+			e = &endpointCollector{}
+			c.endpoints[name] = e
+			c.endpointOrder = append(c.endpointOrder, name)
+		}
+		e.total += oe.total
+		e.failed += oe.failed
+		e.errors += oe.errors
+		e.latency.Merge(&oe.latency)
+		for _, h := range oe.hashes {
+			e.addHash(*h)
+		}
+	}
+	for status, h := range o.byStatus {
+		c.statusHistogram(status).Merge(h)
+	}
+}
+
+// Summary produces the report for everything added so far.
+func (c *collector) Summary(duration time.Duration) Summary {
+	s := Summary{
+		Total:       c.total,
+		Successful:  c.successful,
+		Failed:      c.failed,
+		Errors:      c.errors,
+		Mismatches:  c.mismatches,
+		Redirected:  c.redirected,
+		Retried:     c.retried,
+		Timeouts:    c.timeouts,
+		Retries:     c.retries,
+		Hedged:      c.hedged,
+		HedgeWins:   c.hedgeWins,
+		Throttled:   c.throttled,
+		BackedOff:   c.backedOff,
+		Duration:    duration,
+		StatusCodes: maps.Clone(c.statusCodes),
+		Protocols:   maps.Clone(c.protocols),
+		Families:    maps.Clone(c.families),
+		Latency:     c.latency.Stats(),
+		Timing: TimingStats{
+			DNS:      phaseStats(&c.dns),
+			Connect:  phaseStats(&c.connect),
+			TLS:      phaseStats(&c.tls),
+			TTFB:     phaseStats(&c.ttfb),
+			Download: phaseStats(&c.download),
+			Reused:   c.reused,
+		},
+	}
+
+	s.Bytes = ByteStats{Total: c.bytes, Min: c.minBytes, Max: c.maxBytes, Wire: c.wireBytes, Encoded: c.encoded}
+	if responses := int64(c.total - c.errors); responses > 0 {
+		s.Bytes.Mean = c.bytes / responses
+	}
+
+	if duration > 0 {
+		s.RPS = float64(s.Total) / duration.Seconds()
+		s.Bytes.Throughput = float64(c.bytes) / duration.Seconds()
+	}
+
+	for _, cat := range c.categories {
+		s.ErrorKinds = append(s.ErrorKinds, *cat)
+	}
+	slices.SortFunc(s.ErrorKinds, func(a, b ErrorCategory) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Name, b.Name))
+	})
+
+	s.Distribution = distribution(&c.latency, distributionBounds)
+	s.Timeline = c.timeline.Points()
+
+	if c.paced {
+		corrected := c.corrected.Stats()
+		s.Corrected = &corrected
+	}
+
+	for _, name := range c.endpointOrder {
+		e := c.endpoints[name]
+		s.Endpoints = append(s.Endpoints, EndpointStats{
+			Name:       name,
+			Total:      e.total,
+			Failed:     e.failed,
+			Errors:     e.errors,
+			Latency:    e.latency.Stats(),
+			BodyHashes: e.bodyHashes(),
+		})
+	}
+
+	// Status codes in order, then results without one, then errors.
+	statuses := slices.SortedFunc(maps.Keys(c.byStatus), func(a, b int) int {
+		if a > 0 && b > 0 {
+			return cmp.Compare(a, b)
+		}
+		return cmp.Compare(b, a)
+	})
+	for _, status := range statuses {
+		h := c.byStatus[status]
+		s.ByStatus = append(s.ByStatus, StatusLatency{Status: status, Count: int(h.Total), Latency: h.Stats()})
+	}
+
+	return s
+}
+
+// addPercentiles fills in the -percentiles list on s's latency stats.
+func (c *collector) addPercentiles(s *Summary, ps []float64) {
+	s.Latency.Percentiles = c.latency.Quantiles(ps)
+	if s.Corrected != nil {
+		s.Corrected.Percentiles = c.corrected.Quantiles(ps)
+	}
+}
+
+// distribution splits h at bounds and drops the empty buckets before
+// the first and after the last occupied one.
+func distribution(h *histogram, bounds []time.Duration) []LatencyBucket {
+	counts := h.Distribution(bounds)
+	first := slices.IndexFunc(counts, func(c uint64) bool { return c > 0 })
+	if first < 0 {
+		return nil
+	}
+	last := len(counts) - 1
+	for counts[last] == 0 {
+		last--
+	}
+	buckets := make([]LatencyBucket, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		b := LatencyBucket{Count: int(counts[i])}
+		if i > 0 {
+			b.From = bounds[i-1]
+		}
+		if i < len(bounds) {
+			b.To = bounds[i]
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// phaseStats computes mean, p95 and max for one timing phase.
+func phaseStats(h *histogram) PhaseStats {
+	return PhaseStats{
+		Mean: h.Mean(),
+		P95:  h.Percentile(95),
+		Max:  h.Max,
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollectorSummary(t *testing.T) {
+	results := []Result{
+		{Status: 200, Latency: 10 * time.Millisecond},
+		{Status: 200, Latency: 20 * time.Millisecond},
+		{Status: 503, Latency: 30 * time.Millisecond},
+		{Error: errors.New("connection refused"), Latency: 40 * time.Millisecond},
+	}
+
+	c := newCollector()
+	for _, r := range results {
+		c.Add(r)
+	}
+	s := c.Summary(2 * time.Second)
+
+	if s.Total != 4 {
+		t.Errorf("Total = %d, want 4", s.Total)
+	}
+	if s.Successful != 2 {
+		t.Errorf("Successful = %d, want 2", s.Successful)
+	}
+	if s.Failed != 2 {
+		t.Errorf("Failed = %d, want 2", s.Failed)
+	}
+	if s.Errors != 1 {
+		t.Errorf("Errors = %d, want 1", s.Errors)
+	}
+	if s.RPS != 2 {
+		t.Errorf("RPS = %v, want 2", s.RPS)
+	}
+	if s.StatusCodes[200] != 2 || s.StatusCodes[503] != 1 {
+		t.Errorf("StatusCodes = %v, want 200:2 503:1", s.StatusCodes)
+	}
+	if s.Latency.Min != 10*time.Millisecond || s.Latency.Max != 40*time.Millisecond {
+		t.Errorf("Latency min/max = %v/%v, want 10ms/40ms", s.Latency.Min, s.Latency.Max)
+	}
+	if s.Latency.Mean != 25*time.Millisecond {
+		t.Errorf("Latency mean = %v, want 25ms", s.Latency.Mean)
+	}
+}
+
+func TestDistributionTrimsEmptyEdges(t *testing.T) {
+	var h histogram
+	h.Record(30 * time.Millisecond)
+	h.Record(300 * time.Millisecond)
+	got := distribution(&h, distributionBounds)
+	want := []LatencyBucket{
+		{From: 25 * time.Millisecond, To: 50 * time.Millisecond, Count: 1},
+		{From: 50 * time.Millisecond, To: 100 * time.Millisecond},
+		{From: 100 * time.Millisecond, To: 250 * time.Millisecond},
+		{From: 250 * time.Millisecond, To: 500 * time.Millisecond, Count: 1},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("distribution() = %+v, want %+v", got, want)
+	}
+	if got := distribution(&histogram{}, distributionBounds); got != nil {
+		t.Errorf("distribution of empty histogram = %v, want nil", got)
+	}
+}
+
+func TestPercentilesList(t *testing.T) {
+	ps, err := parsePercentiles("50, 90,99.9")
+	if err != nil || !slices.Equal(ps, []float64{50, 90, 99.9}) {
+		t.Fatalf("parsePercentiles() = %v, %v", ps, err)
+	}
+	for _, bad := range []string{"0", "101", "p99", "50,,99"} {
+		if _, err := parsePercentiles(bad); err == nil {
+			t.Errorf("parsePercentiles(%q) should fail", bad)
+		}
+	}
+
+	c := newCollector()
+	for i := 1; i <= 1000; i++ {
+		c.Add(Result{Status: 200, Latency: time.Duration(i) * time.Millisecond})
+	}
+	s := c.Summary(time.Second)
+	c.addPercentiles(&s, ps)
+	if len(s.Latency.Percentiles) != 3 {
+		t.Fatalf("Percentiles = %v, want 3 entries", s.Latency.Percentiles)
+	}
+	if q := s.Latency.Percentiles[2]; q.P != 99.9 || q.Value < 990*time.Millisecond {
+		t.Errorf("p99.9 = %v, want close to 1s", q.Value)
+	}
+
+	j := newJSONLatency(s.Latency)
+	if _, ok := j.Percentiles["p99.9"]; !ok {
+		t.Errorf("JSON percentiles = %v, want a p99.9 key", j.Percentiles)
+	}
+}
+
+func TestLatencyByStatus(t *testing.T) {
+	c := newCollector()
+	c.Add(Result{Status: 503, Latency: 2 * time.Millisecond})
+	c.Add(Result{Status: 200, Latency: 40 * time.Millisecond})
+	c.Add(Result{Status: 200, Latency: 60 * time.Millisecond})
+	c.Add(Result{Error: errors.New("timeout"), Latency: 5 * time.Second})
+
+	other := newCollector()
+	other.Add(Result{Status: 503, Latency: 4 * time.Millisecond})
+	c.Merge(other)
+
+	got := c.Summary(time.Second).ByStatus
+	var statuses []int
+	for _, sl := range got {
+		statuses = append(statuses, sl.Status)
+	}
+	if !slices.Equal(statuses, []int{200, 503, statusErrored}) {
+		t.Fatalf("statuses = %v, want 200, 503, then errors", statuses)
+	}
+	if got[1].Count != 2 || got[1].Latency.Max != 4*time.Millisecond {
+		t.Errorf("503 group = %+v, want 2 fast rejections", got[1])
+	}
+	if got[2].Latency.Max != 5*time.Second {
+		t.Errorf("error group max = %v, want 5s", got[2].Latency.Max)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// statsdPacketSize keeps packets within a typical Ethernet MTU so
+	// they are never fragmented.
+	statsdPacketSize    = 1432
+	statsdFlushInterval = time.Second
+)
+
+// statsdClient emits per-request metrics to a StatsD server over UDP,
+// so dashboards already fed by StatsD or the Datadog agent show load
+// test traffic as it happens. Lines are packed into packets and sent
+// when a packet fills up or on a timer; delivery is fire-and-forget,
+// as usual for StatsD.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+	tags   bool   // append DogStatsD tags
+	runTag string // -tag of the run, added as a tag when set
+
+	mu  sync.Mutex
+	buf []byte
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newStatsdClient(addr, prefix string, tags bool, runTag string) (*statsdClient, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "8125")
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("-statsd: %w", err)
+	}
+	c := &statsdClient{
+		conn:   conn,
+		prefix: strings.TrimSuffix(prefix, "."),
+		tags:   tags,
+		runTag: runTag,
+		buf:    make([]byte, 0, statsdPacketSize),
+		done:   make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.loop()
+	return c, nil
+}
+
+func (c *statsdClient) loop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(statsdFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mu.Lock()
+			c.flush()
+			c.mu.Unlock()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Add emits the metrics for one result: a request counter, a failure
+// counter when it failed, its latency as a timer and the bytes read.
+// Without tags the status is encoded in a counter name instead.
+func (c *statsdClient) Add(r Result) {
+	status := "error"
+	if r.Error == nil {
+		status = strconv.Itoa(r.Status)
+	}
+	var suffix string
+	if c.tags {
+		suffix = "|#status:" + statsdTag(status) + ",step:" + statsdTag(r.Step)
+		if c.runTag != "" {
+			suffix += ",run:" + statsdTag(c.runTag)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.line("requests:1|c" + suffix)
+	if !c.tags {
+		c.line("status." + status + ":1|c")
+	}
+	if failed(r) {
+		c.line("failures:1|c" + suffix)
+	}
+	c.line("latency:" + strconv.FormatFloat(ms(r.Latency), 'f', 3, 64) + "|ms" + suffix)
+	if r.Bytes > 0 {
+		c.line("bytes:" + strconv.FormatInt(r.Bytes, 10) + "|c" + suffix)
+	}
+}
+
+// line appends one metric, sending the packet first if it would not
+// fit. The caller holds c.mu.
+func (c *statsdClient) line(metric string) {
+	if c.prefix != "" {
+		metric = c.prefix + "." + metric
+	}
+	if len(c.buf) > 0 && len(c.buf)+1+len(metric) > statsdPacketSize {
+		c.flush()
+	}
+	if len(c.buf) > 0 {
+		c.buf = append(c.buf, '\n')
+	}
+	c.buf = append(c.buf, metric...)
+}
+
+// flush sends the pending packet. The caller holds c.mu.
+func (c *statsdClient) flush() {
+	if len(c.buf) == 0 {
+		return
+	}
+	c.conn.Write(c.buf) // lost packets are tolerated, like any StatsD client
+	c.buf = c.buf[:0]
+}
+
+// Close sends whatever is pending and releases the socket.
+func (c *statsdClient) Close() error {
+	close(c.done)
+	c.wg.Wait()
+	c.mu.Lock()
+	c.flush()
+	c.mu.Unlock()
+	return c.conn.Close()
+}
+
+// statsdTag replaces the characters that delimit DogStatsD tags.
+var statsdTag = strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_", "\n", "_").Replace
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// statsdListener returns the address of a UDP socket and a func that
+// reads every line received until a short quiet period.
+func statsdListener(t *testing.T) (string, func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	read := func() []string {
+		var lines []string
+		buf := make([]byte, 65536)
+		for {
+			conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return lines
+			}
+			if n > statsdPacketSize {
+				t.Errorf("packet of %d bytes exceeds %d", n, statsdPacketSize)
+			}
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+		}
+	}
+	return conn.LocalAddr().String(), read
+}
+
+func TestStatsdMetrics(t *testing.T) {
+	addr, read := statsdListener(t)
+	c, err := newStatsdClient(addr, "blitz", false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(Result{Step: "get", Status: 200, Bytes: 42, Latency: 12500 * time.Microsecond})
+	c.Add(Result{Step: "get", Error: errors.New("connection refused"), Latency: time.Millisecond})
+	c.Close()
+
+	got := strings.Join(read(), "\n")
+	for _, want := range []string{
+		"blitz.requests:1|c",
+		"blitz.status.200:1|c",
+		"blitz.status.error:1|c",
+		"blitz.failures:1|c",
+		"blitz.latency:12.500|ms",
+		"blitz.bytes:42|c",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestStatsdTags(t *testing.T) {
+	addr, read := statsdListener(t)
+	c, err := newStatsdClient(addr, "lt.", true, "nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Add(Result{Step: "GET /a,b", Status: 503, Latency: time.Millisecond})
+	c.Close()
+
+	lines := read()
+	want := "lt.requests:1|c|#status:503,step:GET_/a_b,run:nightly"
+	if len(lines) == 0 || lines[0] != want {
+		t.Errorf("first line = %q, want %q", lines, want)
+	}
+	for _, l := range lines {
+		if strings.HasPrefix(l, "lt.status.") {
+			t.Errorf("tagged client emitted untagged status counter %q", l)
+		}
+	}
+}
+
+func TestStatsdPacking(t *testing.T) {
+	addr, read := statsdListener(t)
+	c, err := newStatsdClient(addr, "blitz", true, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 200 {
+		c.Add(Result{Step: "get", Status: 200, Latency: time.Millisecond})
+	}
+	c.Close()
+
+	if lines := read(); len(lines) != 400 {
+		t.Errorf("received %d lines, want 400", len(lines))
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// weightedURL is one -url value with its share of the traffic.
+type weightedURL struct {
+	URL    string
+	Weight int
+	Name   string // "A" or "B" for -url-a and -url-b targets, otherwise ""
+}
+
+// urlList collects repeated -url flags.
+type urlList []weightedURL
+
+func (u *urlList) String() string {
+	parts := make([]string, len(*u))
+	for i, w := range *u {
+		parts[i] = w.URL
+		if w.Weight != 1 {
+			parts[i] = w.flagValue()
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (u *urlList) Set(value string) error {
+	w, err := parseWeightedURL(value)
+	if err != nil {
+		return err
+	}
+	*u = append(*u, w)
+	return nil
+}
+
+// parseWeightedURL splits an optional weight off a URL, given after a
+// space as in "http://h/?page=2 30", or for URLs without a query as an
+// "=weight" suffix as in "http://h/a=30". An '=' after a '?' always
+// belongs to a query parameter, so "http://h/?a=1&page=2" is a plain
+// URL.
+func parseWeightedURL(value string) (weightedURL, error) {
+	prefix, suffix, ok := cutLast(strings.TrimSpace(value), " ")
+	if ok {
+		prefix = strings.TrimSpace(prefix)
+		weight, err := strconv.Atoi(suffix)
+		if err != nil {
+			return weightedURL{}, fmt.Errorf("weight %q for %q is not a number", suffix, prefix)
+		}
+		return checkWeight(prefix, weight)
+	}
+	prefix, suffix, ok = cutLast(value, "=")
+	if !ok || strings.Contains(prefix, "?") {
+		return weightedURL{URL: value, Weight: 1}, nil
+	}
+	weight, err := strconv.Atoi(suffix)
+	if err != nil {
+		return weightedURL{URL: value, Weight: 1}, nil
+	}
+	return checkWeight(prefix, weight)
+}
+
+func checkWeight(u string, weight int) (weightedURL, error) {
+	if weight <= 0 {
+		return weightedURL{}, fmt.Errorf("weight for %q must be positive", u)
+	}
+	return weightedURL{URL: u, Weight: weight}, nil
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// flagValue formats w as parseWeightedURL reads it back, using the
+// space separator for URLs with a query.
+func (w weightedURL) flagValue() string {
+	if strings.Contains(w.URL, "?") {
+		return w.URL + " " + strconv.Itoa(w.Weight)
+	}
+	return w.URL + "=" + strconv.Itoa(w.Weight)
+}
+
+// resolve makes relative URLs absolute against the first absolute URL
+// in the list, so "-url http://h/a -url /b=30" targets http://h/b.
+func (u urlList) resolve() (urlList, error) {
+	var base string
+	for _, w := range u {
+		if parsed, err := url.Parse(w.URL); err == nil && parsed.IsAbs() {
+			base = w.URL
+			break
+		}
+	}
+
+	out := make(urlList, len(u))
+	for i, w := range u {
+		target, err := resolveURL(base, w.URL)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = weightedURL{URL: target, Weight: w.Weight, Name: w.Name}
+	}
+	return out, nil
+}
+
+// loadURLFile replaces c.URLs with the -url-file list, read from stdin
+// when the file is "-". A -url, if given, is not a target itself but
+// the base that relative lines resolve against.
+func (c *config) loadURLFile(stdin io.Reader) error {
+	switch {
+	case c.Scenario != "" || c.HAR != "" || c.GRPC.Method != "":
+		return usageError{errors.New("-url-file cannot be combined with -scenario, -har or -grpc")}
+	case len(c.URLs) > 1:
+		return usageError{errors.New("-url-file takes at most one -url, as the base for relative lines")}
+	}
+	var base string
+	if len(c.URLs) == 1 {
+		base = c.URLs[0].URL
+	}
+	r, from := stdin, "stdin"
+	if c.URLFile != "-" {
+		f, err := os.Open(c.URLFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r, from = f, c.URLFile
+	}
+	urls, err := readURLList(r, base)
+	if err != nil {
+		return fmt.Errorf("reading URLs from %s: %w", from, err)
+	}
+	c.URLs, c.listedFrom = urls, from
+	return nil
+}
+
+// readURLList reads one URL per line, skipping blank lines and lines
+// starting with '#', and resolves relative ones against base. Unlike
+// -url values, lines carry no "=weight" suffix, so query strings such
+// as "?page=2" taken from an access log are never misread as one.
+func readURLList(r io.Reader, base string) (urlList, error) {
+	var urls urlList
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20) // room for long query strings
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		target, err := resolveURL(base, text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		urls = append(urls, weightedURL{URL: target, Weight: 1})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no URLs")
+	}
+	return urls, nil
+}
+
+// mix picks a scenario for each job in proportion to its weight, or
+// each in turn when rotate is set.
+type mix struct {
+	scenarios  []*scenario
+	cumulative []int
+	total      int
+	rotate     bool
+	next       atomic.Uint64
+}
+
+// add appends a scenario with the given weight.
+func (m *mix) add(sc *scenario, weight int) {
+	m.total += weight
+	m.scenarios = append(m.scenarios, sc)
+	m.cumulative = append(m.cumulative, m.total)
+}
+
+// pick returns a scenario chosen at random by weight, or the next one
+// round-robin for a rotating mix.
+func (m *mix) pick() *scenario {
+	if len(m.scenarios) == 1 {
+		return m.scenarios[0]
+	}
+	if m.rotate {
+		return m.scenarios[(m.next.Add(1)-1)%uint64(len(m.scenarios))]
+	}
+	n := rand.IntN(m.total)
+	for i, c := range m.cumulative {
+		if n < c {
+			return m.scenarios[i]
+		}
+	}
+	return m.scenarios[len(m.scenarios)-1]
+}
+
+// compile prepares every step's placeholders and checks that they can
+// be rendered with sample, so template mistakes surface before the run.
+func (m *mix) compile(sample map[string]string) error {
+	for _, sc := range m.scenarios {
+		// Values captured by earlier steps stand in as placeholders, so
+		// a step may use them but not a capture that comes later.
+		vars := sample
+		for _, st := range sc.Steps {
+			if err := st.Tmpl.compile(); err != nil {
+				return fmt.Errorf("%s: %w", st.Tmpl.Name, err)
+			}
+			if _, err := st.Tmpl.newRequest(context.Background(), vars); err != nil {
+				return fmt.Errorf("%s: %w", st.Tmpl.Name, err)
+			}
+			if len(st.Tmpl.Capture) > 0 {
+				placeholders := make(map[string]string, len(st.Tmpl.Capture))
+				for _, c := range st.Tmpl.Capture {
+					placeholders[c.Name] = "0"
+				}
+				vars = withCaptures(vars, placeholders)
+			}
+		}
+	}
+	return nil
+}
+
+// steps returns the number of steps in the longest scenario, which is
+// the most results a single job can produce.
+func (m *mix) steps() int {
+	most := 0
+	for _, sc := range m.scenarios {
+		most = max(most, len(sc.Steps))
+	}
+	return most
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseWeightedURL(t *testing.T) {
+	tests := []struct {
+		input  string
+		url    string
+		weight int
+	}{
+		{"http://h/a", "http://h/a", 1},
+		{"http://h/a=70", "http://h/a", 70},
+		{"/api/b=30", "/api/b", 30},
+		{"http://h/?page=2", "http://h/?page=2", 1},
+		{"http://h/?page=2=30", "http://h/?page=2=30", 1},
+		{"http://h/?q=abc", "http://h/?q=abc", 1},
+		{"http://h/s?a=1&page=2", "http://h/s?a=1&page=2", 1},
+		{"http://h/s?a=1&b=2&page=3 40", "http://h/s?a=1&b=2&page=3", 40},
+		{"/api/b 30", "/api/b", 30},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWeightedURL(tt.input)
+		if err != nil {
+			t.Errorf("parseWeightedURL(%q) error = %v", tt.input, err)
+			continue
+		}
+		if got.URL != tt.url || got.Weight != tt.weight {
+			t.Errorf("parseWeightedURL(%q) = %q/%d, want %q/%d", tt.input, got.URL, got.Weight, tt.url, tt.weight)
+		}
+	}
+
+	for _, bad := range []string{"http://h/a=0", "http://h/?a=1 0", "http://h/?a=1 many"} {
+		if _, err := parseWeightedURL(bad); err == nil {
+			t.Errorf("parseWeightedURL(%q): expected error", bad)
+		}
+	}
+	for _, w := range []weightedURL{{URL: "http://h/a", Weight: 3}, {URL: "http://h/s?a=1&page=2", Weight: 5}} {
+		if got, err := parseWeightedURL(w.flagValue()); err != nil || got != w {
+			t.Errorf("parseWeightedURL(%q) = %+v, %v; want %+v back", w.flagValue(), got, err, w)
+		}
+	}
+}
+
+func TestURLListResolve(t *testing.T) {
+	list := urlList{{URL: "/a", Weight: 1}, {URL: "http://h/base", Weight: 2}}
+	got, err := list.resolve()
+	if err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+	if got[0].URL != "http://h/a" {
+		t.Errorf("resolved URL = %q, want http://h/a", got[0].URL)
+	}
+	if got[1].Weight != 2 {
+		t.Errorf("weight = %d, want 2", got[1].Weight)
+	}
+}
+
+func TestMixPick(t *testing.T) {
+	a := singleStep(&requestTemplate{Name: "a"})
+	b := singleStep(&requestTemplate{Name: "b"})
+
+	var m mix
+	m.add(a, 3)
+	m.add(b, 1)
+
+	counts := map[*scenario]int{}
+	for i := 0; i < 4000; i++ {
+		counts[m.pick()]++
+	}
+	if counts[a] < 2700 || counts[a] > 3300 {
+		t.Errorf("picked a %d times out of 4000, want about 3000", counts[a])
+	}
+}
+
+func TestMixRotate(t *testing.T) {
+	m := mix{rotate: true}
+	var want []*scenario
+	for _, name := range []string{"a", "b", "c"} {
+		sc := singleStep(&requestTemplate{Name: name})
+		m.add(sc, 1)
+		want = append(want, sc)
+	}
+	for i := range 7 {
+		if got := m.pick(); got != want[i%3] {
+			t.Fatalf("pick %d = %s, want %s", i, got.Steps[0].Tmpl.Name, want[i%3].Steps[0].Tmpl.Name)
+		}
+	}
+}
+
+func TestReadURLList(t *testing.T) {
+	in := "# from the access log\n/search?q=a&page=2\n\n  http://other/x  \n"
+	got, err := readURLList(strings.NewReader(in), "http://h/")
+	if err != nil {
+		t.Fatalf("readURLList() error = %v", err)
+	}
+	want := urlList{{URL: "http://h/search?q=a&page=2", Weight: 1}, {URL: "http://other/x", Weight: 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("readURLList() = %v, want %v", got, want)
+	}
+
+	if _, err := readURLList(strings.NewReader("\n# nothing\n"), ""); err == nil {
+		t.Error("an empty list should be rejected")
+	}
+	if _, err := readURLList(strings.NewReader("http://h/\n/relative\n"), ""); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("relative line without a base: error = %v, want one naming line 2", err)
+	}
+}
+
+func TestLoadURLFile(t *testing.T) {
+	cfg := config{URLFile: "-", URLs: urlList{{URL: "http://h/", Weight: 1}}}
+	if err := cfg.loadURLFile(strings.NewReader("/a\n/b\n")); err != nil {
+		t.Fatalf("loadURLFile() error = %v", err)
+	}
+	if len(cfg.URLs) != 2 || cfg.URLs[0].URL != "http://h/a" {
+		t.Errorf("URLs = %v, want the two lines resolved against -url, without -url itself", cfg.URLs)
+	}
+
+	cfg = config{URLFile: "-", URLs: urlList{{URL: "http://h/"}, {URL: "http://g/"}}}
+	var usage usageError
+	if err := cfg.loadURLFile(strings.NewReader("/a\n")); !errors.As(err, &usage) {
+		t.Errorf("two -url with -url-file: error = %v, want a usage error", err)
+	}
+}
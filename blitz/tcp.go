@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+// isTCPURL reports whether raw targets a bare TCP endpoint.
+func isTCPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "tcp"
+}
+
+// tcpConnect opens a TCP connection to the target and closes it again,
+// without speaking HTTP, for stress testing load balancers and
+// connection limits. Latency is the connect time. With -tcp-hold the
+// connection is kept open that long first, and a peer that closes or
+// resets it in the meantime counts as a failed accept.
+func (r *requester) tcpConnect(ctx context.Context, tmpl *requestTemplate, vars map[string]string) Result {
+	target, _, _, err := tmpl.expand(vars)
+	if err != nil {
+		return Result{Step: tmpl.Name, Error: err, Timestamp: time.Now()}
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return Result{Step: tmpl.Name, Error: err, Timestamp: time.Now()}
+	}
+	if u.Port() == "" {
+		return Result{Step: tmpl.Name, Error: fmt.Errorf("%s has no port", target), Timestamp: time.Now()}
+	}
+
+	var tr tracer
+	start := time.Now()
+	conn, err := r.tcp(tr.withTrace(ctx), "tcp", u.Host)
+	end := time.Now()
+	res := Result{
+		Step:      tmpl.Name,
+		Proto:     "TCP",
+		Latency:   end.Sub(start),
+		Timestamp: end,
+		Timing:    tr.timing(end),
+		Error:     err,
+	}
+	if err != nil {
+		return res
+	}
+	defer conn.Close()
+	res.Timing.Family = addrFamily(conn.RemoteAddr())
+	if r.tcpHold > 0 {
+		res.Bytes, res.Error = holdOpen(ctx, conn, r.tcpHold)
+	}
+	return res
+}
+
+// holdOpen keeps conn open for d, discarding anything the peer sends,
+// and returns an error if the peer closes it first. The end of the run
+// cuts the hold short without counting as a failure.
+func holdOpen(ctx context.Context, conn net.Conn, d time.Duration) (int64, error) {
+	conn.SetReadDeadline(time.Now().Add(d))
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	start := time.Now()
+	n, err := io.Copy(io.Discard, conn)
+	switch {
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return n, nil
+	case err == nil:
+		err = io.EOF // Copy reports a clean close as success
+	}
+	return n, fmt.Errorf("connection closed by peer after %s: %w", time.Since(start).Round(time.Millisecond), err)
+}
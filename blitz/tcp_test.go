@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tcpListener accepts connections and hands each to handle, returning
+// a tcp:// URL for it.
+func tcpListener(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn)
+		}
+	}()
+	return "tcp://" + ln.Addr().String()
+}
+
+func tcpRequester(hold time.Duration) *requester {
+	var d net.Dialer
+	return &requester{tcp: d.DialContext, tcpHold: hold}
+}
+
+func TestTCPConnect(t *testing.T) {
+	target := tcpListener(t, func(c net.Conn) {
+		time.Sleep(time.Second)
+		c.Close()
+	})
+	res := tcpRequester(0).send(context.Background(), &requestTemplate{URL: target}, nil)
+	if res.Error != nil {
+		t.Fatalf("connect error = %v", res.Error)
+	}
+	if failed(res) {
+		t.Error("a successful connect counted as failed")
+	}
+	if res.Proto != "TCP" || res.Timing.Connect <= 0 {
+		t.Errorf("Proto = %q, Timing.Connect = %v", res.Proto, res.Timing.Connect)
+	}
+
+	res = tcpRequester(50*time.Millisecond).send(context.Background(), &requestTemplate{URL: target}, nil)
+	if res.Error != nil {
+		t.Errorf("held connect error = %v", res.Error)
+	}
+}
+
+func TestTCPConnectClosedByPeer(t *testing.T) {
+	target := tcpListener(t, func(c net.Conn) { c.Close() })
+	res := tcpRequester(time.Second).send(context.Background(), &requestTemplate{URL: target}, nil)
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "closed by peer") {
+		t.Errorf("error = %v, want a closed-by-peer failure", res.Error)
+	}
+}
+
+func TestTCPConnectNoPort(t *testing.T) {
+	res := tcpRequester(0).send(context.Background(), &requestTemplate{URL: "tcp://localhost"}, nil)
+	if res.Error == nil {
+		t.Error("tcp:// URL without a port was accepted")
+	}
+}
+
+func TestTCPModeValidation(t *testing.T) {
+	for _, cfg := range []config{
+		{URLs: urlList{{URL: "tcp://a:1"}, {URL: "http://b"}}, Workers: 1},
+		{URLs: urlList{{URL: "http://b"}}, TCPHold: time.Second, Workers: 1},
+	} {
+		if _, err := newLoadTest(cfg); err == nil {
+			t.Errorf("newLoadTest(%+v) accepted invalid TCP options", cfg.URLs)
+		}
+	}
+}
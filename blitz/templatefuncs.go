@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the functions available in {{ }} placeholders, on
+// top of the -data columns. Each call is evaluated afresh, so two
+// {{uuid}} in one request give two different values.
+var templateFuncs = template.FuncMap{
+	"uuid":        newRequestID,
+	"seq":         nextSeq,
+	"now":         now,
+	"rand":        randN,
+	"randint":     randint,
+	"uniform":     uniform,
+	"zipf":        zipf,
+	"exponential": exponential,
+}
+
+// seqCounter backs {{seq}}.
+var seqCounter atomic.Int64
+
+// nextSeq returns 1, 2, 3, ... across the whole run, shared by all
+// workers, for keys that must be unique and ordered.
+func nextSeq() int64 {
+	return seqCounter.Add(1)
+}
+
+// now formats the current time: RFC 3339 by default, "unix" or
+// "unixms" for epoch seconds or milliseconds, or any Go time layout.
+func now(format ...string) (string, error) {
+	t := time.Now()
+	switch {
+	case len(format) > 1:
+		return "", fmt.Errorf("now: takes at most one format, got %d", len(format))
+	case len(format) == 0:
+		return t.Format(time.RFC3339), nil
+	case format[0] == "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case format[0] == "unixms":
+		return strconv.FormatInt(t.UnixMilli(), 10), nil
+	}
+	return t.Format(format[0]), nil
+}
+
+// randN returns a random integer in [0, n).
+func randN(n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("rand: %d must be positive", n)
+	}
+	return rand.IntN(n), nil
+}
+
+// keyDist picks an offset in [0, n) for randint.
+type keyDist func(n int) int
+
+// randint returns an integer in [lo, hi], drawn uniformly or from dist,
+// as in {{randint 1 100000 zipf}}. The skewed distributions favour lo,
+// so a few low IDs take most of the traffic the way hot keys do, and
+// the spread of keys, and with it a cache's hit ratio, is under control.
+func randint(lo, hi int, dist ...keyDist) (int, error) {
+	switch {
+	case hi < lo:
+		return 0, fmt.Errorf("randint: %d is above %d", lo, hi)
+	case len(dist) > 1:
+		return 0, fmt.Errorf("randint: takes one distribution, got %d", len(dist))
+	}
+	n := hi - lo + 1
+	if len(dist) == 0 || dist[0] == nil {
+		return lo + rand.IntN(n), nil
+	}
+	return lo + dist[0](n), nil
+}
+
+// uniform spreads randint evenly over its range, the default.
+func uniform() keyDist {
+	return func(n int) int { return rand.IntN(n) }
+}
+
+// defaultZipfExponent is a moderate skew: over a range of 100000 the
+// first 1% of keys take about three quarters of the requests.
+const defaultZipfExponent = 1.1
+
+// zipfKey identifies a generator by its parameters, which are fixed in
+// a template, so generators can be shared across requests.
+type zipfKey struct {
+	s float64
+	n int
+}
+
+var (
+	zipfMu   sync.Mutex
+	zipfGens = make(map[zipfKey]*rand.Zipf)
+)
+
+// zipf draws from a Zipf distribution where the k-th value is chosen
+// in proportion to 1/k^s. s must be above 1 and defaults to 1.1; larger
+// values concentrate traffic on fewer keys.
+func zipf(s ...float64) (keyDist, error) {
+	exponent := defaultZipfExponent
+	switch {
+	case len(s) > 1:
+		return nil, fmt.Errorf("zipf: takes at most one exponent, got %d", len(s))
+	case len(s) == 1:
+		exponent = s[0]
+	}
+	if exponent <= 1 {
+		return nil, fmt.Errorf("zipf: exponent %g must be above 1", exponent)
+	}
+	return func(n int) int {
+		zipfMu.Lock()
+		defer zipfMu.Unlock()
+		// rand.Zipf is not safe for concurrent use, hence the lock.
+		key := zipfKey{exponent, n}
+		z, ok := zipfGens[key]
+		if !ok {
+			z = rand.NewZipf(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), exponent, 1, uint64(n-1))
+			zipfGens[key] = z
+		}
+		return int(z.Uint64())
+	}, nil
+}
+
+// defaultExponentialMean is the mean offset of exponential as a fraction
+// of the range.
+const defaultExponentialMean = 0.1
+
+// exponential draws offsets from an exponential distribution whose mean
+// is the given fraction of the range, 0.1 by default. Draws beyond the
+// range are redrawn.
+func exponential(mean ...float64) (keyDist, error) {
+	m := defaultExponentialMean
+	switch {
+	case len(mean) > 1:
+		return nil, fmt.Errorf("exponential: takes at most one mean, got %d", len(mean))
+	case len(mean) == 1:
+		m = mean[0]
+	}
+	if m <= 0 || m > 1 {
+		return nil, fmt.Errorf("exponential: mean %g must be a fraction of the range, above 0 and at most 1", m)
+	}
+	return func(n int) int {
+		for {
+			if v := math.Floor(rand.ExpFloat64() * m * float64(n)); v < float64(n) {
+				return int(v)
+			}
+		}
+	}, nil
+}
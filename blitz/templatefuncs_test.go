@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRandintTemplate(t *testing.T) {
+	tmpl := &requestTemplate{Method: "GET", URL: "http://localhost/items/{{randint 1 1000 zipf}}"}
+	if err := tmpl.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+	low := 0
+	for i := 0; i < 1000; i++ {
+		req, err := tmpl.newRequest(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("newRequest() error = %v", err)
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/items/"))
+		if err != nil || id < 1 || id > 1000 {
+			t.Fatalf("rendered path %q, want an ID in [1, 1000]", req.URL.Path)
+		}
+		if id <= 10 {
+			low++
+		}
+	}
+	// Uniformly, the first 1% of IDs would get about 10 of 1000 requests.
+	if low < 300 {
+		t.Errorf("zipf sent %d of 1000 requests to the first 10 IDs, want a heavy skew", low)
+	}
+}
+
+func TestRandintDistributions(t *testing.T) {
+	exp, err := exponential(0.05)
+	if err != nil {
+		t.Fatalf("exponential() error = %v", err)
+	}
+	sum := 0
+	for i := 0; i < 10000; i++ {
+		v, err := randint(0, 999, exp)
+		if err != nil || v < 0 || v > 999 {
+			t.Fatalf("randint() = %d, %v; want a value in [0, 999]", v, err)
+		}
+		sum += v
+	}
+	if mean := sum / 10000; mean < 35 || mean > 65 {
+		t.Errorf("exponential(0.05) mean = %d, want about 50", mean)
+	}
+
+	if v, err := randint(7, 7, uniform()); err != nil || v != 7 {
+		t.Errorf("randint(7, 7) = %d, %v; want 7", v, err)
+	}
+	if _, err := randint(5, 1); err == nil {
+		t.Error("randint(5, 1) succeeded, want an error")
+	}
+	if _, err := zipf(1); err == nil {
+		t.Error("zipf(1) succeeded, want an error for an exponent not above 1")
+	}
+	if _, err := exponential(2); err == nil {
+		t.Error("exponential(2) succeeded, want an error for a mean beyond the range")
+	}
+}
+
+func TestPerRequestTemplateFuncs(t *testing.T) {
+	tmpl := &requestTemplate{
+		Method: "POST",
+		URL:    "http://localhost/orders",
+		Header: http.Header{"Idempotency-Key": {"{{uuid}}"}, "X-Sent": {"{{now \"unixms\"}}"}},
+		Body:   []byte(`{"n":{{seq}},"pick":{{rand 10}}}`),
+	}
+	if err := tmpl.compile(); err != nil {
+		t.Fatalf("compile() error = %v", err)
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	bodyPattern := regexp.MustCompile(`^\{"n":(\d+),"pick":\d\}$`)
+	keys := make(map[string]bool)
+	lastSeq := 0
+	for i := 0; i < 3; i++ {
+		req, err := tmpl.newRequest(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("newRequest() error = %v", err)
+		}
+		key := req.Header.Get("Idempotency-Key")
+		if !uuidPattern.MatchString(key) || keys[key] {
+			t.Errorf("Idempotency-Key = %q, want a fresh UUID per request", key)
+		}
+		keys[key] = true
+
+		sent, err := strconv.ParseInt(req.Header.Get("X-Sent"), 10, 64)
+		if err != nil || time.Since(time.UnixMilli(sent)) > time.Minute {
+			t.Errorf("X-Sent = %q, want the current time in epoch milliseconds", req.Header.Get("X-Sent"))
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		m := bodyPattern.FindSubmatch(body)
+		if m == nil {
+			t.Fatalf("body = %s, want a rendered sequence number and pick", body)
+		}
+		n, _ := strconv.Atoi(string(m[1]))
+		if n <= lastSeq {
+			t.Errorf("seq went from %d to %d, want it to increase", lastSeq, n)
+		}
+		lastSeq = n
+	}
+}
+
+func TestNowFormats(t *testing.T) {
+	if s, err := now(); err != nil {
+		t.Errorf("now() error = %v", err)
+	} else if _, err := time.Parse(time.RFC3339, s); err != nil {
+		t.Errorf("now() = %q, want RFC 3339", s)
+	}
+	if s, _ := now("2006-01-02"); s != time.Now().Format("2006-01-02") {
+		t.Errorf("now(layout) = %q, want today's date", s)
+	}
+	if _, err := randN(0); err == nil {
+		t.Error("rand 0 succeeded, want an error")
+	}
+}
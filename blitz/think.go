@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// thinkTime is the pause a worker takes between iterations, like a
+// user reading a page before the next click. Pauses change how the
+// target sees the load: connections sit idle, and requests arrive
+// spread out instead of back to back.
+type thinkTime struct {
+	Mean    time.Duration
+	Jitter  time.Duration // uniform variation either side of Mean
+	Poisson bool          // exponentially distributed pauses, making each worker's arrivals a Poisson process
+}
+
+// sample draws one pause.
+func (t thinkTime) sample() time.Duration {
+	switch {
+	case t.Mean <= 0:
+		return 0
+	case t.Poisson:
+		return time.Duration(rand.ExpFloat64() * float64(t.Mean))
+	case t.Jitter > 0:
+		d := t.Mean + time.Duration(rand.Int64N(int64(2*t.Jitter)+1)) - t.Jitter
+		return max(d, 0)
+	}
+	return t.Mean
+}
+
+// pause sleeps for one sampled think time. It returns false if ctx was
+// cancelled first.
+func (t thinkTime) pause(ctx context.Context) bool {
+	d := t.sample()
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestThinkTimeSample(t *testing.T) {
+	if d := (thinkTime{Mean: 50 * time.Millisecond}).sample(); d != 50*time.Millisecond {
+		t.Errorf("constant: %v, want 50ms", d)
+	}
+
+	jitter := thinkTime{Mean: 100 * time.Millisecond, Jitter: 20 * time.Millisecond}
+	for range 1000 {
+		if d := jitter.sample(); d < 80*time.Millisecond || d > 120*time.Millisecond {
+			t.Fatalf("jitter: %v outside [80ms, 120ms]", d)
+		}
+	}
+
+	// Exponential pauses average to the mean, with a spread about as
+	// large as the mean itself.
+	poisson := thinkTime{Mean: 100 * time.Millisecond, Poisson: true}
+	const n = 20000
+	var sum, sumSq float64
+	for range n {
+		d := float64(poisson.sample())
+		sum += d
+		sumSq += d * d
+	}
+	mean := sum / n
+	stddev := math.Sqrt(sumSq/n - mean*mean)
+	if want := float64(100 * time.Millisecond); math.Abs(mean-want) > want*0.05 || math.Abs(stddev-want) > want*0.1 {
+		t.Errorf("poisson: mean %v stddev %v, want about 100ms each", time.Duration(mean), time.Duration(stddev))
+	}
+}
+
+func TestThinkTimePauseCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if (thinkTime{Mean: time.Hour}).pause(ctx) {
+		t.Error("pause should return false once ctx is cancelled")
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// threshold is a pass/fail SLO on one summary metric, written as
+// "metric op value", e.g. "p99<500ms", "error_rate<1%" or "rps>1000".
+type threshold struct {
+	Expr   string
+	Metric string
+	Op     string
+	Value  float64 // in the metric's unit: nanoseconds, percent or plain
+}
+
+// thresholdMetric reads one metric from a summary and formats values
+// of it for display.
+type thresholdMetric struct {
+	get    func(Summary) float64
+	parse  func(string) (float64, error)
+	format func(float64) string
+}
+
+var thresholdMetrics = map[string]thresholdMetric{
+	"min":        latencyMetric(func(l LatencyStats) time.Duration { return l.Min }),
+	"mean":       latencyMetric(func(l LatencyStats) time.Duration { return l.Mean }),
+	"avg":        latencyMetric(func(l LatencyStats) time.Duration { return l.Mean }),
+	"p50":        latencyMetric(func(l LatencyStats) time.Duration { return l.P50 }),
+	"p95":        latencyMetric(func(l LatencyStats) time.Duration { return l.P95 }),
+	"p99":        latencyMetric(func(l LatencyStats) time.Duration { return l.P99 }),
+	"max":        latencyMetric(func(l LatencyStats) time.Duration { return l.Max }),
+	"rps":        plainMetric(func(s Summary) float64 { return s.RPS }),
+	"requests":   plainMetric(func(s Summary) float64 { return float64(s.Total) }),
+	"failed":     plainMetric(func(s Summary) float64 { return float64(s.Failed) }),
+	"apdex":      plainMetric(apdexScore),
+	"error_rate": {get: errorRatePct, parse: parsePercent, format: func(v float64) string { return fmt.Sprintf("%.2f%%", v) }},
+}
+
+// apdexScore is 0 when nothing was scored, so an apdex threshold fails
+// an empty run rather than passing it.
+func apdexScore(s Summary) float64 {
+	if s.Apdex == nil {
+		return 0
+	}
+	return s.Apdex.Score
+}
+
+func latencyMetric(pick func(LatencyStats) time.Duration) thresholdMetric {
+	return thresholdMetric{
+		get: func(s Summary) float64 { return float64(pick(s.Latency)) },
+		parse: func(v string) (float64, error) {
+			d, err := time.ParseDuration(v)
+			return float64(d), err
+		},
+		format: func(v float64) string { return time.Duration(v).Round(time.Millisecond).String() },
+	}
+}
+
+func plainMetric(get func(Summary) float64) thresholdMetric {
+	return thresholdMetric{
+		get:    get,
+		parse:  func(v string) (float64, error) { return strconv.ParseFloat(v, 64) },
+		format: func(v float64) string { return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64) },
+	}
+}
+
+func errorRatePct(s Summary) float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Total) * 100
+}
+
+// parsePercent accepts "1%" or the fraction "0.01", returning percent.
+func parsePercent(v string) (float64, error) {
+	if pct, ok := strings.CutSuffix(v, "%"); ok {
+		return strconv.ParseFloat(pct, 64)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	return f * 100, err
+}
+
+var thresholdExpr = regexp.MustCompile(`^\s*([a-z0-9_]+)\s*(<=|>=|<|>)\s*(\S+)\s*$`)
+
+// parseThresholds parses threshold expressions. Each entry may hold
+// several comma-separated expressions.
+func parseThresholds(entries []string) ([]threshold, error) {
+	var out []threshold
+	for _, entry := range entries {
+		for _, expr := range strings.Split(entry, ",") {
+			m := thresholdExpr.FindStringSubmatch(expr)
+			if m == nil {
+				return nil, fmt.Errorf("invalid threshold %q, expected e.g. p99<500ms", expr)
+			}
+			metric, ok := thresholdMetrics[m[1]]
+			if !ok {
+				return nil, fmt.Errorf("threshold %q: unknown metric %q", expr, m[1])
+			}
+			v, err := metric.parse(m[3])
+			if err != nil {
+				return nil, fmt.Errorf("threshold %q: invalid value %q", expr, m[3])
+			}
+			out = append(out, threshold{Expr: strings.TrimSpace(expr), Metric: m[1], Op: m[2], Value: v})
+		}
+	}
+	return out, nil
+}
+
+// evaluate checks s against the threshold.
+func (t threshold) evaluate(s Summary) AssertionResult {
+	metric := thresholdMetrics[t.Metric]
+	got := metric.get(s)
+	var passed bool
+	switch t.Op {
+	case "<":
+		passed = got < t.Value
+	case "<=":
+		passed = got <= t.Value
+	case ">":
+		passed = got > t.Value
+	case ">=":
+		passed = got >= t.Value
+	}
+	return AssertionResult{
+		Name:   "threshold " + t.Expr,
+		Passed: passed,
+		Detail: t.Metric + " was " + metric.format(got),
+	}
+}
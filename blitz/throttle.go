@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttle holds back requests while the target has asked, with a
+// Retry-After header on a 429 or 503, for clients to wait. With
+// -retry-after global one throttle is shared by every worker; with
+// -retry-after worker each virtual user has its own.
+type throttle struct {
+	global bool
+
+	mu    sync.Mutex
+	until time.Time
+}
+
+// parseRetryAfterMode validates -retry-after, returning nil when it is
+// off.
+func parseRetryAfterMode(mode string) (*throttle, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case "worker":
+		return &throttle{}, nil
+	case "global":
+		return &throttle{global: true}, nil
+	}
+	return nil, fmt.Errorf("unknown -retry-after %q (use worker or global)", mode)
+}
+
+// forWorker returns the throttle a new virtual user should honor.
+func (t *throttle) forWorker() *throttle {
+	if t == nil || t.global {
+		return t
+	}
+	return &throttle{}
+}
+
+// wait blocks until the back-off asked for has passed, returning how
+// long it waited, or false if ctx ended first.
+func (t *throttle) wait(ctx context.Context) (time.Duration, bool) {
+	if t == nil {
+		return 0, true
+	}
+	t.mu.Lock()
+	d := time.Until(t.until)
+	t.mu.Unlock()
+	if d <= 0 {
+		return 0, true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return d, true
+	case <-ctx.Done():
+		return 0, false
+	}
+}
+
+// observe extends the back-off by res.RetryAfter, if it asked for one.
+func (t *throttle) observe(res Result) {
+	if t == nil || res.RetryAfter <= 0 {
+		return
+	}
+	until := res.Timestamp.Add(res.RetryAfter)
+	t.mu.Lock()
+	if until.After(t.until) {
+		t.until = until
+	}
+	t.mu.Unlock()
+}
+
+// retryAfter returns the delay a 429 or 503 response asks for in its
+// Retry-After header, given either as seconds or as an HTTP date, or 0
+// if it asks for none.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	v := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(time.Duration(secs)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
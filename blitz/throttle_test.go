@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		status int
+		header string
+		want   time.Duration
+	}{
+		{http.StatusTooManyRequests, "2", 2 * time.Second},
+		{http.StatusServiceUnavailable, now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{http.StatusTooManyRequests, now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{http.StatusTooManyRequests, "", 0},
+		{http.StatusTooManyRequests, "soon", 0},
+		{http.StatusOK, "2", 0},
+	} {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		if got := retryAfter(resp, now); got != tt.want {
+			t.Errorf("retryAfter(%d, %q) = %s, want %s", tt.status, tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	if _, err := parseRetryAfterMode("always"); err == nil {
+		t.Error("parseRetryAfterMode(always) should fail")
+	}
+	global, _ := parseRetryAfterMode("global")
+	worker, _ := parseRetryAfterMode("worker")
+	if global.forWorker() != global {
+		t.Error("global mode should share one throttle between workers")
+	}
+	a, b := worker.forWorker(), worker.forWorker()
+	if a == b {
+		t.Fatal("worker mode should give each worker its own throttle")
+	}
+
+	a.observe(Result{Timestamp: time.Now(), RetryAfter: 50 * time.Millisecond})
+	if waited, _ := b.wait(context.Background()); waited != 0 {
+		t.Errorf("another worker waited %s, want no wait", waited)
+	}
+	start := time.Now()
+	if waited, ok := a.wait(context.Background()); !ok || waited <= 0 || time.Since(start) < 40*time.Millisecond {
+		t.Errorf("wait() = %s, %t after %s; want about 50ms", waited, ok, time.Since(start))
+	}
+
+	a.observe(Result{Timestamp: time.Now(), RetryAfter: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := a.wait(ctx); ok {
+		t.Error("wait() should give up when ctx ends")
+	}
+}
+
+func TestMakeRequestRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: srv.URL}
+
+	r := &requester{client: srv.Client()}
+	if res := r.makeRequest(context.Background(), tmpl, nil); res.RetryAfter != 0 {
+		t.Errorf("RetryAfter = %s without -retry-after", res.RetryAfter)
+	}
+	r.throttle, _ = parseRetryAfterMode("global")
+	if res := r.makeRequest(context.Background(), tmpl, nil); res.RetryAfter != 3*time.Second {
+		t.Errorf("RetryAfter = %s, want 3s", res.RetryAfter)
+	}
+}
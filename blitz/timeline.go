@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// timeline counts results per wall-clock second, so throughput and
+// latency can be charted over the course of a run. Slots are keyed by
+// absolute time, which lets timelines from several agents be merged.
+type timeline struct {
+	Start int64      // Unix second of Slots[0]
+	Slots []timeSlot // one per second from Start
+}
+
+// timeSlot aggregates the results completed within one second.
+type timeSlot struct {
+	Requests int
+	Failed   int
+	Latency  time.Duration // sum, for the mean
+	Max      time.Duration
+	Buckets  []uint32 // latency counts by slotBucket, for percentiles
+}
+
+// slotBucketsPerDoubling sets the resolution of timeSlot.Buckets. Four
+// buckets per doubling keep percentiles within about 19% while a slot
+// stays a few hundred bytes, which matters over multi-hour runs.
+const slotBucketsPerDoubling = 4
+
+// slotBucket returns the bucket for latency d: 0 below a microsecond,
+// then i covers [2^((i-1)/4), 2^(i/4)) microseconds.
+func slotBucket(d time.Duration) int {
+	us := d.Microseconds()
+	if us < 1 {
+		return 0
+	}
+	return int(math.Log2(float64(us))*slotBucketsPerDoubling) + 1
+}
+
+// slotBucketMax returns the upper bound of bucket i.
+func slotBucketMax(i int) time.Duration {
+	return time.Duration(math.Exp2(float64(i)/slotBucketsPerDoubling) * float64(time.Microsecond))
+}
+
+// add counts latency in its bucket.
+func (s *timeSlot) add(i int, n uint32) {
+	if i >= len(s.Buckets) {
+		s.Buckets = append(s.Buckets, make([]uint32, i-len(s.Buckets)+1)...)
+	}
+	s.Buckets[i] += n
+}
+
+// percentile estimates the slot's p-th latency percentile from its
+// buckets, clamped to the slot's maximum.
+func (s *timeSlot) percentile(p float64) time.Duration {
+	var total uint32
+	for _, c := range s.Buckets {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := min(uint32(float64(total)*p/100)+1, total)
+	var seen uint32
+	for i, c := range s.Buckets {
+		seen += c
+		if seen >= rank {
+			return min(slotBucketMax(i), s.Max)
+		}
+	}
+	return s.Max
+}
+
+// TimelinePoint summarizes one second of a run.
+type TimelinePoint struct {
+	Offset   time.Duration // from the start of the run
+	Requests int
+	Failed   int
+	Mean     time.Duration
+	P95      time.Duration
+	Max      time.Duration
+
+	Time      time.Time // start of the second
+	Latencies []uint32  // counts by latency bucket, as in timeSlot.Buckets
+}
+
+// slot returns the slot for Unix second sec, growing the timeline in
+// either direction as needed.
+func (t *timeline) slot(sec int64) *timeSlot {
+	switch {
+	case len(t.Slots) == 0:
+		t.Start = sec
+		t.Slots = make([]timeSlot, 1)
+	case sec < t.Start:
+		grown := make([]timeSlot, int(t.Start-sec)+len(t.Slots))
+		copy(grown[t.Start-sec:], t.Slots)
+		t.Slots, t.Start = grown, sec
+	case sec >= t.Start+int64(len(t.Slots)):
+		t.Slots = append(t.Slots, make([]timeSlot, int(sec-t.Start)-len(t.Slots)+1)...)
+	}
+	return &t.Slots[sec-t.Start]
+}
+
+// Record adds a result that completed at the given time.
+func (t *timeline) Record(at time.Time, failed bool, latency time.Duration) {
+	if at.IsZero() {
+		return
+	}
+	s := t.slot(at.Unix())
+	s.Requests++
+	if failed {
+		s.Failed++
+	}
+	s.Latency += latency
+	s.Max = max(s.Max, latency)
+	s.add(slotBucket(latency), 1)
+}
+
+// Merge folds other's slots into t.
+func (t *timeline) Merge(other *timeline) {
+	for i, o := range other.Slots {
+		if o.Requests == 0 {
+			continue
+		}
+		s := t.slot(other.Start + int64(i))
+		s.Requests += o.Requests
+		s.Failed += o.Failed
+		s.Latency += o.Latency
+		s.Max = max(s.Max, o.Max)
+		for j, c := range o.Buckets {
+			if c > 0 {
+				s.add(j, c)
+			}
+		}
+	}
+}
+
+// Shift moves the timeline d later, to the nearest second.
+func (t *timeline) Shift(d time.Duration) {
+	t.Start += int64(d.Round(time.Second) / time.Second)
+}
+
+// Points returns the timeline as per-second points.
+func (t *timeline) Points() []TimelinePoint {
+	points := make([]TimelinePoint, len(t.Slots))
+	for i, s := range t.Slots {
+		points[i] = TimelinePoint{
+			Offset:   time.Duration(i) * time.Second,
+			Requests: s.Requests,
+			Failed:   s.Failed,
+			P95:      s.percentile(95),
+			Max:      s.Max,
+
+			Time:      time.Unix(t.Start+int64(i), 0),
+			Latencies: slices.Clone(s.Buckets),
+		}
+		if s.Requests > 0 {
+			points[i].Mean = s.Latency / time.Duration(s.Requests)
+		}
+	}
+	return points
+}
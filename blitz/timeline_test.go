@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimelineRecord(t *testing.T) {
+	base := time.Unix(1000, 0)
+	var tl timeline
+	tl.Record(base.Add(1500*time.Millisecond), false, 10*time.Millisecond)
+	tl.Record(base.Add(1200*time.Millisecond), true, 30*time.Millisecond)
+	// Results can complete slightly out of order around the first second.
+	tl.Record(base.Add(200*time.Millisecond), false, 5*time.Millisecond)
+	tl.Record(base.Add(3100*time.Millisecond), false, 50*time.Millisecond)
+	tl.Record(time.Time{}, false, time.Second) // no timestamp: ignored
+
+	points := tl.Points()
+	want := []TimelinePoint{
+		{Offset: 0, Requests: 1, Mean: 5 * time.Millisecond, P95: 5 * time.Millisecond, Max: 5 * time.Millisecond},
+		{Offset: time.Second, Requests: 2, Failed: 1, Mean: 20 * time.Millisecond, P95: 30 * time.Millisecond, Max: 30 * time.Millisecond},
+		{Offset: 2 * time.Second},
+		{Offset: 3 * time.Second, Requests: 1, Mean: 50 * time.Millisecond, P95: 50 * time.Millisecond, Max: 50 * time.Millisecond},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("got %d points, want %d: %+v", len(points), len(want), points)
+	}
+	if !points[1].Time.Equal(base.Add(time.Second)) {
+		t.Errorf("point 1 time = %v, want %v", points[1].Time, base.Add(time.Second))
+	}
+	for i := range want {
+		// The heatmap fields are covered by TestHeatmapRows.
+		got := points[i]
+		got.Time, got.Latencies = time.Time{}, nil
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("point %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestTimelineMergeAlignsByTime(t *testing.T) {
+	var a, b timeline
+	a.Record(time.Unix(100, 0), false, time.Millisecond)
+	b.Record(time.Unix(102, 0), false, time.Millisecond)
+	b.Record(time.Unix(99, 0), true, time.Millisecond)
+	a.Merge(&b)
+
+	if a.Start != 99 || len(a.Slots) != 4 {
+		t.Fatalf("start %d, %d slots; want 99, 4", a.Start, len(a.Slots))
+	}
+	for i, want := range []int{1, 1, 0, 1} {
+		if got := a.Slots[i].Requests; got != want {
+			t.Errorf("slot %d: %d requests, want %d", i, got, want)
+		}
+	}
+}
+
+func TestTimelineP95(t *testing.T) {
+	var tl timeline
+	at := time.Unix(1000, 0)
+	for i := 1; i <= 100; i++ {
+		tl.Record(at, false, time.Duration(i)*time.Millisecond)
+	}
+	var other timeline
+	other.Record(at, false, 2*time.Second)
+	tl.Merge(&other)
+
+	p95 := tl.Points()[0].P95
+	// 96 of 101 values are at most 96ms; buckets are about 19% wide.
+	if p95 < 96*time.Millisecond || p95 > 115*time.Millisecond {
+		t.Errorf("P95 = %v, want about 96ms", p95)
+	}
+}
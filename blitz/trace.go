@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks a single request's latency into connection phases.
+// Phases that did not happen (e.g. DNS and connect on a reused
+// connection) are zero.
+type Timing struct {
+	DNS      time.Duration // hostname resolution
+	Connect  time.Duration // TCP connect
+	TLS      time.Duration // TLS handshake
+	TTFB     time.Duration // request written to first response byte
+	Download time.Duration // first response byte to end of body
+	Reused   bool          // connection came from the idle pool
+	Family   string        // address family of the connection: "IPv4", "IPv6" or "unix"
+}
+
+// tracer collects httptrace callbacks for one request. Callbacks may
+// fire from dialer goroutines, so marks are guarded by a mutex.
+type tracer struct {
+	mu         sync.Mutex
+	dnsStart   time.Time
+	dnsDone    time.Time
+	connStart  time.Time
+	connDone   time.Time
+	tlsStart   time.Time
+	tlsDone    time.Time
+	wroteReq   time.Time
+	firstByte  time.Time
+	connReused bool
+	family     string
+}
+
+// withTrace returns a context that records phase timings into t.
+func (t *tracer) withTrace(ctx context.Context) context.Context {
+	mark := func(field *time.Time) {
+		t.mu.Lock()
+		*field = time.Now()
+		t.mu.Unlock()
+	}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&t.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { mark(&t.dnsDone) },
+		ConnectStart:      func(string, string) { mark(&t.connStart) },
+		ConnectDone:       func(string, string, error) { mark(&t.connDone) },
+		TLSHandshakeStart: func() { mark(&t.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { mark(&t.tlsDone) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { mark(&t.wroteReq) },
+		GotFirstResponseByte: func() {
+			mark(&t.firstByte)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.connReused = info.Reused
+			t.family = addrFamily(info.Conn.RemoteAddr())
+			t.mu.Unlock()
+		},
+	})
+}
+
+// timing computes the phase breakdown given the time the body finished.
+func (t *tracer) timing(end time.Time) Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Timing{
+		DNS:      span(t.dnsStart, t.dnsDone),
+		Connect:  span(t.connStart, t.connDone),
+		TLS:      span(t.tlsStart, t.tlsDone),
+		TTFB:     span(t.wroteReq, t.firstByte),
+		Download: span(t.firstByte, end),
+		Reused:   t.connReused,
+		Family:   t.family,
+	}
+}
+
+// addrFamily names the address family of a connection's remote end.
+func addrFamily(addr net.Addr) string {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr: // QUIC, with -http3
+		ip = a.IP
+	default:
+		return addr.Network()
+	}
+	switch {
+	case ip.To4() != nil:
+		return "IPv4"
+	default:
+		return "IPv6"
+	}
+}
+
+// span returns end-start, or zero if either mark is missing.
+func span(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
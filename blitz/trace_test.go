@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracePhases(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(delay)
+		w.Write([]byte("last"))
+	}))
+	defer srv.Close()
+
+	// A hostname rather than an IP, so the request has a DNS phase.
+	tmpl := &requestTemplate{Name: "get", Method: http.MethodGet, URL: strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)}
+	client := srv.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com" // in the test certificate
+	r := &requester{client: client}
+
+	first := r.makeRequest(context.Background(), tmpl, nil)
+	if first.Error != nil {
+		t.Fatalf("request error = %v", first.Error)
+	}
+	tm := first.Timing
+	if tm.DNS <= 0 || tm.Connect <= 0 || tm.TLS <= 0 {
+		t.Errorf("new connection: DNS %v, connect %v, TLS %v; want all positive", tm.DNS, tm.Connect, tm.TLS)
+	}
+	if tm.TTFB < delay || tm.Download < delay {
+		t.Errorf("TTFB %v, download %v; want at least %v each", tm.TTFB, tm.Download, delay)
+	}
+	if tm.Reused || tm.Family != "IPv4" {
+		t.Errorf("reused %v, family %q; want a new IPv4 connection", tm.Reused, tm.Family)
+	}
+	if sum := tm.DNS + tm.Connect + tm.TLS + tm.TTFB + tm.Download; sum > first.Latency {
+		t.Errorf("phases add up to %v, more than the latency %v", sum, first.Latency)
+	}
+
+	second := r.makeRequest(context.Background(), tmpl, nil)
+	tm = second.Timing
+	if !tm.Reused || tm.DNS != 0 || tm.Connect != 0 || tm.TLS != 0 {
+		t.Errorf("reused connection: reused %v, DNS %v, connect %v, TLS %v; want only reuse", tm.Reused, tm.DNS, tm.Connect, tm.TLS)
+	}
+	if tm.TTFB < delay {
+		t.Errorf("reused connection TTFB %v, want at least %v", tm.TTFB, delay)
+	}
+}
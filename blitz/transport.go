@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// transportOptions configures the HTTP transport shared by all workers.
+type transportOptions struct {
+	Insecure      bool   // skip server certificate verification
+	CACert        string // PEM file of extra trusted roots
+	ClientCert    string // PEM client certificate for mTLS
+	ClientKey     string // PEM private key for ClientCert
+	TLSMinVersion string // "1.0" through "1.3"
+	HTTP1         bool   // force HTTP/1.1
+	HTTP2         bool   // force HTTP/2
+	HTTP3         bool   // HTTP/3 over QUIC instead of TCP
+
+	CertDir    string        // directory of client key pairs to rotate through
+	CertRotate time.Duration // switch every connection to the next pair this often (0 = next pair per connection)
+
+	Proxy      string     // proxy URL (http, https or socks5); empty uses HTTP_PROXY etc.
+	Resolve    stringList // "host:port:addr" overrides applied when dialing
+	UnixSocket string     // dial this socket for every request, ignoring the URL host
+	IPv4Only   bool       // dial tcp4 only
+	IPv6Only   bool       // dial tcp6 only
+	DNSServer  string     // send lookups to this ip[:port] instead of the system resolver
+	DNSCache   bool       // resolve each host once and reuse the addresses
+	DNSFresh   bool       // new connection, and so a new lookup, for every request
+
+	DisableKeepAlive bool // open a new connection for every request
+	MaxIdleConns     int  // idle connections kept per host (0 = one per worker)
+	MaxConnsPerHost  int  // cap on total connections per host (0 = unlimited)
+}
+
+// newTransport builds an *http.Transport from opts, starting from
+// http.DefaultTransport so proxy-from-environment and HTTP/2 behavior
+// match the standard client.
+func newTransport(opts transportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// The default of two idle connections per host forces most workers
+	// to redial; callers size MaxIdleConns to the worker count instead.
+	transport.DisableKeepAlives = opts.DisableKeepAlive || opts.DNSFresh
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+	transport.MaxConnsPerHost = opts.MaxConnsPerHost
+
+	if opts.UnixSocket != "" {
+		// Every connection goes to the socket, so a proxy would never
+		// be reached; ignore any set in the environment.
+		if opts.Proxy != "" {
+			return nil, fmt.Errorf("-unix-socket and -proxy are mutually exclusive")
+		}
+		transport.Proxy = nil
+	}
+
+	if opts.Proxy != "" {
+		proxyURL, err := parseProxy(opts.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	dial, err := newDialContext(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport.DialContext = dial
+
+	protocols, err := newProtocols(opts)
+	if err != nil {
+		return nil, err
+	}
+	if protocols != nil {
+		transport.Protocols = protocols
+	}
+
+	return transport, nil
+}
+
+// parseProxy validates a -proxy value. The transport dials SOCKS5
+// proxies itself, so socks5:// works alongside http:// and https://.
+func parseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", raw)
+	}
+	return u, nil
+}
+
+// dialFunc matches http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// newDialContext returns the transport's dial function. It uses the
+// same timeouts as http.DefaultTransport and applies -resolve
+// overrides, which pin a host:port to a fixed IP without touching the
+// URL, so Host headers and TLS server names are unchanged. With
+// -unix-socket every connection goes to the socket instead; -4 and -6
+// restrict TCP dials to one address family. -dns-server, -dns-cache
+// and -dns-fresh control how the remaining hosts are looked up.
+func newDialContext(opts transportOptions) (dialFunc, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if opts.UnixSocket != "" {
+		if len(opts.Resolve) > 0 {
+			return nil, fmt.Errorf("-unix-socket and -resolve are mutually exclusive")
+		}
+		if opts.DNSServer != "" || opts.DNSCache || opts.DNSFresh {
+			return nil, fmt.Errorf("-unix-socket cannot be combined with -dns-server, -dns-cache or -dns-fresh")
+		}
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", opts.UnixSocket)
+		}, nil
+	}
+
+	overrides, err := parseResolve(opts.Resolve)
+	if err != nil {
+		return nil, err
+	}
+	var family string
+	switch {
+	case opts.IPv4Only && opts.IPv6Only:
+		return nil, fmt.Errorf("-4 and -6 are mutually exclusive")
+	case opts.IPv4Only:
+		family = "4"
+	case opts.IPv6Only:
+		family = "6"
+	}
+	if opts.DNSCache && opts.DNSFresh {
+		return nil, fmt.Errorf("-dns-cache and -dns-fresh are mutually exclusive")
+	}
+	resolver, err := newResolver(opts.DNSServer, opts.DNSFresh)
+	if err != nil {
+		return nil, err
+	}
+	dialer.Resolver = resolver
+	var cache *dnsCache
+	if opts.DNSCache {
+		cache = newDNSCache(resolver)
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if pinned, ok := overrides[addr]; ok {
+			addr = pinned
+		}
+		if network != "tcp" {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		if cache != nil {
+			return cache.dial(ctx, dialer, network, family, addr)
+		}
+		return dialer.DialContext(ctx, network+family, addr)
+	}, nil
+}
+
+// parseResolve converts curl-style "host:port:addr" entries into a map
+// from the dialed host:port to the address to dial instead. IPv6
+// addresses may be bracketed.
+func parseResolve(entries []string) (map[string]string, error) {
+	overrides := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		host, rest, ok1 := strings.Cut(entry, ":")
+		port, addr, ok2 := strings.Cut(rest, ":")
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if !ok1 || !ok2 || host == "" || port == "" {
+			return nil, fmt.Errorf("invalid -resolve %q, expected host:port:addr", entry)
+		}
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("invalid -resolve %q: %q is not an IP address", entry, addr)
+		}
+		overrides[net.JoinHostPort(host, port)] = net.JoinHostPort(addr, port)
+	}
+	return overrides, nil
+}
+
+// newProtocols returns the protocol set implied by -http1/-http2, or
+// nil to keep the default negotiation (HTTP/2 when the server offers it).
+func newProtocols(opts transportOptions) (*http.Protocols, error) {
+	switch {
+	case opts.HTTP1 && opts.HTTP2:
+		return nil, fmt.Errorf("-http1 and -http2 are mutually exclusive")
+	case opts.HTTP1:
+		p := new(http.Protocols)
+		p.SetHTTP1(true)
+		return p, nil
+	case opts.HTTP2:
+		// Plain-text URLs need h2c with prior knowledge; TLS URLs
+		// negotiate h2 via ALPN.
+		p := new(http.Protocols)
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+		return p, nil
+	}
+	return nil, nil
+}
+
+// newTLSConfig translates the TLS-related options into a tls.Config.
+func newTLSConfig(opts transportOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+	}
+
+	if opts.TLSMinVersion != "" {
+		version, err := parseTLSVersion(opts.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		cfg.MinVersion = version
+	}
+
+	if opts.CACert != "" {
+		pem, err := os.ReadFile(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.ClientCert != "" || opts.ClientKey != "" {
+		if opts.ClientCert == "" || opts.ClientKey == "" {
+			return nil, fmt.Errorf("-cert and -key must be used together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.ClientCert, opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.CertDir != "" {
+		if opts.ClientCert != "" {
+			return nil, fmt.Errorf("-cert-dir replaces -cert and -key")
+		}
+		rotator, err := newCertRotator(opts.CertDir, opts.CertRotate)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = rotator.GetClientCertificate
+	}
+
+	return cfg, nil
+}
+
+// parseTLSVersion maps a version string like "1.2" to its tls constant.
+func parseTLSVersion(s string) (uint16, error) {
+	switch s {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", s)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseProxy(t *testing.T) {
+	for _, raw := range []string{"http://proxy:3128", "https://proxy:443", "socks5://user:pw@proxy:1080"} {
+		if _, err := parseProxy(raw); err != nil {
+			t.Errorf("parseProxy(%q) error = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://proxy", "proxy:3128", "http://"} {
+		if _, err := parseProxy(raw); err == nil {
+			t.Errorf("parseProxy(%q) expected error", raw)
+		}
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	transport, err := newTransport(transportOptions{Proxy: "socks5://127.0.0.1:1080"})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	u, err := transport.Proxy(req)
+	if err != nil || u == nil || u.Host != "127.0.0.1:1080" {
+		t.Errorf("Proxy() = %v, %v; want socks5://127.0.0.1:1080", u, err)
+	}
+}
+
+func TestNewTransportConnOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      transportOptions
+		keepAlive bool
+	}{
+		{"defaults", transportOptions{MaxIdleConns: 8}, true},
+		{"limits", transportOptions{MaxIdleConns: 4, MaxConnsPerHost: 2}, true},
+		{"disable keep-alive", transportOptions{DisableKeepAlive: true, MaxIdleConns: 4}, false},
+		{"dns fresh", transportOptions{DNSFresh: true, MaxIdleConns: 4}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := newTransport(tt.opts)
+			if err != nil {
+				t.Fatalf("newTransport() error = %v", err)
+			}
+			if transport.DisableKeepAlives == tt.keepAlive {
+				t.Errorf("DisableKeepAlives = %v, want %v", transport.DisableKeepAlives, !tt.keepAlive)
+			}
+			if transport.MaxIdleConns != tt.opts.MaxIdleConns || transport.MaxIdleConnsPerHost != tt.opts.MaxIdleConns {
+				t.Errorf("MaxIdleConns = %d, MaxIdleConnsPerHost = %d; want %d for both",
+					transport.MaxIdleConns, transport.MaxIdleConnsPerHost, tt.opts.MaxIdleConns)
+			}
+			if transport.MaxConnsPerHost != tt.opts.MaxConnsPerHost {
+				t.Errorf("MaxConnsPerHost = %d, want %d", transport.MaxConnsPerHost, tt.opts.MaxConnsPerHost)
+			}
+			// Idle connections still expire as they do with the standard client.
+			if want := http.DefaultTransport.(*http.Transport).IdleConnTimeout; transport.IdleConnTimeout != want {
+				t.Errorf("IdleConnTimeout = %v, want %v", transport.IdleConnTimeout, want)
+			}
+		})
+	}
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	v, err := parseTLSVersion("1.2")
+	if err != nil || v != tls.VersionTLS12 {
+		t.Errorf("parseTLSVersion(1.2) = %v, %v", v, err)
+	}
+	if _, err := parseTLSVersion("1.4"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // the failing handshakes are expected
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeClientCert(t, dir, "client")
+	crt, key := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	ca := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(bad, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    transportOptions
+		wantErr string // from newTLSConfig
+		wantCN  string // client certificate the server saw; empty when the handshake should fail
+	}{
+		{"ca and key pair", transportOptions{CACert: ca, ClientCert: crt, ClientKey: key}, "", "client"},
+		{"ca only", transportOptions{CACert: ca}, "", ""},
+		{"key pair only", transportOptions{ClientCert: crt, ClientKey: key}, "", ""},
+		{"cert without key", transportOptions{ClientCert: crt}, "-cert and -key must be used together", ""},
+		{"key without cert", transportOptions{ClientKey: key}, "-cert and -key must be used together", ""},
+		{"missing ca", transportOptions{CACert: filepath.Join(dir, "missing.pem")}, "reading CA certificate", ""},
+		{"bad ca pem", transportOptions{CACert: bad}, "no certificates found", ""},
+		{"bad cert pem", transportOptions{ClientCert: bad, ClientKey: key}, "loading client certificate", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newTLSConfig(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newTLSConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newTLSConfig() error = %v", err)
+			}
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+			resp, err := client.Get(srv.URL)
+			if tt.wantCN == "" {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("handshake succeeded, want it to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("request error = %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.wantCN {
+				t.Errorf("server saw client certificate %q, want %q", body, tt.wantCN)
+			}
+		})
+	}
+}
+
+func TestNewProtocolsExclusive(t *testing.T) {
+	if _, err := newProtocols(transportOptions{HTTP1: true, HTTP2: true}); err == nil {
+		t.Error("expected error when forcing both HTTP/1.1 and HTTP/2")
+	}
+}
+
+func TestParseResolve(t *testing.T) {
+	got, err := parseResolve([]string{"api.example.com:443:10.0.0.7", "api.example.com:80:[::1]"})
+	if err != nil {
+		t.Fatalf("parseResolve() error = %v", err)
+	}
+	if got["api.example.com:443"] != "10.0.0.7:443" || got["api.example.com:80"] != "[::1]:80" {
+		t.Errorf("parseResolve() = %v", got)
+	}
+
+	for _, bad := range []string{"api.example.com:443", "api.example.com:443:not-an-ip", ":443:10.0.0.7"} {
+		if _, err := parseResolve([]string{bad}); err == nil {
+			t.Errorf("parseResolve(%q) expected error", bad)
+		}
+	}
+}
+
+func TestResolveOverrideDials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	transport, err := newTransport(transportOptions{Resolve: stringList{"blitz.invalid:" + port + ":127.0.0.1"}})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://blitz.invalid:" + port + "/")
+	if err != nil {
+		t.Fatalf("request through -resolve override failed: %v", err)
+	}
+	defer resp.Body.Close()
+	host, _ := io.ReadAll(resp.Body)
+	if string(host) != "blitz.invalid:"+port {
+		t.Errorf("Host header = %q, want the original host", host)
+	}
+}
+
+func TestUnixSocketDial(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("via socket"))
+	}))
+	srv.Listener = ln
+	srv.Start()
+	defer srv.Close()
+
+	transport, err := newTransport(transportOptions{UnixSocket: sock})
+	if err != nil {
+		t.Fatalf("newTransport() error = %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://app.local/health")
+	if err != nil {
+		t.Fatalf("request over unix socket failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "via socket" {
+		t.Errorf("body = %q", body)
+	}
+
+	if _, err := newTransport(transportOptions{UnixSocket: sock, Proxy: "http://proxy:3128"}); err == nil {
+		t.Error("expected -unix-socket with -proxy to be rejected")
+	}
+}
+
+func TestAddressFamilySelection(t *testing.T) {
+	if _, err := newTransport(transportOptions{IPv4Only: true, IPv6Only: true}); err == nil {
+		t.Error("expected -4 with -6 to be rejected")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// The test server listens on 127.0.0.1 only, so forcing IPv6 for a
+	// name that resolves to it must fail while IPv4 succeeds.
+	for _, tt := range []struct {
+		opts    transportOptions
+		wantErr bool
+	}{
+		{transportOptions{IPv4Only: true}, false},
+		{transportOptions{IPv6Only: true}, true},
+	} {
+		tt.opts.Resolve = stringList{"blitz.invalid:" + port + ":127.0.0.1"}
+		transport, err := newTransport(tt.opts)
+		if err != nil {
+			t.Fatalf("newTransport() error = %v", err)
+		}
+		resp, err := (&http.Client{Transport: transport}).Get("http://blitz.invalid:" + port)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("IPv4Only=%v IPv6Only=%v: error = %v, wantErr %v", tt.opts.IPv4Only, tt.opts.IPv6Only, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAddrFamily(t *testing.T) {
+	for addr, want := range map[net.Addr]string{
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.1")}:  "IPv4",
+		&net.TCPAddr{IP: net.ParseIP("::1")}:       "IPv6",
+		&net.UDPAddr{IP: net.ParseIP("10.0.0.1")}:  "IPv4",
+		&net.UnixAddr{Name: "/tmp/s", Net: "unix"}: "unix",
+	} {
+		if got := addrFamily(addr); got != want {
+			t.Errorf("addrFamily(%v) = %q, want %q", addr, got, want)
+		}
+	}
+}
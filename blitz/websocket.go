@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// Step names used for WebSocket results, so connect and message
+// latencies are reported separately.
+const (
+	wsConnectStep    = "connect"
+	wsMessageStep    = "message"
+	wsDisconnectStep = "disconnect"
+)
+
+// isWebSocketURL reports whether raw targets a WebSocket endpoint.
+func isWebSocketURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "ws" || u.Scheme == "wss")
+}
+
+// wsSession is the worker loop for WebSocket targets. Each worker holds
+// one connection. With a body, every job sends it as a message and
+// waits for the next message back, reporting the round trip; without
+// one the connection is held open until the run ends, so only connect
+// time and unexpected disconnects are reported.
+func (r *requester) wsSession(ctx context.Context, jobs <-chan job, results chan<- Result) {
+	tmpl := r.targets.pick().Steps[0].Tmpl
+	vars := r.data.next()
+
+	target, header, _, err := tmpl.expand(vars)
+	if err != nil {
+		results <- Result{Step: wsConnectStep, Error: err, Timestamp: time.Now()}
+		return
+	}
+	start := time.Now()
+	conn, resp, err := websocket.Dial(ctx, target, &websocket.DialOptions{
+		HTTPClient: r.client,
+		HTTPHeader: header,
+	})
+	end := time.Now()
+	res := Result{Step: wsConnectStep, Proto: "WebSocket", Latency: end.Sub(start), Timestamp: end, Error: err}
+	if resp != nil {
+		res.Status = resp.StatusCode
+	}
+	results <- res
+	if err != nil {
+		return
+	}
+	defer conn.CloseNow()
+
+	if len(tmpl.Body) == 0 {
+		r.wsHold(ctx, conn, jobs, results)
+		return
+	}
+
+	for j := range jobs {
+		res := r.wsRoundTrip(ctx, conn, tmpl, r.data.next())
+		if !j.Scheduled.IsZero() {
+			res.Queued = max(res.Timestamp.Add(-res.Latency).Sub(j.Scheduled), 0)
+		}
+		results <- res
+		if res.Error != nil {
+			// The connection is unusable after a read or write error.
+			return
+		}
+	}
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// wsRoundTrip sends one message and waits for the reply.
+func (r *requester) wsRoundTrip(ctx context.Context, conn *websocket.Conn, tmpl *requestTemplate, vars map[string]string) Result {
+	_, _, payload, err := tmpl.expand(vars)
+	if err != nil {
+		return Result{Step: wsMessageStep, Error: err, Timestamp: time.Now()}
+	}
+	start := time.Now()
+	err = conn.Write(ctx, websocket.MessageText, payload)
+	var reply []byte
+	if err == nil {
+		_, reply, err = conn.Read(ctx)
+	}
+	end := time.Now()
+	res := Result{
+		Step:      wsMessageStep,
+		Proto:     "WebSocket",
+		Bytes:     int64(len(reply)),
+		Latency:   end.Sub(start),
+		Timestamp: end,
+		Error:     err,
+	}
+	if err == nil {
+		res.Mismatch = r.expect.checkBody(reply)
+	}
+	return res
+}
+
+// wsHold keeps conn open, discarding anything the server sends, until
+// the job stream ends. A disconnect before then is reported as an error.
+func (r *requester) wsHold(ctx context.Context, conn *websocket.Conn, jobs <-chan job, results chan<- Result) {
+	holdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		for range jobs {
+		}
+		cancel()
+	}()
+
+	start := time.Now()
+	for {
+		if _, _, err := conn.Read(holdCtx); err != nil {
+			if holdCtx.Err() == nil {
+				results <- Result{Step: wsDisconnectStep, Proto: "WebSocket", Latency: time.Since(start), Error: err, Timestamp: time.Now()}
+			}
+			return
+		}
+	}
+}
+
+// isWebSocketClose reports whether err is the peer closing the
+// WebSocket connection.
+func isWebSocketClose(err error) bool {
+	var closeErr websocket.CloseError
+	return errors.As(err, &closeErr)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// wsEchoServer echoes messages, closing the connection after limit
+// messages when limit is positive.
+func wsEchoServer(t *testing.T, limit int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		for n := 1; ; n++ {
+			typ, msg, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			conn.Write(r.Context(), typ, msg)
+			if n == limit {
+				conn.Close(websocket.StatusGoingAway, "bye")
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// runWSSession runs one session over n jobs and returns its results.
+func runWSSession(t *testing.T, target string, n int) []Result {
+	t.Helper()
+	targets := &mix{}
+	targets.add(singleStep(&requestTemplate{URL: target, Body: []byte("ping")}), 1)
+	r := &requester{client: http.DefaultClient, targets: targets, ws: true}
+
+	jobs := make(chan job, n)
+	for range n {
+		jobs <- job{}
+	}
+	close(jobs)
+	results := make(chan Result, n+1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.wsSession(ctx, jobs, results)
+	close(results)
+
+	var out []Result
+	for res := range results {
+		out = append(out, res)
+	}
+	return out
+}
+
+func TestWSSession(t *testing.T) {
+	results := runWSSession(t, wsEchoServer(t, 0), 3)
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want connect + 3 messages", len(results))
+	}
+	if results[0].Step != wsConnectStep || results[0].Status != http.StatusSwitchingProtocols {
+		t.Errorf("first result = %s %d, want a 101 connect", results[0].Step, results[0].Status)
+	}
+	for _, res := range results {
+		if failed(res) {
+			t.Errorf("%s result failed: %v", res.Step, res.Error)
+		}
+	}
+	if results[1].Step != wsMessageStep || results[1].Bytes != int64(len("ping")) {
+		t.Errorf("message result = %+v", results[1])
+	}
+}
+
+func TestWSSessionDisconnect(t *testing.T) {
+	results := runWSSession(t, wsEchoServer(t, 1), 3)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want connect, one reply and one failure", len(results))
+	}
+	last := results[2]
+	if got := classify(last); got != catClosed {
+		t.Errorf("disconnect classified as %q (%v), want %q", got, last.Error, catClosed)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import "time"
+
+// The progress displays look back over the last few seconds of results
+// in slots of liveSlot, so their numbers follow a change in load within
+// a second rather than averaging it away since the start of the run.
+const (
+	liveSlot = 250 * time.Millisecond
+	liveSpan = 10 * time.Second // the longest window a display asks for
+)
+
+// liveWindow is a ring of timeSlots covering the trailing liveSpan.
+type liveWindow struct {
+	start time.Time
+	slots []timeSlot
+	index []int64 // which liveSlot since start each ring entry holds
+}
+
+// windowStats summarizes the results completed within a window.
+type windowStats struct {
+	Requests int
+	RPS      float64
+	Failed   float64 // percentage of Requests
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+}
+
+func newLiveWindow(start time.Time) *liveWindow {
+	n := int(liveSpan / liveSlot)
+	return &liveWindow{start: start, slots: make([]timeSlot, n), index: make([]int64, n)}
+}
+
+// Record adds a result that completed at the given time; one without
+// a timestamp counts as completing now.
+func (w *liveWindow) Record(at time.Time, failed bool, latency time.Duration) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	idx := int64(max(at.Sub(w.start), 0) / liveSlot)
+	i := idx % int64(len(w.slots))
+	if w.index[i] != idx {
+		w.slots[i] = timeSlot{}
+		w.index[i] = idx
+	}
+	s := &w.slots[i]
+	s.Requests++
+	if failed {
+		s.Failed++
+	}
+	s.Max = max(s.Max, latency)
+	s.add(slotBucket(latency), 1)
+}
+
+// Stats summarizes the span up to now, which may not exceed liveSpan.
+// Early in a run the span is cut to the time elapsed, so the rate is
+// not diluted by seconds that have not happened yet.
+func (w *liveWindow) Stats(now time.Time, span time.Duration) windowStats {
+	elapsed := now.Sub(w.start)
+	if elapsed <= 0 {
+		return windowStats{}
+	}
+	cur := int64(elapsed / liveSlot)
+	first := max(cur-int64(span/liveSlot)+1, 0)
+
+	var agg timeSlot
+	for idx := first; idx <= cur; idx++ {
+		i := idx % int64(len(w.slots))
+		if w.index[i] != idx {
+			continue
+		}
+		s := w.slots[i]
+		agg.Requests += s.Requests
+		agg.Failed += s.Failed
+		agg.Max = max(agg.Max, s.Max)
+		for j, c := range s.Buckets {
+			if c > 0 {
+				agg.add(j, c)
+			}
+		}
+	}
+
+	// The current slot is only partly over.
+	covered := elapsed - time.Duration(first)*liveSlot
+	st := windowStats{
+		Requests: agg.Requests,
+		RPS:      float64(agg.Requests) / covered.Seconds(),
+		P50:      agg.percentile(50),
+		P95:      agg.percentile(95),
+		P99:      agg.percentile(99),
+	}
+	if agg.Requests > 0 {
+		st.Failed = float64(agg.Failed) / float64(agg.Requests) * 100
+	}
+	return st
+}
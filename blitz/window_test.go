@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLiveWindow(t *testing.T) {
+	start := time.Now()
+	w := newLiveWindow(start)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	// 100 fast requests a second for 20s, then 10 slow, failing ones a
+	// second: the window should show the slowdown, not the average.
+	for ms := 0; ms < 20000; ms += 10 {
+		w.Record(at(time.Duration(ms)*time.Millisecond), false, 10*time.Millisecond)
+	}
+	for ms := 20000; ms < 25000; ms += 100 {
+		w.Record(at(time.Duration(ms)*time.Millisecond), true, 800*time.Millisecond)
+	}
+
+	now := at(25*time.Second - time.Millisecond)
+	st := w.Stats(now, 5*time.Second)
+	if st.Requests != 50 || st.RPS < 9 || st.RPS > 11 {
+		t.Errorf("last 5s: %d requests at %.2f req/s, want 50 at about 10", st.Requests, st.RPS)
+	}
+	if st.Failed != 100 || st.P95 < 600*time.Millisecond {
+		t.Errorf("last 5s: %.0f%% failed, p95 %s; want every slow request", st.Failed, st.P95)
+	}
+
+	st = w.Stats(now, 10*time.Second)
+	if st.Requests != 550 || st.Failed < 9 || st.Failed > 10 {
+		t.Errorf("last 10s: %d requests, %.1f%% failed; want 550 with the 50 slow ones", st.Requests, st.Failed)
+	}
+
+	// Early in a run the rate is over the time elapsed, not the window.
+	early := newLiveWindow(start)
+	for ms := 0; ms < 1000; ms += 10 {
+		early.Record(at(time.Duration(ms)*time.Millisecond), false, time.Millisecond)
+	}
+	if st := early.Stats(at(time.Second), 5*time.Second); st.RPS < 95 || st.RPS > 105 {
+		t.Errorf("1s into a run: %.2f req/s, want about 100", st.RPS)
+	}
+	if st := early.Stats(at(30*time.Second), 5*time.Second); st.Requests != 0 || st.RPS != 0 {
+		t.Errorf("after going quiet: %+v, want an empty window", st)
+	}
+}
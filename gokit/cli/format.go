@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Formatter turns a value passed to Table.AddValues into the text of a
+// cell. Formatters set with Table.SetFormatter keep a column consistent
+// however its values were computed.
+type Formatter func(v any) string
+
+// FormatDuration returns a Formatter that rounds time.Duration values
+// to a multiple of round, so "1.234567ms" prints as "1.23ms" with a
+// round of 10µs. Other values print as with fmt.Sprint.
+func FormatDuration(round time.Duration) Formatter {
+	return func(v any) string {
+		d, ok := v.(time.Duration)
+		if !ok {
+			return fmt.Sprint(v)
+		}
+		return d.Round(round).String()
+	}
+}
+
+// FormatBytes formats an integer byte count with a binary unit suffix,
+// such as "1.50 KiB". Other values print as with fmt.Sprint.
+func FormatBytes(v any) string {
+	n, ok := toInt64(v)
+	if !ok {
+		return fmt.Sprint(v)
+	}
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit || v <= -unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// FormatPercent returns a Formatter that prints a ratio as a percentage
+// with the given number of decimal places, so 0.125 prints as "12.5%"
+// with one. Other values print as with fmt.Sprint.
+func FormatPercent(decimals int) Formatter {
+	return func(v any) string {
+		rv := reflect.ValueOf(v)
+		var ratio float64
+		switch {
+		case rv.CanFloat():
+			ratio = rv.Float()
+		case rv.CanInt():
+			ratio = float64(rv.Int())
+		default:
+			return fmt.Sprint(v)
+		}
+		return fmt.Sprintf("%.*f%%", decimals, ratio*100)
+	}
+}
+
+// toInt64 returns v as an int64 if it is an integer that fits.
+func toInt64(v any) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return rv.Int(), true
+	case rv.CanUint() && rv.Uint() <= 1<<63-1:
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
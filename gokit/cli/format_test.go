@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Formatter
+		input  any
+		want   string
+	}{
+		{"duration", FormatDuration(10 * time.Microsecond), 1234567 * time.Nanosecond, "1.23ms"},
+		{"duration of another type", FormatDuration(time.Millisecond), "n/a", "n/a"},
+		{"bytes", FormatBytes, 512, "512 B"},
+		{"kibibytes", FormatBytes, int64(1536), "1.50 KiB"},
+		{"mebibytes", FormatBytes, uint32(5 << 20), "5.00 MiB"},
+		{"bytes of another type", FormatBytes, 1.5, "1.5"},
+		{"percent", FormatPercent(1), 0.125, "12.5%"},
+		{"whole percent", FormatPercent(0), 1, "100%"},
+		{"percent of another type", FormatPercent(2), "-", "-"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format(tt.input); got != tt.want {
+			t.Errorf("%s: format(%v) = %q, want %q", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAddValues(t *testing.T) {
+	table := NewTable("Endpoint", "Requests", "P95", "Body", "Errors")
+	table.SetFormatter(2, FormatDuration(time.Millisecond))
+	table.SetFormatter(3, FormatBytes)
+	table.SetFormatter(4, FormatPercent(2))
+	table.AddValues("/search", 1200, 48123*time.Microsecond, 2048, 0.005)
+
+	want := []string{"/search", "1200", "48ms", "2.00 KiB", "0.50%"}
+	if !reflect.DeepEqual(table.Rows[0], want) {
+		t.Errorf("AddValues() row = %q, want %q", table.Rows[0], want)
+	}
+
+	table.SetFormatter(2, nil)
+	table.AddValues("/health", 35, 1500*time.Microsecond)
+	if got := table.Rows[1][2]; got != "1.5ms" {
+		t.Errorf("after clearing the formatter, P95 = %q, want fmt.Sprint's 1.5ms", got)
+	}
+}
@@ -4,7 +4,9 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"golang.org/x/term"
 )
@@ -26,6 +28,12 @@ const (
 	Dim     = "\033[2m"
 )
 
+// ANSI escape codes for cursor control, used to redraw live output in place.
+const (
+	ClearLine  = "\033[2K" // erase the current line
+	ClearBelow = "\033[J"  // erase from the cursor to the end of the screen
+)
+
 func init() {
 	colorsEnabled = term.IsTerminal(int(os.Stdout.Fd()))
 }
@@ -36,6 +44,29 @@ func SetColorsEnabled(enabled bool) {
 	colorsEnabled = enabled
 }
 
+// terminalWidth returns the width in columns of the terminal w writes
+// to, or 0 if w is not a terminal.
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// CursorUp returns the escape sequence that moves the cursor up n lines.
+// It returns an empty string for n <= 0.
+func CursorUp(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\033[%dA", n)
+}
+
 // Colorize wraps text with the specified ANSI color code.
 // If colors are disabled (e.g., non-terminal output), returns text unchanged.
 func Colorize(color, text string) string {
@@ -45,6 +76,55 @@ func Colorize(color, text string) string {
 	return fmt.Sprintf("%s%s%s", color, text, Reset)
 }
 
+// StripANSI removes ANSI escape sequences, such as the colors added by
+// Colorize and the cursor controls above, leaving the visible text.
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\033") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// escapeLen returns the length of the ANSI escape sequence s starts
+// with, or 0 if it does not start with one.
+func escapeLen(s string) int {
+	if s == "" || s[0] != '\033' {
+		return 0
+	}
+	if len(s) == 1 {
+		return 1
+	}
+	switch s[1] {
+	case '[': // CSI: parameters, then a final byte in @ through ~
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']': // OSC, such as a hyperlink: ended by BEL or ESC \
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\033' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	return 2
+}
+
 // Success returns text colored green, typically for success messages.
 func Success(text string) string {
 	return Colorize(Green, text)
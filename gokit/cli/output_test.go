@@ -91,3 +91,41 @@ func TestSetColorsEnabled(t *testing.T) {
 		t.Errorf("Expected colored text when colors enabled, got plain text")
 	}
 }
+
+func TestCursorUp(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{3, "\033[3A"},
+		{1, "\033[1A"},
+		{0, ""},
+		{-2, ""},
+	}
+
+	for _, tt := range tests {
+		if got := CursorUp(tt.n); got != tt.want {
+			t.Errorf("CursorUp(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestStripANSI(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"plain", "plain"},
+		{Red + "error" + Reset, "error"},
+		{Bold + Green + "ok" + Reset + " done", "ok done"},
+		{CursorUp(3) + ClearBelow + "frame", "frame"},
+		{"\033]8;;https://example.com\033\\link\033]8;;\a", "link"},
+		{"trailing\033", "trailing"},
+	}
+
+	for _, tt := range tests {
+		if got := StripANSI(tt.input); got != tt.want {
+			t.Errorf("StripANSI(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
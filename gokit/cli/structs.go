@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TableFromStructs builds a table from a slice of structs, or of
+// pointers to structs, with a column per exported field and a row per
+// element, skipping nil pointers. Cells are formatted with fmt.Sprint,
+// so values such as time.Duration print as they would with %v.
+//
+// A field's `table` tag names its column and sets options after a
+// comma:
+//
+//	type endpoint struct {
+//		Path     string        `table:"Endpoint"`
+//		Requests int           `table:",align=right"`
+//		P95      time.Duration `table:"P95,align=right"`
+//		internal string        `table:"-"`
+//	}
+//
+// An empty name keeps the field name, "-" leaves the field out, and
+// align may be left, right or center. TableFromStructs panics if slice
+// is not a slice of structs.
+func TableFromStructs(slice any) *Table {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		panic(fmt.Sprintf("cli.TableFromStructs: %T is not a slice", slice))
+	}
+	elem := v.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("cli.TableFromStructs: %T is not a slice of structs", slice))
+	}
+
+	var fields []int
+	var headers []string
+	var aligns []Align
+	for i := range elem.NumField() {
+		f := elem.Field(i)
+		tag := f.Tag.Get("table")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, i)
+		headers = append(headers, name)
+		aligns = append(aligns, tagAlign(opts))
+	}
+
+	t := NewTable(headers...)
+	for col, a := range aligns {
+		if a != AlignLeft {
+			t.SetAlign(col, a)
+		}
+	}
+	for i := range v.Len() {
+		row := v.Index(i)
+		if row.Kind() == reflect.Pointer {
+			if row.IsNil() {
+				continue
+			}
+			row = row.Elem()
+		}
+		cells := make([]string, len(fields))
+		for col, field := range fields {
+			cells[col] = fmt.Sprint(row.Field(field).Interface())
+		}
+		t.AddRow(cells...)
+	}
+	return t
+}
+
+// tagAlign returns the alignment a table tag's options ask for.
+func tagAlign(opts string) Align {
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "align=right":
+			return AlignRight
+		case "align=center":
+			return AlignCenter
+		}
+	}
+	return AlignLeft
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTableFromStructs(t *testing.T) {
+	type endpoint struct {
+		Path     string        `table:"Endpoint"`
+		Requests int           `table:",align=right"`
+		P95      time.Duration `table:"P95,align=right"`
+		Note     string        `table:"-"`
+		internal string
+	}
+	rows := []*endpoint{
+		{Path: "/search", Requests: 1200, P95: 48 * time.Millisecond, Note: "hidden"},
+		nil,
+		{Path: "/health", Requests: 35, P95: 1500 * time.Microsecond},
+	}
+
+	table := TableFromStructs(rows)
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "" +
+		"Endpoint  Requests    P95  \n" +
+		"--------  --------  -----  \n" +
+		"/search       1200   48ms  \n" +
+		"/health         35  1.5ms  \n"
+	if buf.String() != expected {
+		t.Errorf("Render() of TableFromStructs:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+}
+
+func TestTableFromStructsPanics(t *testing.T) {
+	for _, input := range []any{"not a slice", []int{1, 2}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("TableFromStructs(%T) should panic", input)
+				}
+			}()
+			TableFromStructs(input)
+		}()
+	}
+}
@@ -0,0 +1,51 @@
+package cli
+
+// StyleRule returns the color, such as Red, for a cell with the given
+// text, or "" to leave it as it is. See Table.AddStyleRule.
+type StyleRule func(cell string) string
+
+// StyleAbove returns a rule coloring cells greater than limit with over
+// and the rest with under; either may be "". Cells and limit compare as
+// Table.SortBy compares them: "250ms" against durations and "1%" or
+// "100" against numbers. Cells of another kind, such as text or an empty
+// cell, are left alone.
+func StyleAbove(limit, over, under string) StyleRule {
+	l := parseSortKey(limit)
+	return func(cell string) string {
+		c := parseSortKey(cell)
+		if c.kind != l.kind || (c.kind != sortNumber && c.kind != sortDuration) {
+			return ""
+		}
+		if c.num > l.num {
+			return over
+		}
+		return under
+	}
+}
+
+// StyleBelow returns a rule coloring cells less than limit with under
+// and the rest with over, comparing as StyleAbove does.
+func StyleBelow(limit, under, over string) StyleRule {
+	l := parseSortKey(limit)
+	return func(cell string) string {
+		c := parseSortKey(cell)
+		if c.kind != l.kind || (c.kind != sortNumber && c.kind != sortDuration) {
+			return ""
+		}
+		if c.num < l.num {
+			return under
+		}
+		return over
+	}
+}
+
+// styleCell colors cell with the first color rules pick for it.
+func styleCell(cell string, rules []StyleRule) string {
+	plain := StripANSI(cell)
+	for _, rule := range rules {
+		if color := rule(plain); color != "" {
+			return Colorize(color, plain)
+		}
+	}
+	return cell
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStyleRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule StyleRule
+		cell string
+		want string
+	}{
+		{"duration over", StyleAbove("250ms", Red, Green), "1.2s", Red},
+		{"duration under", StyleAbove("250ms", Red, Green), "48ms", Green},
+		{"duration at the limit", StyleAbove("250ms", Red, Green), "250ms", Green},
+		{"percent over", StyleAbove("1%", Red, ""), "2.5%", Red},
+		{"percent under, no color", StyleAbove("1%", Red, ""), "0.5%", ""},
+		{"number below", StyleBelow("100", Yellow, ""), "35", Yellow},
+		{"number at the limit", StyleBelow("100", Yellow, Green), "100", Green},
+		{"text", StyleAbove("250ms", Red, Green), "n/a", ""},
+		{"empty", StyleAbove("1", Red, Green), "", ""},
+		{"other kind", StyleAbove("250ms", Red, Green), "300", ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.rule(tt.cell); got != tt.want {
+			t.Errorf("%s: rule(%q) = %q, want %q", tt.name, tt.cell, got, tt.want)
+		}
+	}
+}
+
+func TestRenderStyleRules(t *testing.T) {
+	SetColorsEnabled(true)
+	defer SetColorsEnabled(false)
+
+	table := NewTable("Endpoint", "P95")
+	table.AddRow("/search", "48ms")
+	table.AddRow("/upload", Yellow+"1.2s"+Reset)
+	table.AddRow("/health", "-")
+	table.SetFooter("All", "1.2s")
+	table.AddStyleRule(1, StyleAbove("250ms", Red, Green))
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "" +
+		"Endpoint  P95   \n" +
+		"--------  ----  \n" +
+		"/search   " + Green + "48ms" + Reset + "  \n" +
+		"/upload   " + Red + "1.2s" + Reset + "  \n" +
+		"/health   -     \n" +
+		"--------  ----  \n" +
+		"All       1.2s  \n"
+	if buf.String() != expected {
+		t.Errorf("Render() with style rules:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+	if table.Rows[0][1] != "48ms" {
+		t.Errorf("style rules changed the row itself: %q", table.Rows[0][1])
+	}
+}
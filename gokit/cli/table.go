@@ -1,10 +1,22 @@
 package cli
 
 import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
 )
 
 // Table represents a text-based table for CLI output.
@@ -12,7 +24,104 @@ import (
 type Table struct {
 	Header []string   // Column headers
 	Rows   [][]string // Table data rows
+	Footer []string   // Row below the data, such as totals (optional)
 	Writer io.Writer  // Output destination (defaults to os.Stdout)
+	Style  TableStyle // Border style (defaults to StylePlain)
+
+	// MaxWidth limits how wide a rendered line may be, in terminal
+	// columns; 0 means no limit. The widest columns are narrowed first,
+	// down to minColumnWidth, and cells that no longer fit are cut short
+	// with an ellipsis, or wrapped (see Wrap).
+	MaxWidth int
+
+	// Wrap spreads cells wider than their column over several lines
+	// instead of cutting them short. SetWrap overrides it per column.
+	// Markdown tables cannot hold multi-line cells, so StyleMarkdown
+	// always cuts.
+	Wrap bool
+
+	// AutoFit limits lines to the width of the terminal Writer draws
+	// on, when MaxWidth is 0.
+	AutoFit bool
+
+	maxWidths  map[int]int   // per-column limits, set by SetMaxWidth
+	wrapCols   map[int]bool  // per-column Wrap overrides, set by SetWrap
+	priorities map[int]int   // hideable columns, set by SetPriority
+	aligns     map[int]Align // set by SetAlign; columns default to AlignLeft
+
+	formatters map[int]Formatter   // set by SetFormatter, used by AddValues
+	rules      map[int][]StyleRule // set by AddStyleRule
+}
+
+// minColumnWidth is the narrowest MaxWidth shrinks a column to, enough
+// for a character or two and the ellipsis.
+const minColumnWidth = 3
+
+// TableStyle selects how a Table draws its borders.
+type TableStyle int
+
+const (
+	// StylePlain separates columns with spaces and underlines the header
+	// with dashes.
+	StylePlain TableStyle = iota
+	// StyleASCII draws +---+ borders, for logs that may not be UTF-8.
+	StyleASCII
+	// StyleBox draws borders with Unicode box-drawing characters.
+	StyleBox
+	// StyleMarkdown renders a Markdown pipe table, for pasting into
+	// issues and pull request comments.
+	StyleMarkdown
+)
+
+// Align sets how cells line up within their column.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+	AlignCenter
+)
+
+// border holds the pieces a TableStyle draws with. A rule with no fill
+// is not drawn.
+type border struct {
+	top, mid, bottom rule
+	foot             rule   // between the rows and the footer
+	left, sep, right string // between and around the cells of a line
+	marked           bool   // mark column alignment in mid with colons, as Markdown does
+}
+
+// rule is a horizontal line: left, fill repeated across each column,
+// cross between columns, and right.
+type rule struct {
+	left, fill, cross, right string
+}
+
+var borders = map[TableStyle]border{
+	StylePlain: {
+		mid:  rule{"", "-", "  ", "  "},
+		foot: rule{"", "-", "  ", "  "},
+		sep:  "  ", right: "  ",
+	},
+	StyleASCII: {
+		top:    rule{"+-", "-", "-+-", "-+"},
+		mid:    rule{"+-", "-", "-+-", "-+"},
+		foot:   rule{"+-", "-", "-+-", "-+"},
+		bottom: rule{"+-", "-", "-+-", "-+"},
+		left:   "| ", sep: " | ", right: " |",
+	},
+	StyleBox: {
+		top:    rule{"┌─", "─", "─┬─", "─┐"},
+		mid:    rule{"├─", "─", "─┼─", "─┤"},
+		foot:   rule{"├─", "─", "─┼─", "─┤"},
+		bottom: rule{"└─", "─", "─┴─", "─┘"},
+		left:   "│ ", sep: " │ ", right: " │",
+	},
+	StyleMarkdown: {
+		mid:  rule{"| ", "-", " | ", " |"},
+		left: "| ", sep: " | ", right: " |",
+		marked: true,
+	},
 }
 
 // NewTable creates a new Table with the specified column headers.
@@ -31,20 +140,249 @@ func (t *Table) AddRow(values ...string) {
 	t.Rows = append(t.Rows, values)
 }
 
+// AddValues appends a row of typed values, such as durations and byte
+// counts, formatting each with its column's Formatter, or as fmt.Sprint
+// would for columns without one. Values are formatted as the row is
+// added, so set formatters first.
+func (t *Table) AddValues(values ...any) {
+	row := make([]string, len(values))
+	for i, v := range values {
+		if f, ok := t.formatters[i]; ok {
+			row[i] = f(v)
+		} else {
+			row[i] = fmt.Sprint(v)
+		}
+	}
+	t.AddRow(row...)
+}
+
+// SetFormatter sets how AddValues formats the values of column col.
+// A nil f goes back to fmt.Sprint.
+func (t *Table) SetFormatter(col int, f Formatter) {
+	if f == nil {
+		delete(t.formatters, col)
+		return
+	}
+	if t.formatters == nil {
+		t.formatters = make(map[int]Formatter)
+	}
+	t.formatters[col] = f
+}
+
+// AddStyleRule adds a rule coloring the cells of column col as the table
+// is drawn. Rules run in the order added and the first color wins. They
+// see cells without any colors and leave the header, footer and the
+// CSV, TSV and JSON renderings alone.
+func (t *Table) AddStyleRule(col int, rule StyleRule) {
+	if t.rules == nil {
+		t.rules = make(map[int][]StyleRule)
+	}
+	t.rules[col] = append(t.rules[col], rule)
+}
+
+// SetMaxWidth limits column col to n terminal columns. Longer cells are
+// cut short with an ellipsis. An n of 0 or less removes the limit.
+func (t *Table) SetMaxWidth(col, n int) {
+	if n <= 0 {
+		delete(t.maxWidths, col)
+		return
+	}
+	if t.maxWidths == nil {
+		t.maxWidths = make(map[int]int)
+	}
+	t.maxWidths[col] = n
+}
+
+// SetFooter sets a row drawn below the data rows, after a separator,
+// such as a "Total" row. The CSV, TSV and JSON renderings leave it out.
+func (t *Table) SetFooter(values ...string) {
+	t.Footer = values
+}
+
+// SetTotals sets the footer to label, in the first column, followed by
+// the sum of each of cols, or of every column whose cells are all
+// numbers when no cols are given. A sum keeps as many decimal places as
+// the most precise number it adds up.
+func (t *Table) SetTotals(label string, cols ...int) {
+	footer := make([]string, len(t.Header))
+	if len(footer) > 0 {
+		footer[0] = label
+	}
+	if len(cols) == 0 {
+		for col := 1; col < len(t.Header); col++ {
+			cols = append(cols, col)
+		}
+	}
+	for _, col := range cols {
+		if sum, ok := t.sum(col); ok && col < len(footer) {
+			footer[col] = sum
+		}
+	}
+	t.Footer = footer
+}
+
+// sum adds up the non-empty cells of col, reporting false if any is not
+// a plain decimal number or there are none.
+func (t *Table) sum(col int) (string, bool) {
+	var total float64
+	decimals, n := 0, 0
+	for _, row := range t.Rows {
+		if col >= len(row) {
+			continue
+		}
+		cell := strings.TrimSpace(StripANSI(row[col]))
+		if cell == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(cell, 64)
+		if err != nil || strings.ContainsFunc(cell, unicode.IsLetter) { // no Inf, NaN or 1e3
+			return "", false
+		}
+		if i := strings.IndexByte(cell, '.'); i >= 0 {
+			decimals = max(decimals, len(cell)-i-1)
+		}
+		total += v
+		n++
+	}
+	if n == 0 {
+		return "", false
+	}
+	return strconv.FormatFloat(total, 'f', decimals, 64), true
+}
+
+// SortOrder is the direction SortBy sorts rows in.
+type SortOrder int
+
+const (
+	Asc SortOrder = iota
+	Desc
+)
+
+// SortBy sorts the rows by column col. Numbers, including percentages,
+// compare by value and durations such as "1.5ms" by length of time;
+// other cells compare as text, after any numbers or durations. Empty
+// cells sort last in either order, and rows that compare equal keep
+// their order.
+func (t *Table) SortBy(col int, order SortOrder) {
+	type keyed struct {
+		row []string
+		key sortKey
+	}
+	rows := make([]keyed, len(t.Rows))
+	for i, row := range t.Rows {
+		rows[i] = keyed{row, sortKey{kind: sortEmpty}}
+		if col < len(row) {
+			rows[i].key = parseSortKey(row[col])
+		}
+	}
+
+	slices.SortStableFunc(rows, func(a, b keyed) int {
+		if a.key.kind != b.key.kind {
+			return cmp.Compare(a.key.kind, b.key.kind)
+		}
+		c := cmp.Compare(a.key.num, b.key.num)
+		if a.key.kind == sortText {
+			c = strings.Compare(a.key.text, b.key.text)
+		}
+		if order == Desc {
+			return -c
+		}
+		return c
+	})
+	for i, r := range rows {
+		t.Rows[i] = r.row
+	}
+}
+
+// Kinds of sortKey, in the order SortBy puts them.
+const (
+	sortNumber = iota
+	sortDuration
+	sortText
+	sortEmpty
+)
+
+// sortKey is a cell as SortBy compares it.
+type sortKey struct {
+	kind int
+	num  float64 // value of a number, or nanoseconds of a duration
+	text string
+}
+
+func parseSortKey(cell string) sortKey {
+	cell = strings.TrimSpace(StripANSI(cell))
+	if cell == "" {
+		return sortKey{kind: sortEmpty}
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSuffix(cell, "%"), 64); err == nil && !math.IsNaN(v) {
+		return sortKey{kind: sortNumber, num: v}
+	}
+	if d, err := time.ParseDuration(cell); err == nil {
+		return sortKey{kind: sortDuration, num: float64(d)}
+	}
+	return sortKey{kind: sortText, text: cell}
+}
+
+// SetAlign sets how the header and cells of column col line up within
+// its width.
+func (t *Table) SetAlign(col int, align Align) {
+	if t.aligns == nil {
+		t.aligns = make(map[int]Align)
+	}
+	t.aligns[col] = align
+}
+
+// SetWrap sets whether cells in column col that are wider than the
+// column wrap onto more lines or are cut short, overriding Wrap.
+func (t *Table) SetWrap(col int, wrap bool) {
+	if t.wrapCols == nil {
+		t.wrapCols = make(map[int]bool)
+	}
+	t.wrapCols[col] = wrap
+}
+
+// SetPriority lets column col be hidden when the table is wider than
+// MaxWidth, or the terminal with AutoFit, before any column is narrowed.
+// Columns with lower priorities are hidden first, and those given none
+// are never hidden.
+func (t *Table) SetPriority(col, priority int) {
+	if t.priorities == nil {
+		t.priorities = make(map[int]int)
+	}
+	t.priorities[col] = priority
+}
+
+// wraps reports whether column col wraps its cells.
+func (t *Table) wraps(col int) bool {
+	if t.Style == StyleMarkdown {
+		return false
+	}
+	if wrap, ok := t.wrapCols[col]; ok {
+		return wrap
+	}
+	return t.Wrap
+}
+
 // ColumnWidths calculates the maximum width needed for each column
-// based on header and cell content.
+// based on header and cell content, measured in terminal columns: ANSI
+// escape sequences, such as the colors added by Colorize, take up none,
+// and CJK characters and emoji take up two.
 func (t *Table) ColumnWidths() []int {
-	widths := make([]int, len(t.Header))
+	return columnWidths(t.Header, t.Rows)
+}
+
+func columnWidths(header []string, rows [][]string) []int {
+	widths := make([]int, len(header))
 
-	for i, header := range t.Header {
-		widths[i] = len(header)
+	for i, cell := range header {
+		widths[i] = displayWidth(cell)
 	}
 
-	for _, row := range t.Rows {
+	for _, row := range rows {
 		for j, cell := range row {
 			if j < len(widths) {
-				if len(cell) > widths[j] {
-					widths[j] = len(cell)
+				if w := displayWidth(cell); w > widths[j] {
+					widths[j] = w
 				}
 			}
 		}
@@ -53,40 +391,445 @@ func (t *Table) ColumnWidths() []int {
 	return widths
 }
 
+// displayWidth returns the number of columns s takes up on a terminal.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range StripANSI(s) {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth returns the number of columns r takes up on a terminal:
+// none for combining marks and format characters such as the zero-width
+// joiner, two for East Asian wide and fullwidth characters, which include
+// most emoji, and one otherwise.
+func runeWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
 // pad returns a string left-aligned and padded to the specified width.
+// Padding is measured by displayWidth, so colored text lines up.
 func pad(s string, width int) string {
-	return fmt.Sprintf("%-*s", width, s)
+	if n := width - displayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// truncate shortens s to at most width columns, ending it with an
+// ellipsis when anything is cut. Escape sequences before the cut are
+// kept and followed by a Reset, so a cut color does not bleed into the
+// rest of the line.
+func truncate(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	head, _ := cut(s, width-1)
+	if strings.Contains(head, "\033") {
+		return head + "…" + Reset
+	}
+	return head + "…"
+}
+
+// cut splits s after as many runes as fit in width columns, keeping
+// escape sequences with the head.
+func cut(s string, width int) (head, tail string) {
+	used := 0
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w := runeWidth(r)
+		if used+w > width {
+			return s[:i], s[i:]
+		}
+		used += w
+		i += size
+	}
+	return s, ""
+}
+
+// wrap breaks s into lines of at most width columns, between words
+// where it can and inside words longer than a line. A color that spans
+// a break is reset at the end of the line and picked up again on the
+// next one.
+func wrap(s string, width int) []string {
+	var lines []string
+	line, used := "", 0
+	for _, word := range strings.Fields(s) {
+		w := displayWidth(word)
+		switch {
+		case used > 0 && used+1+w <= width:
+			line += " " + word
+			used += 1 + w
+			continue
+		case used > 0:
+			lines = append(lines, line)
+		}
+		for w > width && width > 0 {
+			head, tail := cut(word, width)
+			if displayWidth(head) == 0 { // a wide rune in a one-column cell
+				head, tail = cut(word, 2)
+			}
+			lines = append(lines, head)
+			word = tail
+			w = displayWidth(word)
+		}
+		line, used = word, w
+	}
+	lines = append(lines, line)
+	return carryStyles(lines)
+}
+
+// carryStyles closes each line's open escape sequences with a Reset and
+// reopens them at the start of the next line.
+func carryStyles(lines []string) []string {
+	var open string
+	for i, line := range lines {
+		line = open + line
+		for j := 0; j < len(line); j++ {
+			n := escapeLen(line[j:])
+			if n == 0 {
+				continue
+			}
+			if seq := line[j : j+n]; seq == Reset || seq == "\033[m" {
+				open = ""
+			} else {
+				open += seq
+			}
+			j += n - 1
+		}
+		if open != "" {
+			line += Reset
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// align pads s to width as align says.
+func align(s string, width int, align Align) string {
+	n := width - displayWidth(s)
+	if n <= 0 {
+		return s
+	}
+	switch align {
+	case AlignRight:
+		return strings.Repeat(" ", n) + s
+	case AlignCenter:
+		return strings.Repeat(" ", n/2) + s + strings.Repeat(" ", n-n/2)
+	}
+	return pad(s, width)
 }
 
 // Render outputs the table to the configured Writer.
-// The table includes headers, a separator line, and all data rows.
+// The table includes headers, a separator line, and all data rows,
+// drawn in the table's Style.
 func (t *Table) Render() {
-	widths := t.ColumnWidths()
+	header, rows, footer := t.cells()
+	b, ok := borders[t.Style]
+	if !ok {
+		b = borders[StylePlain]
+	}
 
-	// Headers
-	for i, header := range t.Header {
-		padded := pad(header, widths[i])
-		fmt.Fprint(t.Writer, padded+"  ")
+	measured := rows
+	if footer != nil {
+		measured = append(rows[:len(rows):len(rows)], footer)
+	}
+	cols, widths := t.layout(b, columnWidths(header, measured))
+	header = pick(header, cols)
+	for i, row := range rows {
+		rows[i] = pick(row, cols)
 	}
-	fmt.Fprintln(t.Writer)
 
-	// Separator
-	for _, width := range widths {
-		wString := strings.Repeat("-", width)
-		fmt.Fprint(t.Writer, wString+"  ")
+	t.renderRule(b.top, cols, widths, false)
+	t.renderRow(b, cols, header, widths)
+	t.renderRule(b.mid, cols, widths, b.marked)
+	for _, row := range rows {
+		t.renderRow(b, cols, row, widths)
 	}
-	fmt.Fprintln(t.Writer)
+	if footer != nil {
+		t.renderRule(b.foot, cols, widths, false)
+		t.renderRow(b, cols, pick(footer, cols), widths)
+	}
+	t.renderRule(b.bottom, cols, widths, false)
+}
+
+// RenderCSV writes the table to w as CSV, header first, for tools that
+// read the output rather than people. Colors are stripped.
+func (t *Table) RenderCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(t.records()) // flushes
+	return cw.Error()
+}
 
-	// Rows
+// RenderTSV writes the table to w as tab-separated values, header first.
+// TSV has no quoting, so tabs and newlines in cells become spaces.
+func (t *Table) RenderTSV(w io.Writer) error {
+	clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+	var buf bytes.Buffer
+	for _, record := range t.records() {
+		for i, cell := range record {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(clean.Replace(cell))
+		}
+		buf.WriteByte('\n')
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// RenderJSON writes the table to w as a JSON array with an object per
+// row, keyed by header in column order. Colors are stripped.
+func (t *Table) RenderJSON(w io.Writer) error {
+	records := t.records()
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for r, row := range records[1:] {
+		if r > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for i, cell := range row {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			key, _ := json.Marshal(records[0][i])
+			value, _ := json.Marshal(cell)
+			buf.Write(key)
+			buf.WriteString(":")
+			buf.Write(value)
+		}
+		buf.WriteString("}")
+	}
+	if len(records) > 1 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// records returns the header and rows without colors, every row with
+// one cell per column.
+func (t *Table) records() [][]string {
+	records := make([][]string, 0, len(t.Rows)+1)
+	header := make([]string, len(t.Header))
+	for i, cell := range t.Header {
+		header[i] = StripANSI(cell)
+	}
+	records = append(records, header)
 	for _, row := range t.Rows {
-		for i := 0; i < len(t.Header); i++ {
-			cell := ""
-			if i < len(row) {
-				cell = row[i]
+		record := make([]string, len(t.Header))
+		for i := range min(len(row), len(t.Header)) {
+			record[i] = StripANSI(row[i])
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// cells returns the header, rows and footer as they are drawn: every
+// row with one cell per column, escaped for the table's Style. The
+// footer is nil if the table has none.
+func (t *Table) cells() (header []string, rows [][]string, footer []string) {
+	escape := func(s string) string { return s }
+	if t.Style == StyleMarkdown {
+		escape = strings.NewReplacer("|", "\\|", "\n", " ").Replace
+	}
+
+	line := func(cells []string) []string {
+		line := make([]string, len(t.Header))
+		for i := range min(len(cells), len(t.Header)) {
+			line[i] = escape(cells[i])
+		}
+		return line
+	}
+
+	header = line(t.Header)
+	rows = make([][]string, len(t.Rows))
+	for r, row := range t.Rows {
+		rows[r] = line(row)
+	}
+	if t.Footer != nil {
+		footer = line(t.Footer)
+	}
+
+	for col, rules := range t.rules {
+		if col >= len(t.Header) {
+			continue
+		}
+		for _, row := range rows {
+			row[col] = styleCell(row[col], rules)
+		}
+	}
+	return header, rows, footer
+}
+
+// layout picks the columns to draw, by index, and their widths. While
+// lines are wider than the width limit, columns given a priority are
+// hidden, lowest first; then the rest are narrowed to fit.
+func (t *Table) layout(b border, natural []int) (cols, widths []int) {
+	limit := t.widthLimit()
+	cols = make([]int, len(natural))
+	for i := range cols {
+		cols[i] = i
+	}
+	for {
+		widths = t.fitWidths(b, cols, natural, 0)
+		if limit <= 0 || len(cols) <= 1 || lineWidth(b, widths) <= limit {
+			break
+		}
+		hide := -1
+		for i, col := range cols {
+			if p, ok := t.priorities[col]; ok && (hide < 0 || p <= t.priorities[cols[hide]]) {
+				hide = i
+			}
+		}
+		if hide < 0 {
+			break
+		}
+		cols = slices.Delete(cols, hide, hide+1)
+	}
+	return cols, t.fitWidths(b, cols, natural, limit)
+}
+
+// widthLimit returns the widest a line may be: MaxWidth, or with AutoFit
+// and no MaxWidth, the width of the terminal Writer draws on.
+func (t *Table) widthLimit() int {
+	if t.MaxWidth > 0 || !t.AutoFit {
+		return t.MaxWidth
+	}
+	return terminalWidth(t.Writer)
+}
+
+// fitWidths returns the widths of cols: their natural widths capped at
+// the columns' SetMaxWidth limits, then with the widest narrowed until a
+// line fits in limit.
+func (t *Table) fitWidths(b border, cols, natural []int, limit int) []int {
+	widths := make([]int, len(cols))
+	for i, col := range cols {
+		widths[i] = natural[col]
+		if n, ok := t.maxWidths[col]; ok {
+			widths[i] = min(widths[i], n)
+		}
+	}
+	if limit <= 0 || len(widths) == 0 {
+		return widths
+	}
+	for over := lineWidth(b, widths) - limit; over > 0; over-- {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+// pick returns the cells of cols.
+func pick(cells []string, cols []int) []string {
+	picked := make([]string, len(cols))
+	for i, col := range cols {
+		picked[i] = cells[col]
+	}
+	return picked
+}
+
+// lineWidth returns how many terminal columns a line of cells padded to
+// widths takes up, borders included.
+func lineWidth(b border, widths []int) int {
+	n := displayWidth(b.left) + displayWidth(b.right) + displayWidth(b.sep)*(len(widths)-1)
+	for _, w := range widths {
+		n += w
+	}
+	return n
+}
+
+// renderRow writes a row of the cells of cols fitted to widths: cut
+// short, or in wrapping columns spread over as many lines as the tallest
+// cell needs, with the other cells blank below their first line.
+func (t *Table) renderRow(b border, cols []int, cells []string, widths []int) {
+	lines := make([][]string, len(cells))
+	height := 1
+	for i, cell := range cells {
+		if t.wraps(cols[i]) && displayWidth(cell) > widths[i] {
+			lines[i] = wrap(cell, widths[i])
+		} else {
+			lines[i] = []string{truncate(cell, widths[i])}
+		}
+		height = max(height, len(lines[i]))
+	}
+
+	line := make([]string, len(cells))
+	for n := range height {
+		for i := range cells {
+			line[i] = ""
+			if n < len(lines[i]) {
+				line[i] = lines[i][n]
+			}
+		}
+		t.renderLine(b, cols, line, widths)
+	}
+}
+
+// renderLine writes one line of the cells of cols, padded to widths.
+func (t *Table) renderLine(b border, cols []int, cells []string, widths []int) {
+	var line strings.Builder
+	line.WriteString(b.left)
+	for i, cell := range cells {
+		if i > 0 {
+			line.WriteString(b.sep)
+		}
+		line.WriteString(align(cell, widths[i], t.aligns[cols[i]]))
+	}
+	line.WriteString(b.right)
+	fmt.Fprintln(t.Writer, line.String())
+}
+
+// renderRule writes a horizontal rule across the widths of cols, if r
+// is drawn, marking the columns' alignment with colons if marked.
+func (t *Table) renderRule(r rule, cols, widths []int, marked bool) {
+	if r.fill == "" {
+		return
+	}
+	var line strings.Builder
+	line.WriteString(r.left)
+	for i, width := range widths {
+		if i > 0 {
+			line.WriteString(r.cross)
+		}
+		fill := strings.Repeat(r.fill, width)
+		if marked {
+			switch t.aligns[cols[i]] {
+			case AlignRight:
+				fill = strings.Repeat(r.fill, max(width-1, 1)) + ":"
+			case AlignCenter:
+				fill = ":" + strings.Repeat(r.fill, max(width-2, 1)) + ":"
 			}
-			padded := pad(cell, widths[i])
-			fmt.Fprint(t.Writer, padded+"  ")
 		}
-		fmt.Fprintln(t.Writer)
+		line.WriteString(fill)
 	}
+	line.WriteString(r.right)
+	fmt.Fprintln(t.Writer, line.String())
 }
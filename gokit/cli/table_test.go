@@ -2,7 +2,9 @@ package cli
 
 import (
 	"bytes"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -144,3 +146,442 @@ func TestRenderWithMissingCells(t *testing.T) {
 		t.Errorf("Render() with missing cells:\n%q\nwant:\n%q", buf.String(), expected)
 	}
 }
+
+func TestRenderColoredCells(t *testing.T) {
+	table := NewTable("Status", "Count")
+	table.AddRow(Green+"ok"+Reset, "10")
+	table.AddRow(Red+"failed"+Reset, "2")
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "Status  Count  \n------  -----  \n" +
+		Green + "ok" + Reset + "      10     \n" +
+		Red + "failed" + Reset + "  2      \n"
+	if buf.String() != expected {
+		t.Errorf("Render() with colored cells:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"hello", 5},
+		{"日本語", 6},
+		{"ｆｕｌｌ", 8},
+		{"café", 4},
+		{"cafe\u0301", 4},
+		{"🚀 ok", 5},
+		{"a\u200db", 2},
+		{Red + "東京" + Reset, 4},
+	}
+
+	for _, tt := range tests {
+		if got := displayWidth(tt.input); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestRenderWideCharacters(t *testing.T) {
+	table := NewTable("City", "Region")
+	table.AddRow("東京", "Kantō")
+	table.AddRow("Zürich", "🇨🇭")
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "City    Region  \n------  ------  \n東京    Kantō   \nZürich  🇨🇭      \n"
+	if buf.String() != expected {
+		t.Errorf("Render() with wide characters:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+}
+
+func TestRenderStyles(t *testing.T) {
+	tests := []struct {
+		style TableStyle
+		want  string
+	}{
+		{StyleASCII, "" +
+			"+-------+-----+\n" +
+			"| Name  | Age |\n" +
+			"+-------+-----+\n" +
+			"| Alice | 30  |\n" +
+			"| Bob   |     |\n" +
+			"+-------+-----+\n"},
+		{StyleBox, "" +
+			"┌───────┬─────┐\n" +
+			"│ Name  │ Age │\n" +
+			"├───────┼─────┤\n" +
+			"│ Alice │ 30  │\n" +
+			"│ Bob   │     │\n" +
+			"└───────┴─────┘\n"},
+		{StyleMarkdown, "" +
+			"| Name  | Age |\n" +
+			"| ----- | --- |\n" +
+			"| Alice | 30  |\n" +
+			"| Bob   |     |\n"},
+	}
+
+	for _, tt := range tests {
+		table := NewTable("Name", "Age")
+		table.AddRow("Alice", "30")
+		table.AddRow("Bob")
+		table.Style = tt.style
+
+		var buf bytes.Buffer
+		table.Writer = &buf
+		table.Render()
+
+		if buf.String() != tt.want {
+			t.Errorf("Render() with style %d:\n%s\nwant:\n%s", tt.style, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRenderAlignment(t *testing.T) {
+	tests := []struct {
+		style TableStyle
+		want  string
+	}{
+		{StylePlain, "" +
+			"Name  Count  Mid  \n" +
+			"----  -----  ---  \n" +
+			"a         1   x   \n"},
+		{StyleBox, "" +
+			"┌──────┬───────┬─────┐\n" +
+			"│ Name │ Count │ Mid │\n" +
+			"├──────┼───────┼─────┤\n" +
+			"│ a    │     1 │  x  │\n" +
+			"└──────┴───────┴─────┘\n"},
+		{StyleMarkdown, "" +
+			"| Name | Count | Mid |\n" +
+			"| ---- | ----: | :-: |\n" +
+			"| a    |     1 |  x  |\n"},
+	}
+
+	for _, tt := range tests {
+		table := NewTable("Name", "Count", "Mid")
+		table.AddRow("a", "1", "x")
+		table.SetAlign(1, AlignRight)
+		table.SetAlign(2, AlignCenter)
+		table.Style = tt.style
+
+		var buf bytes.Buffer
+		table.Writer = &buf
+		table.Render()
+
+		if buf.String() != tt.want {
+			t.Errorf("Render() with style %d and alignment:\n%q\nwant:\n%q", tt.style, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRenderMarkdownEscapesPipes(t *testing.T) {
+	table := NewTable("Check", "Result")
+	table.AddRow("a|b", "ok")
+	table.Style = StyleMarkdown
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "| Check | Result |\n| ----- | ------ |\n| a\\|b  | ok     |\n"
+	if buf.String() != expected {
+		t.Errorf("Render() markdown with a pipe:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		input string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly", 7, "exactly"},
+		{"https://example.com/a/long/path", 12, "https://exa…"},
+		{"日本語テキスト", 6, "日本…"},
+		{Red + "connection refused" + Reset, 8, Red + "connect…" + Reset},
+		{"abc", 0, ""},
+	}
+
+	for _, tt := range tests {
+		got := truncate(tt.input, tt.width)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+		}
+		if w := displayWidth(got); w > tt.width {
+			t.Errorf("truncate(%q, %d) is %d columns wide", tt.input, tt.width, w)
+		}
+	}
+}
+
+func TestRenderSetMaxWidth(t *testing.T) {
+	table := NewTable("URL", "Status")
+	table.AddRow("https://example.com/search?q=load", "200")
+	table.AddRow("/health", "200")
+	table.SetMaxWidth(0, 12)
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "URL           Status  \n------------  ------  \nhttps://exa…  200     \n/health       200     \n"
+	if buf.String() != expected {
+		t.Errorf("Render() with SetMaxWidth:\n%q\nwant:\n%q", buf.String(), expected)
+	}
+
+	table.SetMaxWidth(0, 0)
+	if got := table.ColumnWidths(); got[0] != 33 {
+		t.Errorf("after removing the limit, ColumnWidths() = %v", got)
+	}
+}
+
+func TestRenderMaxWidth(t *testing.T) {
+	table := NewTable("Endpoint", "Error", "Count")
+	table.AddRow("/api/users", "dial tcp 10.0.0.1:443: connect: connection refused", "12")
+	table.Style = StyleASCII
+	table.MaxWidth = 40
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if w := displayWidth(line); w != 40 {
+			t.Errorf("line %q is %d columns wide, want 40", line, w)
+		}
+	}
+	if !strings.Contains(buf.String(), "| /api/users | dial tcp 10.0.… | 12    |") {
+		t.Errorf("Render() with MaxWidth 40 narrowed the wrong column:\n%s", buf.String())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		input string
+		width int
+		want  []string
+	}{
+		{"fits", 10, []string{"fits"}},
+		{"the quick brown fox jumps", 10, []string{"the quick", "brown fox", "jumps"}},
+		{"see https://example.com/long", 10, []string{"see", "https://ex", "ample.com/", "long"}},
+		{"日本語のテキスト", 6, []string{"日本語", "のテキ", "スト"}},
+		{Red + "connection refused" + Reset, 10, []string{Red + "connection" + Reset, Red + "refused" + Reset}},
+		{"", 5, []string{""}},
+	}
+
+	for _, tt := range tests {
+		got := wrap(tt.input, tt.width)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrap(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestRenderWrap(t *testing.T) {
+	table := NewTable("Endpoint", "Error", "Count")
+	table.AddRow("/api/users", "dial tcp: connection refused", "12")
+	table.AddRow("/health", "timeout", "1")
+	table.SetMaxWidth(1, 12)
+	table.SetWrap(1, true)
+	table.Style = StyleASCII
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "" +
+		"+------------+--------------+-------+\n" +
+		"| Endpoint   | Error        | Count |\n" +
+		"+------------+--------------+-------+\n" +
+		"| /api/users | dial tcp:    | 12    |\n" +
+		"|            | connection   |       |\n" +
+		"|            | refused      |       |\n" +
+		"| /health    | timeout      | 1     |\n" +
+		"+------------+--------------+-------+\n"
+	if buf.String() != expected {
+		t.Errorf("Render() with a wrapped column:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestRenderHidesLowPriorityColumns(t *testing.T) {
+	newTable := func(maxWidth int) *Table {
+		table := NewTable("Endpoint", "Requests", "P50", "P95", "P99")
+		table.AddRow("/api/users", "1200", "12ms", "48ms", "95ms")
+		table.SetPriority(2, 1) // P50
+		table.SetPriority(4, 2) // P99
+		table.MaxWidth = maxWidth
+		return table
+	}
+
+	tests := []struct {
+		maxWidth int
+		want     string
+	}{
+		{60, "" +
+			"Endpoint    Requests  P50   P95   P99   \n" +
+			"----------  --------  ----  ----  ----  \n" +
+			"/api/users  1200      12ms  48ms  95ms  \n"},
+		{30, "" +
+			"Endpoint    Requests  P95   \n" +
+			"----------  --------  ----  \n" +
+			"/api/users  1200      48ms  \n"},
+		{22, "" +
+			"Endpo…  Reque…  P95   \n" +
+			"------  ------  ----  \n" +
+			"/api/…  1200    48ms  \n"},
+	}
+
+	for _, tt := range tests {
+		table := newTable(tt.maxWidth)
+		var buf bytes.Buffer
+		table.Writer = &buf
+		table.Render()
+
+		if buf.String() != tt.want {
+			t.Errorf("Render() with MaxWidth %d:\n%s\nwant:\n%s", tt.maxWidth, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestAutoFitWithoutTerminal(t *testing.T) {
+	table := NewTable("Message")
+	table.AddRow(strings.Repeat("x", 500))
+	table.AutoFit = true
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	if !strings.Contains(buf.String(), strings.Repeat("x", 500)) {
+		t.Error("AutoFit should leave output that is not to a terminal alone")
+	}
+}
+
+func TestRenderMachineReadable(t *testing.T) {
+	table := NewTable("Endpoint", "Status", "Note")
+	table.AddRow("/search", Green+"200"+Reset, `said "hi", then left`)
+	table.AddRow("/upload", Red+"500"+Reset)
+
+	tests := []struct {
+		name   string
+		render func(io.Writer) error
+		want   string
+	}{
+		{"CSV", table.RenderCSV, "" +
+			"Endpoint,Status,Note\n" +
+			"/search,200,\"said \"\"hi\"\", then left\"\n" +
+			"/upload,500,\n"},
+		{"TSV", table.RenderTSV, "" +
+			"Endpoint\tStatus\tNote\n" +
+			"/search\t200\tsaid \"hi\", then left\n" +
+			"/upload\t500\t\n"},
+		{"JSON", table.RenderJSON, "[\n" +
+			`  {"Endpoint":"/search","Status":"200","Note":"said \"hi\", then left"},` + "\n" +
+			`  {"Endpoint":"/upload","Status":"500","Note":""}` + "\n" +
+			"]\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.render(&buf); err != nil {
+			t.Fatalf("Render%s() error = %v", tt.name, err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("Render%s():\n%s\nwant:\n%s", tt.name, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestRenderJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewTable("A").RenderJSON(&buf); err != nil || buf.String() != "[]\n" {
+		t.Errorf("RenderJSON() = %q, %v; want an empty array", buf.String(), err)
+	}
+}
+
+func TestRenderFooter(t *testing.T) {
+	table := NewTable("Endpoint", "Requests", "Errors", "Share")
+	table.AddRow("/search", "1200", Red+"3"+Reset, "0.75")
+	table.AddRow("/health", "400", "", "0.25")
+	table.AddRow("/upload", "n/a", "1", "0.0")
+	table.SetTotals("Total")
+
+	if want := []string{"Total", "", "4", "1.00"}; !reflect.DeepEqual(table.Footer, want) {
+		t.Errorf("SetTotals() footer = %q, want %q", table.Footer, want)
+	}
+
+	table.SetTotals("Total", 1)
+	if want := []string{"Total", "", "", ""}; !reflect.DeepEqual(table.Footer, want) {
+		t.Errorf("SetTotals() of a non-numeric column = %q, want %q", table.Footer, want)
+	}
+
+	table = NewTable("Endpoint", "Requests")
+	table.AddRow("/search", "1200")
+	table.AddRow("/health", "400")
+	table.SetFooter("All endpoints", "1600")
+	table.Style = StyleASCII
+
+	var buf bytes.Buffer
+	table.Writer = &buf
+	table.Render()
+
+	expected := "" +
+		"+---------------+----------+\n" +
+		"| Endpoint      | Requests |\n" +
+		"+---------------+----------+\n" +
+		"| /search       | 1200     |\n" +
+		"| /health       | 400      |\n" +
+		"+---------------+----------+\n" +
+		"| All endpoints | 1600     |\n" +
+		"+---------------+----------+\n"
+	if buf.String() != expected {
+		t.Errorf("Render() with a footer:\n%s\nwant:\n%s", buf.String(), expected)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	newTable := func() *Table {
+		table := NewTable("Endpoint", "Requests", "P95", "Errors")
+		table.AddRow("/search", "1200", "48ms", "0.5%")
+		table.AddRow("/health", "35", "1.5ms", "")
+		table.AddRow("/upload", "n/a", "1.2s", "12%")
+		table.AddRow("/login", "900", Red+"250ms"+Reset, "2%")
+		return table
+	}
+	endpoints := func(table *Table) []string {
+		var got []string
+		for _, row := range table.Rows {
+			got = append(got, row[0])
+		}
+		return got
+	}
+
+	tests := []struct {
+		col   int
+		order SortOrder
+		want  []string
+	}{
+		{0, Asc, []string{"/health", "/login", "/search", "/upload"}},
+		{1, Asc, []string{"/health", "/login", "/search", "/upload"}},
+		{1, Desc, []string{"/search", "/login", "/health", "/upload"}},
+		{2, Desc, []string{"/upload", "/login", "/search", "/health"}},
+		{3, Desc, []string{"/upload", "/login", "/search", "/health"}},
+		{3, Asc, []string{"/search", "/login", "/upload", "/health"}},
+		{9, Asc, []string{"/search", "/health", "/upload", "/login"}},
+	}
+
+	for _, tt := range tests {
+		table := newTable()
+		table.SortBy(tt.col, tt.order)
+		if got := endpoints(table); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortBy(%d, %d) = %q, want %q", tt.col, tt.order, got, tt.want)
+		}
+	}
+}
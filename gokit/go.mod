@@ -5,4 +5,5 @@ go 1.25.1
 require (
 	golang.org/x/sys v0.40.0 // indirect
 	golang.org/x/term v0.39.0 // indirect
+	golang.org/x/text v0.26.0
 )
@@ -0,0 +1,180 @@
+// Package loadtest holds the parts of blitz's load engine that do not
+// depend on how requests are made: the latency histogram, rate
+// schedules and the paced job generator.
+package loadtest
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// Histogram records durations into log-linear buckets in the style of
+// HdrHistogram: values below subBuckets nanoseconds are exact, and
+// every power-of-two range above that is split into subBuckets/2
+// linear buckets, bounding relative error to about 1.6%. Memory grows
+// with the log of the largest value, not with the number of samples,
+// so multi-hour runs cost the same as short ones.
+type Histogram struct {
+	Counts []uint64
+	Total  uint64
+	Sum    time.Duration
+	Min    time.Duration
+	Max    time.Duration
+}
+
+const (
+	subBucketBits = 7
+	subBuckets    = 1 << subBucketBits // exact values below this
+	halfBuckets   = subBuckets / 2     // linear buckets per power of two
+)
+
+// bucketIndex maps a non-negative value to its bucket.
+func bucketIndex(v uint64) int {
+	if v < subBuckets {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBucketBits
+	return subBuckets + (shift-1)*halfBuckets + int(v>>shift) - halfBuckets
+}
+
+// bucketBounds returns the inclusive value range of bucket i.
+func bucketBounds(i int) (lo, hi uint64) {
+	if i < subBuckets {
+		return uint64(i), uint64(i)
+	}
+	shift := (i-subBuckets)/halfBuckets + 1
+	m := uint64((i-subBuckets)%halfBuckets + halfBuckets)
+	return m << shift, (m+1)<<shift - 1
+}
+
+// Record adds one observation.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := bucketIndex(uint64(d))
+	if i >= len(h.Counts) {
+		grown := make([]uint64, i+1)
+		copy(grown, h.Counts)
+		h.Counts = grown
+	}
+	h.Counts[i]++
+	if h.Total == 0 || d < h.Min {
+		h.Min = d
+	}
+	if d > h.Max {
+		h.Max = d
+	}
+	h.Total++
+	h.Sum += d
+}
+
+// Merge folds other's observations into h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.Total == 0 {
+		return
+	}
+	if len(other.Counts) > len(h.Counts) {
+		grown := make([]uint64, len(other.Counts))
+		copy(grown, h.Counts)
+		h.Counts = grown
+	}
+	for i, c := range other.Counts {
+		h.Counts[i] += c
+	}
+	if h.Total == 0 || other.Min < h.Min {
+		h.Min = other.Min
+	}
+	if other.Max > h.Max {
+		h.Max = other.Max
+	}
+	h.Total += other.Total
+	h.Sum += other.Sum
+}
+
+// Mean returns the exact average of all observations.
+func (h *Histogram) Mean() time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Total)
+}
+
+// Percentile returns the p-th percentile, using the same nearest-rank
+// convention as percentile on a sorted slice. The result is the upper
+// bound of the containing bucket, clamped to the observed min and max.
+func (h *Histogram) Percentile(p float64) time.Duration {
+	if h.Total == 0 {
+		return 0
+	}
+	rank := uint64(float64(h.Total)*p/100) + 1
+	if rank > h.Total {
+		rank = h.Total
+	}
+	var seen uint64
+	for i, c := range h.Counts {
+		seen += c
+		if seen >= rank {
+			_, hi := bucketBounds(i)
+			return min(max(time.Duration(hi), h.Min), h.Max)
+		}
+	}
+	return h.Max
+}
+
+// Distribution counts the observations in each range between
+// consecutive bounds: [0, bounds[0]), [bounds[0], bounds[1]), ... and a
+// final open-ended [bounds[len-1], ∞). Buckets are placed by their
+// lower bound, so counts near an edge share the histogram's precision.
+func (h *Histogram) Distribution(bounds []time.Duration) []uint64 {
+	counts := make([]uint64, len(bounds)+1)
+	for i, c := range h.Counts {
+		if c == 0 {
+			continue
+		}
+		lo, _ := bucketBounds(i)
+		j := sort.Search(len(bounds), func(k int) bool { return bounds[k] > time.Duration(lo) })
+		counts[j] += c
+	}
+	return counts
+}
+
+// Stats summarizes the histogram as a LatencyStats.
+func (h *Histogram) Stats() LatencyStats {
+	return LatencyStats{
+		Min:  h.Min,
+		Mean: h.Mean(),
+		P50:  h.Percentile(50),
+		P95:  h.Percentile(95),
+		P99:  h.Percentile(99),
+		Max:  h.Max,
+	}
+}
+
+// LatencyStats holds the latency distribution of a run.
+type LatencyStats struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+
+	Percentiles []Quantile // any extra percentiles asked for; P50-P99 are always set
+}
+
+// Quantile is one extra latency percentile.
+type Quantile struct {
+	P     float64
+	Value time.Duration
+}
+
+// Quantiles returns the percentiles ps of h.
+func (h *Histogram) Quantiles(ps []float64) []Quantile {
+	var out []Quantile
+	for _, p := range ps {
+		out = append(out, Quantile{P: p, Value: h.Percentile(p)})
+	}
+	return out
+}
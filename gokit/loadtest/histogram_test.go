@@ -0,0 +1,91 @@
+package loadtest
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBucketRoundTrip(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 129, 255, 256, 1000, 1 << 20, 123456789, 1 << 40} {
+		i := bucketIndex(v)
+		lo, hi := bucketBounds(i)
+		if v < lo || v > hi {
+			t.Errorf("value %d landed in bucket %d with bounds [%d, %d]", v, i, lo, hi)
+		}
+	}
+
+	// Buckets must tile the value space with no gaps or overlaps.
+	_, prevHi := bucketBounds(0)
+	for i := 1; i < 2000; i++ {
+		lo, hi := bucketBounds(i)
+		if lo != prevHi+1 {
+			t.Fatalf("bucket %d starts at %d, want %d", i, lo, prevHi+1)
+		}
+		prevHi = hi
+	}
+}
+
+func TestHistogramPercentileAccuracy(t *testing.T) {
+	var h Histogram
+	values := make([]time.Duration, 10000)
+	for i := range values {
+		values[i] = time.Duration(rand.Int64N(int64(2 * time.Second)))
+		h.Record(values[i])
+	}
+	slices.Sort(values)
+
+	for _, p := range []float64{50, 90, 95, 99, 99.9} {
+		exact := values[min(int(float64(len(values))*p/100), len(values)-1)]
+		got := h.Percentile(p)
+		diff := float64(got-exact) / float64(exact)
+		if diff < -0.02 || diff > 0.02 {
+			t.Errorf("p%v = %v, exact %v (error %.2f%%)", p, got, exact, diff*100)
+		}
+	}
+
+	if h.Min != values[0] || h.Max != values[len(values)-1] {
+		t.Errorf("min/max = %v/%v, want %v/%v", h.Min, h.Max, values[0], values[len(values)-1])
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	var a, b Histogram
+	a.Record(10 * time.Millisecond)
+	a.Record(20 * time.Millisecond)
+	b.Record(5 * time.Millisecond)
+	b.Record(2 * time.Second)
+
+	a.Merge(&b)
+
+	if a.Total != 4 {
+		t.Errorf("Total = %d, want 4", a.Total)
+	}
+	if a.Min != 5*time.Millisecond || a.Max != 2*time.Second {
+		t.Errorf("min/max = %v/%v, want 5ms/2s", a.Min, a.Max)
+	}
+	if a.Mean() != (2035*time.Millisecond)/4 {
+		t.Errorf("Mean = %v, want %v", a.Mean(), (2035*time.Millisecond)/4)
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	var h Histogram
+	if h.Percentile(99) != 0 || h.Mean() != 0 {
+		t.Error("empty histogram should report zero")
+	}
+}
+
+func TestHistogramDistribution(t *testing.T) {
+	var h Histogram
+	for _, d := range []time.Duration{1 * time.Millisecond, 5 * time.Millisecond, 30 * time.Millisecond, 2 * time.Second} {
+		h.Record(d)
+	}
+	bounds := []time.Duration{10 * time.Millisecond, 100 * time.Millisecond, time.Second}
+	got := h.Distribution(bounds)
+	want := []uint64{2, 1, 0, 1}
+	if !slices.Equal(got, want) {
+		t.Errorf("Distribution() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package loadtest
+
+import (
+	"context"
+	"time"
+)
+
+// Job is one unit of work for a worker, such as one request or one
+// scenario iteration.
+type Job struct {
+	// Scheduled is when the schedule intended this job to start. It is
+	// zero for unpaced runs. Comparing it with the actual send time
+	// exposes coordinated omission: when the target slows down, jobs
+	// wait for a free worker and the wait is invisible to raw latency.
+	Scheduled time.Time
+}
+
+// Generate emits up to count jobs (unlimited when count <= 0), paced
+// according to sched. A nil schedule sends as fast as workers accept.
+// The channel is closed once all jobs are sent, the schedule ends, or
+// ctx is cancelled.
+func Generate(ctx context.Context, count int, sched Schedule) <-chan Job {
+	jobsChan := make(chan Job)
+
+	go func() {
+		defer close(jobsChan)
+
+		start := time.Now()
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for i := 1; count <= 0 || i <= count; i++ {
+			var j Job
+			if sched != nil {
+				at, ok := sched.TimeOf(float64(i))
+				if !ok {
+					return
+				}
+				j.Scheduled = start.Add(at)
+				timer.Reset(time.Until(j.Scheduled))
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobsChan <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return jobsChan
+}
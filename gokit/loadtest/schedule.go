@@ -0,0 +1,265 @@
+package loadtest
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage is one segment of a load profile. The target rate changes
+// linearly from From to To requests/sec over Duration. A Duration of
+// zero on the final stage means "hold From forever".
+type Stage struct {
+	Duration time.Duration
+	From     float64
+	To       float64
+}
+
+// Schedule is a piecewise-linear load profile made of consecutive stages.
+type Schedule []Stage
+
+// ConstantRate returns a schedule that holds rate forever.
+func ConstantRate(rate float64) Schedule {
+	return Schedule{{From: rate, To: rate}}
+}
+
+// RampUp returns a schedule that ramps linearly from zero to rate
+// over d, then holds rate.
+func RampUp(d time.Duration, rate float64) Schedule {
+	return Schedule{
+		{Duration: d, From: 0, To: rate},
+		{From: rate, To: rate},
+	}
+}
+
+// TimeOf returns the offset from the start of the run at which the n-th
+// request should be sent, i.e. the point where the integral of the rate
+// curve reaches n. The second return value is false once n falls past
+// the end of the final stage.
+func (s Schedule) TimeOf(n float64) (time.Duration, bool) {
+	var offset time.Duration
+	for i, st := range s {
+		last := i == len(s)-1
+		if st.Duration <= 0 {
+			if !last || st.From <= 0 {
+				return 0, false
+			}
+			return offset + seconds(n/st.From), true
+		}
+
+		length := st.Duration.Seconds()
+		count := (st.From + st.To) / 2 * length
+		if n <= count {
+			slope := (st.To - st.From) / length
+			var tau float64
+			if slope == 0 {
+				tau = n / st.From
+			} else {
+				// Solve From*tau + slope*tau^2/2 = n for tau.
+				tau = (-st.From + math.Sqrt(st.From*st.From+2*slope*n)) / slope
+			}
+			return offset + seconds(tau), true
+		}
+		n -= count
+		offset += st.Duration
+	}
+	return 0, false
+}
+
+// Length returns the total duration of the schedule, or zero when the
+// final stage is open-ended.
+func (s Schedule) Length() time.Duration {
+	var total time.Duration
+	for _, st := range s {
+		if st.Duration <= 0 {
+			return 0
+		}
+		total += st.Duration
+	}
+	return total
+}
+
+// Scale returns a copy of s with every rate multiplied by f, used to
+// give each agent of a distributed run its share of the load.
+func (s Schedule) Scale(f float64) Schedule {
+	scaled := make(Schedule, len(s))
+	for i, st := range s {
+		scaled[i] = Stage{Duration: st.Duration, From: st.From * f, To: st.To * f}
+	}
+	return scaled
+}
+
+func seconds(f float64) time.Duration {
+	return time.Duration(f * float64(time.Second))
+}
+
+// ParseStages parses a stage list such as "0-30s:10rps,30s-2m:100rps".
+// Each entry is "start-end:rate" or "length:rate". A rate of the form
+// "10-100rps" ramps linearly across the stage; a single value holds
+// steady. Stages must be contiguous.
+func ParseStages(spec string) (Schedule, error) {
+	var sched Schedule
+	var cursor time.Duration
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		span, rate, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage %q, expected 'start-end:rate'", part)
+		}
+
+		var length time.Duration
+		if startStr, endStr, isRange := strings.Cut(span, "-"); isRange {
+			start, err := time.ParseDuration(startStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stage start in %q: %w", part, err)
+			}
+			end, err := time.ParseDuration(endStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stage end in %q: %w", part, err)
+			}
+			if start != cursor {
+				return nil, fmt.Errorf("stage %q starts at %s, expected %s", part, start, cursor)
+			}
+			length = end - start
+		} else {
+			d, err := time.ParseDuration(span)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stage length in %q: %w", part, err)
+			}
+			length = d
+		}
+		if length <= 0 {
+			return nil, fmt.Errorf("stage %q has no duration", part)
+		}
+
+		from, to, err := parseStageRate(rate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stage rate in %q: %w", part, err)
+		}
+
+		sched = append(sched, Stage{Duration: length, From: from, To: to})
+		cursor += length
+	}
+
+	return sched, nil
+}
+
+// parseStageRate parses "10rps", "10" or "10-100rps".
+func parseStageRate(s string) (from, to float64, err error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "rps")
+	fromStr, toStr, isRamp := strings.Cut(s, "-")
+	from, err = strconv.ParseFloat(fromStr, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	to = from
+	if isRamp {
+		to, err = strconv.ParseFloat(toStr, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if from < 0 || to < 0 {
+		return 0, 0, fmt.Errorf("rate must not be negative")
+	}
+	return from, to, nil
+}
+
+// Burst is a spike pattern: Count extra requests every Every, spread
+// evenly over Length, on top of a baseline schedule.
+type Burst struct {
+	Count  int
+	Every  time.Duration
+	Length time.Duration
+}
+
+// WithBursts returns s with b's spikes added, as extra stages up to
+// horizon; bursts start one interval into the run. The result ends at
+// horizon, which must be set when s itself is open-ended.
+func (s Schedule) WithBursts(b Burst, horizon time.Duration) (Schedule, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf("bursts need a baseline rate")
+	}
+	if l := s.Length(); l > 0 && (horizon <= 0 || l < horizon) {
+		horizon = l
+	}
+	if horizon <= 0 {
+		return nil, fmt.Errorf("bursts need a bounded run: a horizon or a schedule with a fixed length")
+	}
+
+	// Split the run at every stage boundary and every burst edge, then
+	// emit one stage per piece with the burst rate added where it falls
+	// inside a burst.
+	cuts := []time.Duration{0, horizon}
+	var offset time.Duration
+	for _, st := range s {
+		offset += st.Duration
+		if st.Duration > 0 && offset < horizon {
+			cuts = append(cuts, offset)
+		}
+	}
+	for start := b.Every; start < horizon; start += b.Every {
+		cuts = append(cuts, start, min(start+b.Length, horizon))
+	}
+	slices.Sort(cuts)
+	cuts = slices.Compact(cuts)
+
+	extra := float64(b.Count) / b.Length.Seconds()
+	var out Schedule
+	for i := 0; i+1 < len(cuts); i++ {
+		from, to := s.rateBetween(cuts[i], cuts[i+1])
+		if cuts[i] >= b.Every && cuts[i]%b.Every < b.Length {
+			from += extra
+			to += extra
+		}
+		out = append(out, Stage{Duration: cuts[i+1] - cuts[i], From: from, To: to})
+	}
+	return out, nil
+}
+
+// rateBetween returns the rate of s at t0 and just before t1, where both
+// fall within the same stage.
+func (s Schedule) rateBetween(t0, t1 time.Duration) (from, to float64) {
+	var offset time.Duration
+	for i, st := range s {
+		if st.Duration <= 0 || t0 < offset+st.Duration || i == len(s)-1 {
+			if st.Duration <= 0 {
+				return st.From, st.From
+			}
+			slope := (st.To - st.From) / st.Duration.Seconds()
+			return st.From + slope*(t0-offset).Seconds(), st.From + slope*(t1-offset).Seconds()
+		}
+		offset += st.Duration
+	}
+	return 0, 0
+}
+
+// After returns what remains of s once d of it has run, so a resumed
+// run picks the profile up where it stopped. It is empty when d covers
+// all of a bounded schedule.
+func (s Schedule) After(d time.Duration) Schedule {
+	if s == nil {
+		return nil
+	}
+	out := Schedule{}
+	var offset time.Duration
+	for _, st := range s {
+		if st.Duration <= 0 {
+			return append(out, st)
+		}
+		end := offset + st.Duration
+		if end > d {
+			from := st.From
+			if d > offset {
+				from += (st.To - st.From) * (d - offset).Seconds() / st.Duration.Seconds()
+			}
+			out = append(out, Stage{Duration: end - max(d, offset), From: from, To: st.To})
+		}
+		offset = end
+	}
+	return out
+}
@@ -0,0 +1,145 @@
+package loadtest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseStages(t *testing.T) {
+	sched, err := ParseStages("0-30s:10rps,30s-2m:10-100rps,1m:5")
+	if err != nil {
+		t.Fatalf("ParseStages() error = %v", err)
+	}
+
+	want := Schedule{
+		{Duration: 30 * time.Second, From: 10, To: 10},
+		{Duration: 90 * time.Second, From: 10, To: 100},
+		{Duration: time.Minute, From: 5, To: 5},
+	}
+	if len(sched) != len(want) {
+		t.Fatalf("got %d stages, want %d", len(sched), len(want))
+	}
+	for i := range want {
+		if sched[i] != want[i] {
+			t.Errorf("stage %d = %+v, want %+v", i, sched[i], want[i])
+		}
+	}
+	if got := sched.Length(); got != 3*time.Minute {
+		t.Errorf("Length() = %v, want 3m", got)
+	}
+}
+
+func TestParseStagesErrors(t *testing.T) {
+	tests := []string{
+		"30s",              // missing rate
+		"0-30s:abc",        // bad rate
+		"0-30s:10,1m-2m:5", // gap between stages
+		"10s-5s:10",        // negative length
+		"0-30s:-5",         // negative rate
+	}
+	for _, spec := range tests {
+		if _, err := ParseStages(spec); err == nil {
+			t.Errorf("ParseStages(%q) expected error, got nil", spec)
+		}
+	}
+}
+
+func TestScheduleTimeOf(t *testing.T) {
+	tests := []struct {
+		name  string
+		sched Schedule
+		n     float64
+		want  time.Duration
+		ok    bool
+	}{
+		{"constant", ConstantRate(10), 5, 500 * time.Millisecond, true},
+		{"ramp midpoint", RampUp(10*time.Second, 10), 12.5, 5 * time.Second, true},
+		{"after ramp", RampUp(10*time.Second, 10), 60, 11 * time.Second, true},
+		{"idle stage skipped", Schedule{{Duration: time.Second}, {Duration: time.Second, From: 10, To: 10}}, 5, 1500 * time.Millisecond, true},
+		{"past end", Schedule{{Duration: time.Second, From: 10, To: 10}}, 11, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.sched.TimeOf(tt.n)
+			if ok != tt.ok {
+				t.Fatalf("TimeOf(%v) ok = %v, want %v", tt.n, ok, tt.ok)
+			}
+			if diff := got - tt.want; diff > time.Microsecond || diff < -time.Microsecond {
+				t.Errorf("TimeOf(%v) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithBursts(t *testing.T) {
+	b := Burst{Count: 100, Every: 10 * time.Second, Length: 2 * time.Second}
+	sched, err := ConstantRate(10).WithBursts(b, 25*time.Second)
+	if err != nil {
+		t.Fatalf("WithBursts() error = %v", err)
+	}
+	want := Schedule{
+		{Duration: 10 * time.Second, From: 10, To: 10},
+		{Duration: 2 * time.Second, From: 60, To: 60},
+		{Duration: 8 * time.Second, From: 10, To: 10},
+		{Duration: 2 * time.Second, From: 60, To: 60},
+		{Duration: 3 * time.Second, From: 10, To: 10},
+	}
+	if len(sched) != len(want) {
+		t.Fatalf("got %v, want %v", sched, want)
+	}
+	for i := range want {
+		if sched[i] != want[i] {
+			t.Errorf("stage %d = %+v, want %+v", i, sched[i], want[i])
+		}
+	}
+	// 10 req/s for 25s plus two bursts of 100.
+	if _, ok := sched.TimeOf(450); !ok {
+		t.Error("schedule ended before its 450th request")
+	}
+	if _, ok := sched.TimeOf(451); ok {
+		t.Error("schedule ran past its 450th request")
+	}
+
+	// A ramp keeps its slope across the split.
+	sched, err = RampUp(20*time.Second, 20).WithBursts(b, 30*time.Second)
+	if err != nil {
+		t.Fatalf("WithBursts() on ramp error = %v", err)
+	}
+	if got := sched[1]; got.From != 60 || got.To != 62 {
+		t.Errorf("burst on ramp = %+v, want 60 to 62", got)
+	}
+
+	if _, err := ConstantRate(10).WithBursts(b, 0); err == nil {
+		t.Error("WithBursts accepted an open-ended run")
+	}
+	if _, err := Schedule(nil).WithBursts(b, time.Minute); err == nil {
+		t.Error("WithBursts accepted no baseline")
+	}
+}
+
+func TestScheduleAfter(t *testing.T) {
+	sched := Schedule{
+		{Duration: 10 * time.Second, From: 0, To: 100},
+		{Duration: 10 * time.Second, From: 100, To: 100},
+	}
+	got := sched.After(5 * time.Second)
+	want := Schedule{
+		{Duration: 5 * time.Second, From: 50, To: 100},
+		{Duration: 10 * time.Second, From: 100, To: 100},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("After(5s) = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stage %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := sched.After(25 * time.Second); len(got) != 0 {
+		t.Errorf("After(25s) = %+v, want nothing left", got)
+	}
+	if got := ConstantRate(10).After(time.Hour); len(got) != 1 || got[0].From != 10 {
+		t.Errorf("open-ended After(1h) = %+v, want the rate held", got)
+	}
+}